@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mattvella07/hue"
+)
+
+var configResource = resource{
+	name:    "config",
+	summary: "show the bridge configuration and manage users",
+	commands: []command{
+		{"show", "", "show the bridge configuration", configShow},
+		{"users", "", "list the users in the whitelist", configUsers},
+		{"delete-user", "<username>", "remove a user from the whitelist", configDeleteUser},
+	},
+}
+
+var pairResource = resource{
+	name:    "pair",
+	summary: "create a user and save it as a profile",
+	commands: []command{
+		{"", "[-devicetype name] [-timeout d] [-name profile]", "create a user and save it as a profile", pair},
+	},
+}
+
+func configShow(a *app, args []string) error {
+	_, err := parse(a.flagSet("config show"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	config, err := a.h.GetConfiguration()
+	if err != nil {
+		return err
+	}
+
+	t := table{headers: []string{"FIELD", "VALUE"}}
+	t.add("name", config.Name)
+	t.add("bridgeid", config.BridgeID)
+	t.add("ipaddress", config.IPAddress)
+	t.add("mac", config.Mac)
+	t.add("swversion", config.SWVersion)
+	t.add("apiversion", config.APIVersion)
+	t.add("zigbeechannel", itoa(config.ZigbeeChannel))
+	t.add("timezone", orDash(config.Timezone))
+	t.add("localtime", orDash(config.LocalTime))
+	t.add("users", itoa(len(config.Whitelist)))
+
+	return a.print(config, t)
+}
+
+func configUsers(a *app, args []string) error {
+	_, err := parse(a.flagSet("config users"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	config, err := a.h.GetConfiguration()
+	if err != nil {
+		return err
+	}
+
+	t := table{headers: []string{"USERNAME", "NAME", "CREATED", "LASTUSED"}}
+	for _, u := range config.Whitelist {
+		t.add(u.ID, u.Name, orDash(u.CreateDate), orDash(u.LastUseDate))
+	}
+
+	return a.print(config.Whitelist, t)
+}
+
+func configDeleteUser(a *app, args []string) error {
+	args, err := parse(a.flagSet("config delete-user"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	return a.h.DeleteUser(args[0])
+}
+
+// pairResult is the output of pair
+type pairResult struct {
+	Username string `json:"username"`
+}
+
+func pair(a *app, args []string) error {
+	fs := a.flagSet("pair")
+	deviceType := fs.String("devicetype", "hue#cli", "`name` of the application and device, as app#device")
+	timeout := fs.Duration("timeout", 30*time.Second, "how long to wait for the link button to be pressed")
+	name := fs.String("name", "", "`name` of the profile to save, defaults to -profile or default")
+
+	_, err := parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.stderr, "Press the link button on the bridge")
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	username, err := a.h.Pair(ctx, *deviceType)
+	if err != nil {
+		return err
+	}
+
+	t := table{}
+	t.add(username)
+
+	err = a.print(pairResult{Username: username}, t)
+	if err != nil {
+		return err
+	}
+
+	// Save the address that was used, even if the bridge was discovered
+	address, err := a.h.GetBridgeIPAddress()
+	if err != nil {
+		return err
+	}
+
+	profile := hue.Profile{
+		Address:  address,
+		Username: username,
+	}
+
+	// Remember the bridge's ID so it can be discovered again if its address
+	// changes
+	config, err := profile.Connection().GetConfiguration()
+	if err != nil {
+		return err
+	}
+	profile.BridgeID = config.BridgeID
+
+	profileName := *name
+	if profileName == "" {
+		profileName = a.profile
+	}
+	if profileName == "" {
+		profileName = "default"
+	}
+
+	return a.saveProfile(profileName, profile)
+}
@@ -0,0 +1,552 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattvella07/hue"
+)
+
+var dashboardResource = resource{
+	name:    "dashboard",
+	summary: "show rooms, lights, and sensors, and control them from the keyboard",
+	commands: []command{
+		{"", "[-interval d]", "show rooms, lights, and sensors, and control them from the keyboard", dashboardRun},
+	},
+}
+
+// dimStep is how much the brightness changes each time a light is dimmed
+const dimStep = 25
+
+// dashboardHelp lists the keys the dashboard supports
+const dashboardHelp = "up/down select  space toggle  +/- dim  s next scene  r refresh  q quit"
+
+// dashboardRow is a selectable row of the dashboard. Rows for a room have a
+// light of 0, and lights that aren't in a room have a group of 0.
+type dashboardRow struct {
+	group int
+	light int
+}
+
+// dashboard contains the state of the bridge as last polled, and which row is
+// selected
+type dashboard struct {
+	h          *hue.Connection
+	groups     []hue.Group
+	lights     map[int]hue.Light
+	sensors    []hue.Sensor
+	scenes     map[int][]hue.Scene
+	rows       []dashboardRow
+	selected   int
+	sceneIndex map[int]int
+	status     string
+}
+
+func newDashboard(h *hue.Connection) *dashboard {
+	return &dashboard{
+		h:          h,
+		lights:     map[int]hue.Light{},
+		scenes:     map[int][]hue.Scene{},
+		sceneIndex: map[int]int{},
+	}
+}
+
+func dashboardRun(a *app, args []string) error {
+	fs := a.flagSet("dashboard")
+	interval := fs.Duration("interval", 2*time.Second, "how often to poll the bridge")
+
+	_, err := parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	if *interval <= 0 {
+		return usagef("interval must be greater than 0")
+	}
+
+	d := newDashboard(a.h)
+
+	// Fail before taking over the terminal if the bridge can't be reached
+	err = d.refresh()
+	if err != nil {
+		return err
+	}
+
+	t, err := openTerminal(a.stdout)
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			for _, key := range parseKeys(buf[:n]) {
+				keys <- key
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		t.draw(d.render())
+
+		select {
+		case key, ok := <-keys:
+			if !ok || d.handleKey(key) {
+				return nil
+			}
+		case <-ticker.C:
+		}
+
+		err = d.refresh()
+		if err != nil {
+			d.status = err.Error()
+		}
+	}
+}
+
+// refresh polls the bridge for the state of all groups, lights, sensors, and
+// scenes. The selected row is kept if it still exists.
+func (d *dashboard) refresh() error {
+	groups, err := d.h.GetGroups()
+	if err != nil {
+		return err
+	}
+
+	lights, err := d.h.GetLights()
+	if err != nil {
+		return err
+	}
+
+	sensors, err := d.h.GetSensors()
+	if err != nil {
+		return err
+	}
+
+	scenes, err := d.h.GetScenes()
+	if err != nil {
+		return err
+	}
+
+	var selected dashboardRow
+	if d.selected < len(d.rows) {
+		selected = d.rows[d.selected]
+	}
+
+	d.groups = []hue.Group{}
+	for _, g := range groups {
+		if g.Type == hue.GroupTypeRoom {
+			d.groups = append(d.groups, g)
+		}
+	}
+
+	d.lights = map[int]hue.Light{}
+	for _, l := range lights {
+		d.lights[l.ID] = l
+	}
+
+	d.sensors = sensors
+
+	d.scenes = map[int][]hue.Scene{}
+	for _, s := range scenes {
+		group, err := strconv.Atoi(s.Group)
+		if err != nil {
+			continue
+		}
+		d.scenes[group] = append(d.scenes[group], s)
+	}
+	for _, s := range d.scenes {
+		sort.Slice(s, func(i, j int) bool { return s[i].Name < s[j].Name })
+	}
+
+	// Rooms are followed by their lights, and lights that aren't in a room
+	// come last
+	d.rows = []dashboardRow{}
+	inRoom := map[int]bool{}
+
+	for _, g := range d.groups {
+		d.rows = append(d.rows, dashboardRow{group: g.ID})
+
+		for _, id := range g.Lights {
+			light, err := strconv.Atoi(id)
+			if _, ok := d.lights[light]; err != nil || !ok {
+				continue
+			}
+
+			d.rows = append(d.rows, dashboardRow{group: g.ID, light: light})
+			inRoom[light] = true
+		}
+	}
+
+	for _, l := range lights {
+		if !inRoom[l.ID] {
+			d.rows = append(d.rows, dashboardRow{light: l.ID})
+		}
+	}
+
+	d.selected = 0
+	for i, row := range d.rows {
+		if row == selected {
+			d.selected = i
+		}
+	}
+
+	return nil
+}
+
+// handleKey performs the action for a key and returns whether the dashboard
+// should quit
+func (d *dashboard) handleKey(key string) bool {
+	var err error
+
+	switch key {
+	case "q", "ctrl-c":
+		return true
+	case "up", "k":
+		if d.selected > 0 {
+			d.selected--
+		}
+	case "down", "j":
+		if d.selected < len(d.rows)-1 {
+			d.selected++
+		}
+	case "space", "enter", "t":
+		err = d.toggle()
+	case "+", "=", "right":
+		err = d.dim(dimStep)
+	case "-", "left":
+		err = d.dim(-dimStep)
+	case "s":
+		err = d.nextScene()
+	case "r":
+		d.status = ""
+	}
+
+	if err != nil {
+		d.status = err.Error()
+	}
+
+	return false
+}
+
+// row returns the selected row
+func (d *dashboard) row() (dashboardRow, bool) {
+	if d.selected >= len(d.rows) {
+		return dashboardRow{}, false
+	}
+
+	return d.rows[d.selected], true
+}
+
+func (d *dashboard) group(id int) hue.Group {
+	for _, g := range d.groups {
+		if g.ID == id {
+			return g
+		}
+	}
+
+	return hue.Group{}
+}
+
+// toggle turns the selected light or room on or off
+func (d *dashboard) toggle() error {
+	row, ok := d.row()
+	if !ok {
+		return nil
+	}
+
+	if row.light != 0 {
+		l := d.lights[row.light]
+		d.status = fmt.Sprintf("Turned %s %s", onOff(!l.State.On), l.Name)
+
+		if l.State.On {
+			return d.h.TurnOffLight(row.light)
+		}
+		return d.h.TurnOnLight(row.light)
+	}
+
+	g := d.group(row.group)
+	d.status = fmt.Sprintf("Turned %s %s", onOff(!g.State.AnyOn), g.Name)
+
+	if g.State.AnyOn {
+		return d.h.TurnOffGroup(row.group)
+	}
+	return d.h.TurnOnGroup(row.group)
+}
+
+// dim changes the brightness of the selected light or room
+func (d *dashboard) dim(delta int) error {
+	row, ok := d.row()
+	if !ok {
+		return nil
+	}
+
+	if row.light != 0 {
+		l := d.lights[row.light]
+
+		// Lights without a brightness, such as plugs, can't be dimmed
+		if l.State.Bri == 0 {
+			return fmt.Errorf("%s can't be dimmed", l.Name)
+		}
+
+		bri := clampBri(l.State.Bri + delta)
+		d.status = fmt.Sprintf("Set %s to %d%%", l.Name, percent(bri))
+
+		return d.h.FadeLight(context.Background(), row.light, []hue.FadeKeyframe{{Bri: bri}})
+	}
+
+	g := d.group(row.group)
+	bri := clampBri(g.Action.Bri + delta)
+	d.status = fmt.Sprintf("Set %s to %d%%", g.Name, percent(bri))
+
+	return d.h.FadeGroup(context.Background(), row.group, []hue.FadeKeyframe{{Bri: bri}})
+}
+
+// nextScene recalls the next scene of the selected room, or of the room the
+// selected light is in
+func (d *dashboard) nextScene() error {
+	row, ok := d.row()
+	if !ok || row.group == 0 {
+		return nil
+	}
+
+	g := d.group(row.group)
+	scenes := d.scenes[row.group]
+	if len(scenes) == 0 {
+		return fmt.Errorf("%s has no scenes", g.Name)
+	}
+
+	scene := scenes[d.sceneIndex[row.group]%len(scenes)]
+	d.sceneIndex[row.group]++
+	d.status = fmt.Sprintf("Recalled %s in %s", scene.Name, g.Name)
+
+	return d.h.RecallScene(row.group, scene.ID, hue.SceneTransition)
+}
+
+// render returns the lines of the dashboard
+func (d *dashboard) render() []string {
+	lines := []string{"Rooms and lights", ""}
+	otherLights := false
+
+	for i, row := range d.rows {
+		var line string
+
+		switch {
+		case row.light == 0:
+			g := d.group(row.group)
+			line = fmt.Sprintf("%s %-24s %-11s %s", swatch(groupColor(g)), g.Name, groupOnOff(g), brightness(g.Action.Bri))
+		default:
+			if row.group == 0 && !otherLights {
+				lines = append(lines, "Other lights")
+				otherLights = true
+			}
+
+			l := d.lights[row.light]
+			line = fmt.Sprintf("  %s %-22s %-11s %s", swatch(lightColor(l)), l.Name, lightOnOff(l), brightness(l.State.Bri))
+		}
+
+		if i == d.selected {
+			line = escReverse + line + escReset
+		}
+
+		lines = append(lines, line)
+	}
+
+	if len(d.sensors) > 0 {
+		lines = append(lines, "", "Sensors")
+
+		for _, s := range d.sensors {
+			line := fmt.Sprintf("  %-24s %s", s.Name, sensorReading(s))
+			if s.Config.Battery > 0 {
+				line += fmt.Sprintf("  battery %d%%", s.Config.Battery)
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	return append(lines, "", d.status, dashboardHelp)
+}
+
+func groupOnOff(g hue.Group) string {
+	switch {
+	case g.State.AllOn:
+		return "on"
+	case g.State.AnyOn:
+		return "some on"
+	}
+
+	return "off"
+}
+
+func lightOnOff(l hue.Light) string {
+	if !l.State.Reachable {
+		return "unreachable"
+	}
+
+	return onOff(l.State.On)
+}
+
+// brightness returns a bar showing the brightness, or nothing if there isn't
+// one
+func brightness(bri int) string {
+	if bri == 0 {
+		return ""
+	}
+
+	filled := int(math.Round(float64(bri) / 254 * 10))
+
+	return fmt.Sprintf("%s%s %3d%%", strings.Repeat("█", filled), strings.Repeat("░", 10-filled), percent(bri))
+}
+
+func percent(bri int) int {
+	return int(math.Round(float64(bri) / 254 * 100))
+}
+
+func clampBri(bri int) int {
+	return int(math.Max(1, math.Min(254, float64(bri))))
+}
+
+// sensorReading returns the value reported by a sensor, based on its type
+func sensorReading(s hue.Sensor) string {
+	switch strings.TrimPrefix(strings.TrimPrefix(s.Type, "ZLL"), "CLIP") {
+	case "Temperature":
+		return fmt.Sprintf("%.1f°C", s.State.Celsius())
+	case "Presence":
+		if s.State.Presence {
+			return "motion"
+		}
+		return "no motion"
+	case "LightLevel":
+		return fmt.Sprintf("%.0f lux", s.State.Lux())
+	case "Switch":
+		return fmt.Sprintf("button %d", s.State.ButtonEvent)
+	case "Humidity":
+		return fmt.Sprintf("%.1f%%", float64(s.State.Humidity)/100)
+	case "Daylight":
+		if s.State.Daylight {
+			return "daylight"
+		}
+		return "dark"
+	}
+
+	return orDash("")
+}
+
+// rgb is a color shown by the dashboard
+type rgb struct {
+	r, g, b int
+}
+
+// swatchOff is the color of lights that are off
+var swatchOff = rgb{60, 60, 60}
+
+// swatch returns a block of the color using a 24-bit background color
+func swatch(c rgb) string {
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%dm  %s", c.r, c.g, c.b, escReset)
+}
+
+func lightColor(l hue.Light) rgb {
+	if !l.State.On || !l.State.Reachable {
+		return swatchOff
+	}
+
+	return stateColor(l.State.ColorMode, l.State.XY, l.State.CT, l.State.Hue, l.State.Sat)
+}
+
+func groupColor(g hue.Group) rgb {
+	if !g.State.AnyOn {
+		return swatchOff
+	}
+
+	return stateColor(g.Action.ColorMode, g.Action.XY, g.Action.CT, g.Action.Hue, g.Action.Sat)
+}
+
+// stateColor converts the color of a light state to RGB. Lights without a
+// color are shown as warm white.
+func stateColor(colorMode string, xy []float32, ct, hueValue, sat int) rgb {
+	switch {
+	case colorMode == "xy" && len(xy) == 2:
+		r, g, b := hue.XYToRGB(xy[0], xy[1])
+		return rgb{int(r), int(g), int(b)}
+	case colorMode == "ct" && ct > 0:
+		return ctToRGB(ct)
+	case colorMode == "hs":
+		return hsToRGB(hueValue, sat)
+	}
+
+	return rgb{255, 214, 170}
+}
+
+// ctToRGB converts a color temperature in mireds to RGB, using an
+// approximation of the black body curve
+func ctToRGB(ct int) rgb {
+	temp := 1e6 / float64(ct) / 100
+
+	r, g, b := 255.0, 0.0, 255.0
+
+	if temp <= 66 {
+		g = 99.4708025861*math.Log(temp) - 161.1195681661
+	} else {
+		r = 329.698727446 * math.Pow(temp-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(temp-60, -0.0755148492)
+	}
+
+	if temp < 66 {
+		b = 0
+		if temp > 19 {
+			b = 138.5177312231*math.Log(temp-10) - 305.0447927307
+		}
+	}
+
+	return rgb{channel(r), channel(g), channel(b)}
+}
+
+// hsToRGB converts a hue from 0 to 65535 and a saturation from 0 to 254 to
+// RGB at full brightness
+func hsToRGB(hue, sat int) rgb {
+	h := float64(hue) / 65536 * 6
+	s := float64(sat) / 254
+
+	f := h - math.Floor(h)
+	p := 1 - s
+	q := 1 - s*f
+	t := 1 - s*(1-f)
+
+	var r, g, b float64
+	switch int(h) % 6 {
+	case 0:
+		r, g, b = 1, t, p
+	case 1:
+		r, g, b = q, 1, p
+	case 2:
+		r, g, b = p, 1, t
+	case 3:
+		r, g, b = p, q, 1
+	case 4:
+		r, g, b = t, p, 1
+	default:
+		r, g, b = 1, p, q
+	}
+
+	return rgb{channel(r * 255), channel(g * 255), channel(b * 255)}
+}
+
+func channel(c float64) int {
+	return int(math.Round(math.Max(0, math.Min(255, c))))
+}
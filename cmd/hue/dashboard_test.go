@@ -0,0 +1,137 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mattvella07/hue"
+)
+
+func TestDashboard(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	h := s.Connection()
+	d := newDashboard(h)
+
+	err := d.refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The room comes first, followed by its lights, then lights not in a room
+	{
+		expected := []dashboardRow{{group: 1}, {group: 1, light: 1}, {group: 1, light: 2}, {light: 3}}
+		if !reflect.DeepEqual(d.rows, expected) {
+			t.Fatalf("Expected rows %v, got %v", expected, d.rows)
+		}
+	}
+
+	screen := strings.Join(d.render(), "\n")
+	for _, expected := range []string{"Office", "Desk", "Other lights", "Hall", "Motion", "no motion", "battery 100%"} {
+		if !strings.Contains(screen, expected) {
+			t.Fatalf("Expected %q on the screen, got %s", expected, screen)
+		}
+	}
+
+	t.Run("Toggle", func(t *testing.T) {
+		d.handleKey("down")
+		d.handleKey("space")
+
+		if on, _ := s.Bridge.Get("lights/1/state/on"); on != true {
+			t.Fatalf("Expected light 1 to be on, got %v", on)
+		}
+
+		err := d.refresh()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The selection is kept after refreshing
+		if row, _ := d.row(); row.light != 1 {
+			t.Fatalf("Expected light 1 to be selected, got %v", row)
+		}
+	})
+
+	t.Run("Dim", func(t *testing.T) {
+		bri := d.lights[1].State.Bri
+		d.handleKey("-")
+
+		if got, _ := s.Bridge.Get("lights/1/state/bri"); got != float64(bri-dimStep) {
+			t.Fatalf("Expected bri %d, got %v", bri-dimStep, got)
+		}
+	})
+
+	t.Run("Scenes", func(t *testing.T) {
+		d.handleKey("s")
+		if !strings.Contains(d.status, "has no scenes") {
+			t.Fatalf("Expected no scenes, got %q", d.status)
+		}
+
+		err := h.CreateGroupScene("Bright", 1, false, hue.SceneAppData{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = h.TurnOffLight(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = d.refresh()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d.handleKey("s")
+		if d.status != "Recalled Bright in Office" {
+			t.Fatalf("Unexpected status %q", d.status)
+		}
+
+		if on, _ := s.Bridge.Get("lights/1/state/on"); on != true {
+			t.Fatalf("Expected the scene to turn light 1 on, got %v", on)
+		}
+	})
+
+	t.Run("Quit", func(t *testing.T) {
+		if !d.handleKey("q") || !d.handleKey("ctrl-c") {
+			t.Fatal("Expected the dashboard to quit")
+		}
+	})
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("q \r\x1b[A\x1b[D\x03\x1b+"))
+
+	expected := []string{"q", "space", "enter", "up", "left", "ctrl-c", "esc", "+"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("Expected %v, got %v", expected, keys)
+	}
+}
+
+func TestColors(t *testing.T) {
+	for name, test := range map[string]struct {
+		color    rgb
+		expected rgb
+	}{
+		"Red xy":     {stateColor("xy", []float32{0.675, 0.322}, 0, 0, 0), rgb{255, 50, 0}},
+		"Warm white": {ctToRGB(500), rgb{255, 137, 14}},
+		"Cool white": {ctToRGB(153), rgb{255, 250, 244}},
+		"Green hue":  {hsToRGB(21845, 254), rgb{0, 255, 0}},
+		"No color":   {stateColor("", nil, 0, 0, 0), rgb{255, 214, 170}},
+	} {
+		// Conversions are approximate, so only the dominant channels matter
+		if abs(test.color.r-test.expected.r) > 40 || abs(test.color.g-test.expected.g) > 40 || abs(test.color.b-test.expected.b) > 40 {
+			t.Errorf("%s: expected %v, got %v", name, test.expected, test.color)
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+
+	"github.com/mattvella07/hue"
+	"github.com/mattvella07/hue/huetest"
+)
+
+// newDemoServer starts an emulated bridge with a few rooms of lights, scenes,
+// and sensors, for trying out the command line tool without a bridge
+func newDemoServer() (*huetest.Server, error) {
+	s := huetest.NewServer()
+	b := s.Bridge
+
+	b.AddLight("Sofa lamp", huetest.ExtendedColorLight)
+	b.AddLight("Ceiling", huetest.ColorTemperatureLight)
+	b.AddLight("Reading light", huetest.DimmableLight)
+	b.AddLight("Counter", huetest.ExtendedColorLight)
+	b.AddLight("Pendant", huetest.ColorLight)
+	b.AddLight("Nightstand", huetest.ExtendedColorLight)
+	b.AddLight("Fan", huetest.OnOffPlug)
+
+	b.AddGroup("Living room", "Room", []string{"1", "2", "3"})
+	b.AddGroup("Kitchen", "Room", []string{"4", "5"})
+	b.AddGroup("Bedroom", "Room", []string{"6", "7"})
+
+	motion := b.AddSensor("Hallway motion", huetest.PresenceSensor)
+	b.AddSensor("Hallway temperature", huetest.TemperatureSensor)
+	b.AddSensor("Hallway light level", huetest.LightLevelSensor)
+	b.AddSensor("Daylight", huetest.DaylightSensor)
+	b.SetSensorState(motion, map[string]interface{}{"presence": true})
+
+	h := s.Connection()
+
+	// Each room gets a few scenes, captured from the lights' state
+	scenes := []struct {
+		name     string
+		keyframe hue.FadeKeyframe
+	}{
+		{"Bright", hue.FadeKeyframe{Bri: 254, CT: 233}},
+		{"Relax", hue.FadeKeyframe{Bri: 120, CT: 447}},
+		{"Sunset", hue.FadeKeyframe{Bri: 180, XY: []float32{0.5, 0.4}}},
+	}
+
+	for group := 1; group <= 3; group++ {
+		for _, scene := range scenes {
+			err := demoScene(h, group, scene.name, scene.keyframe)
+			if err != nil {
+				s.Close()
+				return nil, err
+			}
+		}
+	}
+
+	// Only the living room is left on. Lights are turned off one at a time as
+	// group commands are rate limited more heavily.
+	for light := 4; light <= 7; light++ {
+		err := h.TurnOffLight(light)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// demoScene sets the lights in a group to a state and saves it as a scene.
+// States the lights don't support are left out.
+func demoScene(h *hue.Connection, group int, name string, keyframe hue.FadeKeyframe) error {
+	g, err := h.GetGroup(group)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range g.Lights {
+		light, err := h.ResolveLight(id)
+		if err != nil {
+			return err
+		}
+
+		l, err := h.GetLight(light)
+		if err != nil {
+			return err
+		}
+
+		k := hue.FadeKeyframe{}
+		if l.State.Bri != 0 {
+			k.Bri = keyframe.Bri
+		}
+		if keyframe.CT != 0 && l.State.CT != 0 {
+			k.CT = keyframe.CT
+		}
+		if keyframe.XY != nil && l.State.XY != nil {
+			k.XY = keyframe.XY
+		}
+
+		if k.Bri == 0 && k.CT == 0 && k.XY == nil {
+			err = h.TurnOnLight(light)
+		} else {
+			err = h.FadeLight(context.Background(), light, []hue.FadeKeyframe{k})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return h.CreateGroupScene(name, group, false, hue.SceneAppData{})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/mattvella07/hue"
+)
+
+var exporterResource = resource{
+	name:    "exporter",
+	summary: "serve the state of lights, groups, sensors, and rules as Prometheus metrics",
+	commands: []command{
+		{"", "[-listen address] [-path p] [-requests=false]", "serve the state of lights, groups, sensors, and rules as Prometheus metrics", exporter},
+	},
+}
+
+func exporter(a *app, args []string) error {
+	fs := a.flagSet("exporter")
+	listen := fs.String("listen", "127.0.0.1:9366", "`address` to listen on")
+	path := fs.String("path", "/metrics", "`path` metrics are served on")
+	requests := fs.Bool("requests", true, "also export metrics for the requests sent to the bridge")
+
+	_, err := parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(*path, "/") {
+		return usagef("-path must start with /")
+	}
+
+	// Fail early if the bridge can't be reached or the user isn't authorized
+	_, err = a.h.GetLights()
+	if err != nil {
+		return err
+	}
+
+	var collector *hue.MetricsCollector
+	if *requests {
+		collector = hue.NewMetricsCollector()
+		a.h.SetMetrics(collector)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+*path, func(w http.ResponseWriter, r *http.Request) {
+		out, err := a.h.ExportState()
+		if err != nil {
+			fmt.Fprintf(a.stderr, "Reading the bridge failed: %s\n", err)
+		}
+
+		// Request metrics are read after the state, so they include the
+		// requests for this scrape
+		if collector != nil {
+			out += collector.String()
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(out))
+	})
+
+	server := &http.Server{
+		Addr:    *listen,
+		Handler: mux,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(a.stderr, "Serving metrics on http://%s%s\n", *listen, *path)
+
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
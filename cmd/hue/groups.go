@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mattvella07/hue"
+)
+
+var groupsResource = resource{
+	name:    "groups",
+	summary: "list and control groups of lights",
+	commands: []command{
+		{"list", "", "list all groups", groupsList},
+		{"get", "<group>", "show a group", groupsGet},
+		{"on", "<group>", "turn all lights in a group on", groupsOn},
+		{"off", "<group>", "turn all lights in a group off", groupsOff},
+		{"set", "<group> [-bri n] [-ct mireds] [-xy x,y] [-transition d]", "turn a group on and set its state", groupsSet},
+		{"identify", "<group> [-long]", "make all lights in a group breathe", groupsIdentify},
+		{"create", "<name> <light...> [-type type] [-class class]", "create a group", groupsCreate},
+		{"lights", "<group> <light...>", "set the lights in a group", groupsLights},
+		{"class", "<group> <class>", "set the class of a room", groupsClass},
+		{"rename", "<group> <name>", "rename a group", groupsRename},
+		{"delete", "<group>", "delete a group", groupsDelete},
+	},
+}
+
+func groupsTable(groups []hue.Group) table {
+	t := table{headers: []string{"ID", "NAME", "TYPE", "CLASS", "LIGHTS", "ANY_ON", "ALL_ON"}}
+
+	for _, g := range groups {
+		t.add(itoa(g.ID), g.Name, string(g.Type), orDash(string(g.Class)), list(g.Lights), yesNo(g.State.AnyOn), yesNo(g.State.AllOn))
+	}
+
+	return t
+}
+
+// group resolves a group name or ID
+func (a *app) group(name string) (int, error) {
+	id, err := a.h.ResolveGroup(name)
+	return id, resolveError(err)
+}
+
+func groupsList(a *app, args []string) error {
+	_, err := parse(a.flagSet("groups list"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	groups, err := a.h.GetGroups()
+	if err != nil {
+		return err
+	}
+
+	return a.print(groups, groupsTable(groups))
+}
+
+func groupsGet(a *app, args []string) error {
+	args, err := parse(a.flagSet("groups get"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.group(args[0])
+	if err != nil {
+		return err
+	}
+
+	group, err := a.h.GetGroup(id)
+	if err != nil {
+		return err
+	}
+	group.ID = id
+
+	return a.print(group, groupsTable([]hue.Group{group}))
+}
+
+func groupsOn(a *app, args []string) error {
+	return a.withGroup("groups on", args, a.h.TurnOnGroup)
+}
+
+func groupsOff(a *app, args []string) error {
+	return a.withGroup("groups off", args, a.h.TurnOffGroup)
+}
+
+func groupsDelete(a *app, args []string) error {
+	return a.withGroup("groups delete", args, a.h.DeleteGroup)
+}
+
+// withGroup runs a command that takes a single group
+func (a *app) withGroup(name string, args []string, fn func(group int) error) error {
+	args, err := parse(a.flagSet(name), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.group(args[0])
+	if err != nil {
+		return err
+	}
+
+	return fn(id)
+}
+
+func groupsSet(a *app, args []string) error {
+	fs := a.flagSet("groups set")
+	state := stateFlags(fs)
+
+	args, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	keyframe, err := state.keyframe()
+	if err != nil {
+		return err
+	}
+
+	id, err := a.group(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.FadeGroup(context.Background(), id, []hue.FadeKeyframe{keyframe})
+}
+
+func groupsIdentify(a *app, args []string) error {
+	fs := a.flagSet("groups identify")
+	long := fs.Bool("long", false, "breathe for 15 seconds instead of once")
+
+	args, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.group(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.IdentifyGroup(id, alert(*long))
+}
+
+func groupsCreate(a *app, args []string) error {
+	fs := a.flagSet("groups create")
+	types := make([]string, len(hue.GroupTypes))
+	for i, t := range hue.GroupTypes {
+		types[i] = string(t)
+	}
+
+	groupType := fs.String("type", string(hue.GroupTypeLightGroup), "group `type`: "+strings.Join(types, ", "))
+	class := fs.String("class", "", "`class` of a Room, Zone, or Entertainment group, such as Living room")
+
+	args, err := parse(fs, args, 2, -1)
+	if err != nil {
+		return err
+	}
+
+	lights, err := a.lights(args[1:])
+	if err != nil {
+		return err
+	}
+
+	return a.h.CreateGroup(args[0], hue.GroupType(*groupType), hue.RoomClass(*class), lights)
+}
+
+func groupsLights(a *app, args []string) error {
+	args, err := parse(a.flagSet("groups lights"), args, 2, -1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.group(args[0])
+	if err != nil {
+		return err
+	}
+
+	lights, err := a.lights(args[1:])
+	if err != nil {
+		return err
+	}
+
+	return a.h.SetLightsInGroup(id, lights)
+}
+
+func groupsClass(a *app, args []string) error {
+	args, err := parse(a.flagSet("groups class"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.group(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.SetGroupClass(id, hue.RoomClass(args[1]))
+}
+
+func groupsRename(a *app, args []string) error {
+	args, err := parse(a.flagSet("groups rename"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.group(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.RenameGroup(id, args[1])
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattvella07/hue"
+)
+
+var lightsResource = resource{
+	name:    "lights",
+	summary: "list and control lights",
+	commands: []command{
+		{"list", "", "list all lights", lightsList},
+		{"get", "<light>", "show a light", lightsGet},
+		{"on", "<light>", "turn a light on", lightsOn},
+		{"off", "<light>", "turn a light off", lightsOff},
+		{"set", "<light> [-bri n] [-ct mireds] [-xy x,y] [-transition d]", "turn a light on and set its state", lightsSet},
+		{"identify", "<light> [-long]", "make a light breathe so it can be found", lightsIdentify},
+		{"startup", "<light> <mode> [-bri n] [-ct mireds] [-xy x,y]", "set the state a light powers on to", lightsStartup},
+		{"rename", "<light> <name>", "rename a light", lightsRename},
+		{"delete", "<light>", "delete a light", lightsDelete},
+		{"search", "[serial...] [-timeout d]", "search for new lights", lightsSearch},
+		{"new", "", "list the lights found by the last search", lightsNew},
+	},
+}
+
+func lightsTable(lights []hue.Light) table {
+	t := table{headers: []string{"ID", "NAME", "ON", "BRI", "COLORMODE", "REACHABLE", "TYPE"}}
+
+	for _, l := range lights {
+		t.add(itoa(l.ID), l.Name, onOff(l.State.On), itoa(l.State.Bri), orDash(l.State.ColorMode), yesNo(l.State.Reachable), l.Type)
+	}
+
+	return t
+}
+
+// light resolves a light name or ID
+func (a *app) light(name string) (int, error) {
+	id, err := a.h.ResolveLight(name)
+	return id, resolveError(err)
+}
+
+// lights resolves a list of light names or IDs
+func (a *app) lights(names []string) ([]int, error) {
+	ids := make([]int, len(names))
+	for i, name := range names {
+		id, err := a.light(name)
+		if err != nil {
+			return nil, err
+		}
+
+		ids[i] = id
+	}
+
+	return ids, nil
+}
+
+func lightsList(a *app, args []string) error {
+	_, err := parse(a.flagSet("lights list"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	lights, err := a.h.GetLights()
+	if err != nil {
+		return err
+	}
+
+	return a.print(lights, lightsTable(lights))
+}
+
+func lightsGet(a *app, args []string) error {
+	args, err := parse(a.flagSet("lights get"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.light(args[0])
+	if err != nil {
+		return err
+	}
+
+	light, err := a.h.GetLight(id)
+	if err != nil {
+		return err
+	}
+	light.ID = id
+
+	return a.print(light, lightsTable([]hue.Light{light}))
+}
+
+func lightsOn(a *app, args []string) error {
+	return a.withLight("lights on", args, a.h.TurnOnLight)
+}
+
+func lightsOff(a *app, args []string) error {
+	return a.withLight("lights off", args, a.h.TurnOffLight)
+}
+
+func lightsDelete(a *app, args []string) error {
+	return a.withLight("lights delete", args, a.h.DeleteLight)
+}
+
+// withLight runs a command that takes a single light
+func (a *app) withLight(name string, args []string, fn func(light int) error) error {
+	args, err := parse(a.flagSet(name), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.light(args[0])
+	if err != nil {
+		return err
+	}
+
+	return fn(id)
+}
+
+func lightsSet(a *app, args []string) error {
+	fs := a.flagSet("lights set")
+	state := stateFlags(fs)
+
+	args, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	keyframe, err := state.keyframe()
+	if err != nil {
+		return err
+	}
+
+	id, err := a.light(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.FadeLight(context.Background(), id, []hue.FadeKeyframe{keyframe})
+}
+
+func lightsIdentify(a *app, args []string) error {
+	fs := a.flagSet("lights identify")
+	long := fs.Bool("long", false, "breathe for 15 seconds instead of once")
+
+	args, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.light(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.IdentifyLight(id, alert(*long))
+}
+
+func lightsStartup(a *app, args []string) error {
+	fs := a.flagSet("lights startup")
+	bri := fs.Int("bri", 0, "custom brightness, from 1 to 254")
+	ct := fs.Int("ct", 0, "custom color temperature in `mireds`")
+	xy := fs.String("xy", "", "custom color as `x,y`")
+
+	args, err := parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	custom := hue.LightStartupSettings{Bri: *bri, CT: *ct}
+	if *xy != "" {
+		custom.XY, err = parseXY(*xy)
+		if err != nil {
+			return err
+		}
+	}
+
+	id, err := a.light(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.SetLightStartup(id, args[1], custom)
+}
+
+func lightsRename(a *app, args []string) error {
+	args, err := parse(a.flagSet("lights rename"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.light(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.RenameLight(id, args[1])
+}
+
+func lightsSearch(a *app, args []string) error {
+	fs := a.flagSet("lights search")
+	timeout := fs.Duration("timeout", 60*time.Second, "how long to wait for the search to finish")
+
+	serials, err := parse(fs, args, 0, -1)
+	if err != nil {
+		return err
+	}
+
+	if len(serials) == 0 {
+		err = a.h.FindNewLights()
+	} else {
+		err = a.h.SearchLightsBySerial(serials)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	newLights, err := a.h.WaitForLightSearch(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.printNewLights(newLights)
+}
+
+func lightsNew(a *app, args []string) error {
+	_, err := parse(a.flagSet("lights new"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	newLights, err := a.h.GetNewLights()
+	if err != nil {
+		return err
+	}
+
+	return a.printNewLights(newLights)
+}
+
+func (a *app) printNewLights(newLights hue.NewLightResponse) error {
+	t := table{headers: []string{"ID", "NAME"}}
+	for _, l := range newLights.NewLights {
+		t.add(itoa(l.ID), l.Name)
+	}
+
+	return a.print(newLights, t)
+}
+
+// state contains the flags used to set the state of a light or group
+type state struct {
+	bri        *int
+	ct         *int
+	xy         *string
+	transition *time.Duration
+}
+
+func stateFlags(fs *flag.FlagSet) state {
+	return state{
+		bri:        fs.Int("bri", 0, "brightness, from 1 to 254"),
+		ct:         fs.Int("ct", 0, "color temperature in `mireds`, from 153 to 500"),
+		xy:         fs.String("xy", "", "color as `x,y`"),
+		transition: fs.Duration("transition", 0, "how long the change takes"),
+	}
+}
+
+// keyframe returns the fade keyframe for the state
+func (s state) keyframe() (hue.FadeKeyframe, error) {
+	keyframe := hue.FadeKeyframe{
+		Bri:      *s.bri,
+		CT:       *s.ct,
+		Duration: *s.transition,
+	}
+
+	if *s.xy != "" {
+		xy, err := parseXY(*s.xy)
+		if err != nil {
+			return hue.FadeKeyframe{}, err
+		}
+
+		keyframe.XY = xy
+	}
+
+	if keyframe.Bri == 0 && keyframe.CT == 0 && keyframe.XY == nil {
+		return hue.FadeKeyframe{}, usagef("at least one of -bri, -ct, or -xy must be set")
+	}
+
+	return keyframe, nil
+}
+
+// parseXY parses a color of the form x,y
+func parseXY(value string) ([]float32, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return nil, usagef("invalid color %s: must be of the form x,y", value)
+	}
+
+	xy := make([]float32, 2)
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, usagef("invalid color %s: must be of the form x,y", value)
+		}
+
+		xy[i] = float32(f)
+	}
+
+	return xy, nil
+}
+
+// alert returns the alert that breathes once, or for 15 seconds if long is true
+func alert(long bool) string {
+	if long {
+		return hue.AlertLSelect
+	}
+
+	return hue.AlertSelect
+}
@@ -0,0 +1,450 @@
+// Command hue controls a Phillips Hue bridge from the command line.
+//
+// Usage:
+//
+//	hue [flags] <resource> <command> [arguments]
+//
+// Lights, groups, scenes, and other resources can be referred to by name or ID.
+// Results are printed as a table, or as JSON or JSON lines for scripting.
+//
+// Bridges are configured as named profiles in ~/.config/hue/config.json. The
+// default profile is used unless -profile, or -user, is given. Pairing with a
+// bridge saves its profile.
+//
+// The exit code is 0 on success, 1 for errors reported by the bridge or the
+// library, 2 for invalid usage, 3 if a resource wasn't found, 4 if a name
+// matches more than one resource, 5 if the bridge couldn't be reached, and 6 if
+// the user isn't authorized or the link button wasn't pressed.
+//
+// The dashboard shows rooms, lights, and sensors in the terminal and controls
+// them from the keyboard. The -demo flag runs any command against an emulated
+// bridge, for example:
+//
+//	hue -demo dashboard
+//
+// The serve, mqtt, and exporter commands run until interrupted, sharing the
+// bridge with other clients over a REST API, an MQTT broker, or Prometheus
+// metrics:
+//
+//	hue -demo mqtt -embedded 127.0.0.1:1883
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mattvella07/hue"
+)
+
+// Exit codes
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitNotFound     = 3
+	exitAmbiguous    = 4
+	exitUnreachable  = 5
+	exitUnauthorized = 6
+)
+
+// app contains the state shared by all commands
+type app struct {
+	h          *hue.Connection
+	stdout     io.Writer
+	stderr     io.Writer
+	format     string
+	profile    string
+	configPath string
+}
+
+// command is a single command of a resource, such as lights list
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(a *app, args []string) error
+}
+
+// resource is a group of commands, such as lights
+type resource struct {
+	name     string
+	summary  string
+	commands []command
+}
+
+// resources contains every resource the command line tool supports. Commands
+// that don't belong to a resource, such as pair, have a single unnamed command.
+var resources = []resource{
+	lightsResource,
+	groupsResource,
+	scenesResource,
+	schedulesResource,
+	rulesResource,
+	sensorsResource,
+	resourceLinksResource,
+	configResource,
+	dashboardResource,
+	serveResource,
+	mqttResource,
+	exporterResource,
+	profilesResource,
+	pairResource,
+}
+
+// usageError is returned when a command is used incorrectly
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// notFoundError is returned when a name or ID doesn't match a resource
+type notFoundError struct {
+	err error
+}
+
+func (e *notFoundError) Error() string {
+	return e.err.Error()
+}
+
+func (e *notFoundError) Unwrap() error {
+	return e.err
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command line tool and returns its exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	a := &app{stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("hue", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { a.usage() }
+
+	bridge := fs.String("bridge", os.Getenv("HUE_BRIDGE"), "`address` of the bridge, discovered if empty (env HUE_BRIDGE)")
+	user := fs.String("user", defaultUser(), "`username` on the bridge (env HUE_USER)")
+	profile := fs.String("profile", os.Getenv("HUE_PROFILE"), "`name` of the profile to use (env HUE_PROFILE)")
+	config := fs.String("config", defaultConfigPath(), "`path` of the profiles file (env HUE_CONFIG)")
+	demo := fs.Bool("demo", false, "use an emulated bridge with example rooms, lights, and sensors")
+	a.outputFlags(fs)
+
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+
+	a.configPath = *config
+	a.profile = *profile
+
+	a.h = &hue.Connection{
+		UserID:  *user,
+		Address: *bridge,
+	}
+
+	// The demo bridge only lasts as long as the command
+	if *demo {
+		s, err := newDemoServer()
+		if err != nil {
+			fmt.Fprintf(stderr, "hue: %s\n", err)
+			return exitError
+		}
+		defer s.Close()
+
+		a.h = s.Connection()
+
+		return a.dispatch(fs.Args())
+	}
+
+	// Use a profile unless a user was given without one. The bridge and user
+	// flags override the profile.
+	if *profile != "" || *user == "" {
+		err = a.useProfile(*profile, *bridge, *user)
+
+		// Pairing creates the profile if it doesn't exist
+		var notFoundErr *notFoundError
+		isPair := fs.NArg() > 0 && fs.Arg(0) == pairResource.name
+		if err != nil && *profile != "" && !(isPair && errors.As(err, &notFoundErr)) {
+			fmt.Fprintf(stderr, "hue: %s\n", err)
+			return exitCode(err)
+		}
+	}
+
+	return a.dispatch(fs.Args())
+}
+
+// useProfile connects using the profile with the specified name, or the
+// default profile if the name is empty
+func (a *app) useProfile(name, bridge, user string) error {
+	profiles, err := hue.LoadProfiles(a.configPath)
+	if err != nil {
+		return err
+	}
+
+	p, err := profiles.Profile(name)
+	if err != nil {
+		return &notFoundError{err: err}
+	}
+
+	a.h = p.Connection()
+	if bridge != "" {
+		a.h.Address = bridge
+	}
+	if user != "" {
+		a.h.UserID = user
+	}
+
+	if a.format == "" && p.Defaults["output"] != "" {
+		return a.setFormat(p.Defaults["output"])
+	}
+
+	return nil
+}
+
+// defaultConfigPath returns the path of the profiles file from the
+// environment, or the library's default path
+func defaultConfigPath() string {
+	if path := os.Getenv("HUE_CONFIG"); path != "" {
+		return path
+	}
+
+	path, _ := hue.DefaultProfilesPath()
+
+	return path
+}
+
+// defaultUser returns the username from the environment. hueUserID is used by
+// the examples and is still supported.
+func defaultUser() string {
+	if user := os.Getenv("HUE_USER"); user != "" {
+		return user
+	}
+
+	return os.Getenv("hueUserID")
+}
+
+// dispatch runs the command named by the arguments
+func (a *app) dispatch(args []string) int {
+	if len(args) == 0 || args[0] == "help" {
+		a.usage()
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	r, ok := findResource(args[0])
+	if !ok {
+		fmt.Fprintf(a.stderr, "hue: unknown command %s\n", args[0])
+		a.usage()
+		return exitUsage
+	}
+
+	// Commands without a resource take their arguments directly
+	if len(r.commands) == 1 && r.commands[0].name == "" {
+		return a.runCommand(r, r.commands[0], args[1:])
+	}
+
+	if len(args) < 2 || args[1] == "help" {
+		a.resourceUsage(r)
+		if len(args) < 2 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	for _, c := range r.commands {
+		if c.name == args[1] {
+			return a.runCommand(r, c, args[2:])
+		}
+	}
+
+	fmt.Fprintf(a.stderr, "hue: unknown command %s %s\n", r.name, args[1])
+	a.resourceUsage(r)
+
+	return exitUsage
+}
+
+func (a *app) runCommand(r resource, c command, args []string) int {
+	err := c.run(a, args)
+	if err == nil || err == flag.ErrHelp {
+		return exitOK
+	}
+
+	fmt.Fprintf(a.stderr, "hue: %s\n", err)
+
+	code := exitCode(err)
+	if code == exitUsage {
+		fmt.Fprintf(a.stderr, "usage: hue %s\n", strings.TrimSpace(strings.Join([]string{r.name, c.name, c.args}, " ")))
+	}
+
+	return code
+}
+
+// exitCode returns the exit code for an error
+func exitCode(err error) int {
+	var usageErr *usageError
+	var notFoundErr *notFoundError
+	var ambiguousErr *hue.AmbiguousNameError
+	var bridgeErrs hue.BridgeErrors
+	var urlErr *url.Error
+	var opErr *net.OpError
+
+	switch {
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &ambiguousErr):
+		return exitAmbiguous
+	case errors.As(err, &notFoundErr):
+		return exitNotFound
+	case errors.As(err, &bridgeErrs):
+		if bridgeErrs.HasType(hue.ErrorUnauthorizedUser) || bridgeErrs.HasType(hue.ErrorLinkButtonNotPressed) {
+			return exitUnauthorized
+		}
+		return exitError
+	case errors.As(err, &urlErr), errors.As(err, &opErr):
+		return exitUnreachable
+	}
+
+	return exitError
+}
+
+func findResource(name string) (resource, bool) {
+	for _, r := range resources {
+		if r.name == name {
+			return r, true
+		}
+	}
+
+	return resource{}, false
+}
+
+func (a *app) usage() {
+	fmt.Fprintln(a.stderr, "usage: hue [flags] <resource> <command> [arguments]")
+	fmt.Fprintln(a.stderr)
+	fmt.Fprintln(a.stderr, "Resources:")
+
+	for _, r := range resources {
+		fmt.Fprintf(a.stderr, "  %-15s %s\n", r.name, r.summary)
+	}
+
+	fmt.Fprintln(a.stderr)
+	fmt.Fprintln(a.stderr, "Flags:")
+	fmt.Fprintln(a.stderr, "  -bridge address  address of the bridge, discovered if empty (env HUE_BRIDGE)")
+	fmt.Fprintln(a.stderr, "  -user username   username on the bridge (env HUE_USER)")
+	fmt.Fprintln(a.stderr, "  -profile name    profile to use, or the default profile (env HUE_PROFILE)")
+	fmt.Fprintln(a.stderr, "  -config path     profiles file (env HUE_CONFIG)")
+	fmt.Fprintln(a.stderr, "  -demo            use an emulated bridge with example rooms, lights, and sensors")
+	fmt.Fprintln(a.stderr, "  -o format        output format: table, json, or jsonl")
+	fmt.Fprintln(a.stderr)
+	fmt.Fprintln(a.stderr, "Run 'hue <resource> help' for the commands of a resource.")
+}
+
+func (a *app) resourceUsage(r resource) {
+	fmt.Fprintf(a.stderr, "usage: hue %s <command> [arguments]\n", r.name)
+	fmt.Fprintln(a.stderr)
+	fmt.Fprintln(a.stderr, "Commands:")
+
+	w := tabwriter.NewWriter(a.stderr, 0, 4, 2, ' ', 0)
+	for _, c := range r.commands {
+		fmt.Fprintf(w, "  %s\t%s\n", strings.TrimSpace(c.name+" "+c.args), c.summary)
+	}
+	w.Flush()
+}
+
+// flagSet creates the flags for a command, including the output flags
+func (a *app) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("hue "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	a.outputFlags(fs)
+
+	return fs
+}
+
+func (a *app) outputFlags(fs *flag.FlagSet) {
+	fs.Func("o", "output `format`: table, json, or jsonl", a.setFormat)
+	fs.Func("output", "output `format`: table, json, or jsonl", a.setFormat)
+}
+
+func (a *app) setFormat(format string) error {
+	switch format {
+	case formatTable, formatJSON, formatJSONL:
+		a.format = format
+		return nil
+	}
+
+	return fmt.Errorf("output format must be one of the following: %s, %s, %s", formatTable, formatJSON, formatJSONL)
+}
+
+// parse parses the flags and arguments of a command, which may be mixed, and
+// checks that there are between min and max arguments. If max is -1 there is
+// no limit.
+func parse(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	positional := []string{}
+
+	for {
+		err := fs.Parse(args)
+		if err == flag.ErrHelp {
+			return nil, err
+		}
+		if err != nil {
+			return nil, &usageError{message: err.Error()}
+		}
+
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+
+		// Everything after -- is an argument
+		if len(args) > len(rest) && args[len(args)-len(rest)-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	if len(positional) < min || (max >= 0 && len(positional) > max) {
+		return nil, usagef("wrong number of arguments")
+	}
+
+	return positional, nil
+}
+
+// resolveError marks errors from resolving a name as not found, unless the
+// bridge couldn't be asked or more than one resource matched
+func resolveError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ambiguousErr *hue.AmbiguousNameError
+	var bridgeErrs hue.BridgeErrors
+	var urlErr *url.Error
+	var opErr *net.OpError
+
+	if errors.As(err, &ambiguousErr) || errors.As(err, &bridgeErrs) || errors.As(err, &urlErr) || errors.As(err, &opErr) || errors.Is(err, hue.ErrDiscovery) {
+		return err
+	}
+
+	return &notFoundError{err: err}
+}
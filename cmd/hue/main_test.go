@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattvella07/hue"
+	"github.com/mattvella07/hue/huetest"
+)
+
+// createTestServer creates an emulated bridge with a few lights, a room, and a
+// scene
+func createTestServer() *huetest.Server {
+	s := huetest.NewServer()
+	s.Bridge.AddLight("Desk", huetest.ExtendedColorLight)
+	s.Bridge.AddLight("Floor lamp", huetest.ColorTemperatureLight)
+	s.Bridge.AddLight("Hall", huetest.DimmableLight)
+	s.Bridge.AddGroup("Office", "Room", []string{"1", "2"})
+	s.Bridge.AddSensor("Motion", huetest.PresenceSensor)
+
+	return s
+}
+
+// runCLI runs the command line tool against the server, with a profiles file
+// in a temporary directory
+func runCLI(t *testing.T, s *huetest.Server, args ...string) (string, string, int) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	args = append([]string{"-config", filepath.Join(t.TempDir(), "config.json"), "-bridge", s.Address(), "-user", s.Username}, args...)
+	code := run(args, stdout, stderr)
+
+	return stdout.String(), stderr.String(), code
+}
+
+func expectCode(t *testing.T, expected int, stdout, stderr string, code int) {
+	t.Helper()
+
+	if code != expected {
+		t.Fatalf("Expected exit code %d, got %d\nstdout: %s\nstderr: %s", expected, code, stdout, stderr)
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	t.Run("Table", func(t *testing.T) {
+		stdout, stderr, code := runCLI(t, s, "lights", "list")
+		expectCode(t, exitOK, stdout, stderr, code)
+
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("Expected a header and 3 lights, got %q", stdout)
+		}
+
+		if !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[2], "Floor lamp") {
+			t.Fatalf("Unexpected table %q", stdout)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		stdout, stderr, code := runCLI(t, s, "-o", "json", "lights", "list")
+		expectCode(t, exitOK, stdout, stderr, code)
+
+		lights := []hue.Light{}
+
+		err := json.Unmarshal([]byte(stdout), &lights)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(lights) != 3 || lights[1].Name != "Floor lamp" || lights[1].ID != 2 {
+			t.Fatalf("Unexpected lights %+v", lights)
+		}
+	})
+
+	t.Run("JSON lines", func(t *testing.T) {
+		// Flags may also follow the command
+		stdout, stderr, code := runCLI(t, s, "groups", "list", "-o", "jsonl")
+		expectCode(t, exitOK, stdout, stderr, code)
+
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		if len(lines) != 1 {
+			t.Fatalf("Expected 1 group, got %q", stdout)
+		}
+
+		group := hue.Group{}
+
+		err := json.Unmarshal([]byte(lines[0]), &group)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if group.Name != "Office" {
+			t.Fatalf("Expected Office, got %s", group.Name)
+		}
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		stdout, stderr, code := runCLI(t, s, "lights", "list", "-o", "xml")
+		expectCode(t, exitUsage, stdout, stderr, code)
+	})
+}
+
+func TestLightCommands(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	// Lights can be referred to by name or ID
+	stdout, stderr, code := runCLI(t, s, "lights", "on", "floor lamp")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	if on, _ := s.Bridge.Get("lights/2/state/on"); on != true {
+		t.Fatalf("Expected light 2 to be on, got %v", on)
+	}
+
+	stdout, stderr, code = runCLI(t, s, "lights", "set", "1", "-bri", "100", "-xy", "0.3,0.4")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	if bri, _ := s.Bridge.Get("lights/1/state/bri"); bri != float64(100) {
+		t.Fatalf("Expected bri 100, got %v", bri)
+	}
+
+	stdout, stderr, code = runCLI(t, s, "lights", "rename", "Hall", "Hallway")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	stdout, stderr, code = runCLI(t, s, "lights", "get", "Hallway", "-o", "json")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	if !strings.Contains(stdout, `"id": 3`) {
+		t.Fatalf("Expected light 3, got %s", stdout)
+	}
+
+	// The state must contain something to set
+	stdout, stderr, code = runCLI(t, s, "lights", "set", "1")
+	expectCode(t, exitUsage, stdout, stderr, code)
+}
+
+func TestGroupAndSceneCommands(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	stdout, stderr, code := runCLI(t, s, "groups", "create", "Lounge", "Hall", "-type", "LightGroup")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	stdout, stderr, code = runCLI(t, s, "groups", "create", "Study", "Desk", "-type", "Room", "-class", "Office")
+	expectCode(t, exitError, stdout, stderr, code)
+
+	if !strings.Contains(stderr, "Light 1 is already in room Office (1)") {
+		t.Fatalf("Unexpected error %s", stderr)
+	}
+
+	stdout, stderr, code = runCLI(t, s, "scenes", "create", "Bright", "-group", "Office")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	stdout, stderr, code = runCLI(t, s, "groups", "off", "Office")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	// Group 0 contains every light
+	stdout, stderr, code = runCLI(t, s, "groups", "on", "0")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	stdout, stderr, code = runCLI(t, s, "groups", "rename", "0", "Everything")
+	expectCode(t, exitError, stdout, stderr, code)
+
+	stdout, stderr, code = runCLI(t, s, "scenes", "recall", "Office", "Bright", "-transition", "2s")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	stdout, stderr, code = runCLI(t, s, "scenes", "recall", "Office", "Bright")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	stdout, stderr, code = runCLI(t, s, "scenes", "recall", "Office", "Bright", "-transition", "-1s")
+	expectCode(t, exitUsage, stdout, stderr, code)
+
+	stdout, stderr, code = runCLI(t, s, "scenes", "list", "-group", "Office")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	if !strings.Contains(stdout, "Bright") {
+		t.Fatalf("Expected the scene to be listed, got %s", stdout)
+	}
+
+	stdout, stderr, code = runCLI(t, s, "groups", "list")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	if !strings.Contains(stdout, "Lounge") {
+		t.Fatalf("Expected the new group to be listed, got %s", stdout)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	t.Run("Not found", func(t *testing.T) {
+		stdout, stderr, code := runCLI(t, s, "lights", "on", "Garage")
+		expectCode(t, exitNotFound, stdout, stderr, code)
+
+		if !strings.Contains(stderr, "Light Garage not found") {
+			t.Fatalf("Unexpected error %s", stderr)
+		}
+	})
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		s.Bridge.AddLight("Desk", huetest.DimmableLight)
+
+		stdout, stderr, code := runCLI(t, s, "lights", "on", "Desk")
+		expectCode(t, exitAmbiguous, stdout, stderr, code)
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		for _, args := range [][]string{
+			{},
+			{"bulbs"},
+			{"lights"},
+			{"lights", "explode"},
+			{"lights", "rename", "1"},
+			{"lights", "list", "-nope"},
+			{"serve"},
+			{"serve", "-token", "secret"},
+			{"mqtt"},
+			{"exporter", "extra"},
+			{"exporter", "-path", "metrics"},
+			{"mqtt", "-broker", "localhost:1883", "-embedded", "127.0.0.1:0"},
+		} {
+			stdout, stderr, code := runCLI(t, s, args...)
+			expectCode(t, exitUsage, stdout, stderr, code)
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+
+		code := run([]string{"-config", filepath.Join(t.TempDir(), "config.json"), "-bridge", s.Address(), "-user", "nobody", "lights", "list"}, stdout, stderr)
+		expectCode(t, exitUnauthorized, stdout.String(), stderr.String(), code)
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		closed := huetest.NewServer()
+		closed.Close()
+
+		stdout, stderr, code := runCLI(t, closed, "lights", "list")
+		expectCode(t, exitUnreachable, stdout, stderr, code)
+	})
+}
+
+func TestPair(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	s.Bridge.PressLinkButton()
+
+	stdout, stderr, code := runCLI(t, s, "pair", "-devicetype", "hue#test", "-o", "json")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	result := pairResult{}
+
+	err := json.Unmarshal([]byte(stdout), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Bridge.Get("config/whitelist/" + result.Username); !ok {
+		t.Fatalf("Expected %s to be in the whitelist", result.Username)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/mattvella07/hue/mqtt"
+)
+
+// mqttRetryInterval is how long to wait before reconnecting to the broker
+const mqttRetryInterval = 5 * time.Second
+
+var mqttResource = resource{
+	name:    "mqtt",
+	summary: "publish the bridge's state to an MQTT broker and accept commands from it",
+	commands: []command{
+		{"", "-broker host:port | -embedded address [-username u] [-password p] [-prefix p] [-name n] [-discovery-prefix p] [-interval d]", "publish the bridge's state to an MQTT broker and accept commands from it", runMQTT},
+	},
+}
+
+func runMQTT(a *app, args []string) error {
+	config := mqtt.Config{}
+
+	fs := a.flagSet("mqtt")
+	fs.StringVar(&config.Broker, "broker", "", "`address` of the MQTT broker, as host:port")
+	embedded := fs.String("embedded", "", "run a built-in test broker on `address` instead of connecting to -broker")
+	fs.StringVar(&config.Username, "username", "", "username for the broker")
+	fs.StringVar(&config.Password, "password", os.Getenv("HUE_MQTT_PASSWORD"), "password for the broker (env HUE_MQTT_PASSWORD)")
+	fs.StringVar(&config.Prefix, "prefix", "hue", "first level of every topic")
+	fs.StringVar(&config.Bridge, "name", "", "second level of every topic, defaults to the bridge ID")
+	fs.StringVar(&config.DiscoveryPrefix, "discovery-prefix", "homeassistant", "prefix for Home Assistant discovery, or - to disable it")
+	fs.DurationVar(&config.Interval, "interval", 2*time.Second, "how often the bridge is polled for changes")
+
+	_, err := parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	if (config.Broker == "") == (*embedded == "") {
+		return usagef("exactly one of -broker and -embedded is required")
+	}
+
+	if config.Interval <= 0 {
+		return usagef("-interval must be greater than 0")
+	}
+
+	// Fail early if the bridge can't be reached or the user isn't authorized
+	_, err = a.h.GetLights()
+	if err != nil {
+		return err
+	}
+
+	if *embedded != "" {
+		broker := mqtt.NewBroker()
+		defer broker.Close()
+
+		config.Broker, err = broker.Listen(*embedded)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(a.stderr, "Running a test broker on %s\n", config.Broker)
+	}
+
+	config.OnError = func(err error) {
+		fmt.Fprintf(a.stderr, "Command failed: %s\n", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintf(a.stderr, "Publishing the bridge to %s\n", config.Broker)
+
+	// Reconnect until interrupted
+	for {
+		err := mqtt.Run(ctx, a.h, config)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		fmt.Fprintf(a.stderr, "%s, reconnecting in %s\n", err, mqttRetryInterval)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(mqttRetryInterval):
+		}
+	}
+}
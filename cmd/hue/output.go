@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatJSONL = "jsonl"
+)
+
+// table contains the output of a command in the table format
+type table struct {
+	headers []string
+	rows    [][]string
+}
+
+func (t *table) add(row ...string) {
+	t.rows = append(t.rows, row)
+}
+
+// print writes the result of a command in the selected format. The value is
+// used for the JSON formats, and the table for the table format. Slices are
+// written one element per line in the JSON lines format.
+func (a *app) print(value interface{}, t table) error {
+	switch a.format {
+	case formatJSON:
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(a.stdout, "%s\n", data)
+		return err
+	case formatJSONL:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice {
+			return writeJSONLine(a, value)
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			err := writeJSONLine(a, v.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+
+	if len(t.headers) > 0 {
+		fmt.Fprintln(w, strings.Join(t.headers, "\t"))
+	}
+
+	for _, row := range t.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+func writeJSONLine(a *app, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(a.stdout, "%s\n", data)
+
+	return err
+}
+
+// Formatting helpers for table cells
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+
+	return "off"
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+
+	return "no"
+}
+
+func itoa(i int) string {
+	return strconv.Itoa(i)
+}
+
+func list(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+
+	return strings.Join(values, ",")
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattvella07/hue"
+)
+
+var profilesResource = resource{
+	name:    "profiles",
+	summary: "list and manage bridge profiles",
+	commands: []command{
+		{"list", "", "list all profiles", profilesList},
+		{"use", "<profile>", "make a profile the default", profilesUse},
+		{"delete", "<profile>", "delete a profile", profilesDelete},
+	},
+}
+
+// profileResult is the output of profiles list
+type profileResult struct {
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+	hue.Profile
+}
+
+func profilesList(a *app, args []string) error {
+	_, err := parse(a.flagSet("profiles list"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	profiles, err := hue.LoadProfiles(a.configPath)
+	if err != nil {
+		return err
+	}
+
+	results := []profileResult{}
+	t := table{headers: []string{"NAME", "DEFAULT", "BRIDGEID", "ADDRESS"}}
+
+	for _, name := range profiles.Names() {
+		p := profiles.Profiles[name]
+		isDefault := name == profiles.Default
+
+		results = append(results, profileResult{Name: name, Default: isDefault, Profile: p})
+		t.add(name, yesNo(isDefault), orDash(p.BridgeID), orDash(p.Address))
+	}
+
+	return a.print(results, t)
+}
+
+func profilesUse(a *app, args []string) error {
+	args, err := parse(a.flagSet("profiles use"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	return a.updateProfiles(func(profiles *hue.Profiles) error {
+		_, err := profiles.Profile(args[0])
+		if err != nil {
+			return &notFoundError{err: err}
+		}
+
+		profiles.Default = args[0]
+
+		return nil
+	})
+}
+
+func profilesDelete(a *app, args []string) error {
+	args, err := parse(a.flagSet("profiles delete"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	return a.updateProfiles(func(profiles *hue.Profiles) error {
+		_, err := profiles.Profile(args[0])
+		if err != nil {
+			return &notFoundError{err: err}
+		}
+
+		delete(profiles.Profiles, args[0])
+		if profiles.Default == args[0] {
+			profiles.Default = ""
+		}
+
+		return nil
+	})
+}
+
+// saveProfile adds or replaces a profile in the profiles file
+func (a *app) saveProfile(name string, profile hue.Profile) error {
+	err := a.updateProfiles(func(profiles *hue.Profiles) error {
+		return profiles.Set(name, profile)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stderr, "Saved profile %s to %s\n", name, a.configPath)
+
+	return nil
+}
+
+// updateProfiles loads the profiles file, changes it, and saves it
+func (a *app) updateProfiles(fn func(profiles *hue.Profiles) error) error {
+	if a.configPath == "" {
+		return fmt.Errorf("unable to determine the path of the profiles file, use -config")
+	}
+
+	profiles, err := hue.LoadProfiles(a.configPath)
+	if err != nil {
+		return err
+	}
+
+	err = fn(profiles)
+	if err != nil {
+		return err
+	}
+
+	return profiles.Save(a.configPath)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattvella07/hue"
+)
+
+func TestProfiles(t *testing.T) {
+	s := createTestServer()
+	defer s.Close()
+
+	config := filepath.Join(t.TempDir(), "hue", "config.json")
+
+	runWithConfig := func(args ...string) (string, string, int) {
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+
+		code := run(append([]string{"-config", config}, args...), stdout, stderr)
+
+		return stdout.String(), stderr.String(), code
+	}
+
+	// Pairing saves a profile, even if it doesn't exist yet
+	s.Bridge.PressLinkButton()
+
+	stdout, stderr, code := runWithConfig("-profile", "lab", "-bridge", s.Address(), "pair")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	profiles, err := hue.LoadProfiles(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := profiles.Profile("lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if profile.Username != strings.TrimSpace(stdout) || profile.Address != s.Address() || profile.BridgeID == "" {
+		t.Fatalf("Unexpected profile %+v", profile)
+	}
+
+	// The first profile is the default
+	stdout, stderr, code = runWithConfig("lights", "list")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	if !strings.Contains(stdout, "Floor lamp") {
+		t.Fatalf("Expected lights to be listed, got %s", stdout)
+	}
+
+	// Profiles can set the default output format
+	profile.Defaults = map[string]string{"output": "jsonl"}
+	profiles.Set("office", profile)
+
+	err = profiles.Save(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, code = runWithConfig("-profile", "office", "groups", "list")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	if !strings.HasPrefix(stdout, "{") {
+		t.Fatalf("Expected JSON lines, got %s", stdout)
+	}
+
+	stdout, stderr, code = runWithConfig("profiles", "use", "office")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	stdout, stderr, code = runWithConfig("profiles", "list")
+	expectCode(t, exitOK, stdout, stderr, code)
+
+	if !strings.Contains(stdout, "office") || !strings.Contains(stdout, "lab") {
+		t.Fatalf("Expected both profiles, got %s", stdout)
+	}
+
+	stdout, stderr, code = runWithConfig("-profile", "home", "lights", "list")
+	expectCode(t, exitNotFound, stdout, stderr, code)
+
+	stdout, stderr, code = runWithConfig("profiles", "delete", "home")
+	expectCode(t, exitNotFound, stdout, stderr, code)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"github.com/mattvella07/hue"
+)
+
+var resourceLinksResource = resource{
+	name:    "resourcelinks",
+	summary: "list and manage resource links",
+	commands: []command{
+		{"list", "", "list all resource links", resourceLinksList},
+		{"get", "<resourcelink>", "show a resource link", resourceLinksGet},
+		{"create", "<name> <link...> [-description text]", "create a resource link to resources such as /groups/1", resourceLinksCreate},
+		{"describe", "<resourcelink> <description>", "set the description of a resource link", resourceLinksDescribe},
+		{"rename", "<resourcelink> <name>", "rename a resource link", resourceLinksRename},
+		{"delete", "<resourcelink>", "delete a resource link", resourceLinksDelete},
+	},
+}
+
+func resourceLinksTable(links []hue.ResourceLink) table {
+	t := table{headers: []string{"ID", "NAME", "CLASS", "DESCRIPTION", "LINKS"}}
+
+	for _, l := range links {
+		t.add(itoa(l.ID), l.Name, itoa(l.Class), orDash(l.Description), list(l.Links))
+	}
+
+	return t
+}
+
+// resourceLink resolves a resource link name or ID
+func (a *app) resourceLink(name string) (int, error) {
+	id, err := a.h.ResolveResourceLink(name)
+	return id, resolveError(err)
+}
+
+func resourceLinksList(a *app, args []string) error {
+	_, err := parse(a.flagSet("resourcelinks list"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	links, err := a.h.GetResourceLinks()
+	if err != nil {
+		return err
+	}
+
+	return a.print(links, resourceLinksTable(links))
+}
+
+func resourceLinksGet(a *app, args []string) error {
+	args, err := parse(a.flagSet("resourcelinks get"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.resourceLink(args[0])
+	if err != nil {
+		return err
+	}
+
+	link, err := a.h.GetResourceLink(id)
+	if err != nil {
+		return err
+	}
+	link.ID = id
+
+	return a.print(link, resourceLinksTable([]hue.ResourceLink{link}))
+}
+
+func resourceLinksCreate(a *app, args []string) error {
+	fs := a.flagSet("resourcelinks create")
+	description := fs.String("description", "", "description of the resource link")
+
+	args, err := parse(fs, args, 2, -1)
+	if err != nil {
+		return err
+	}
+
+	return a.h.CreateResourceLink(args[0], *description, false, args[1:])
+}
+
+func resourceLinksDescribe(a *app, args []string) error {
+	args, err := parse(a.flagSet("resourcelinks describe"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.resourceLink(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.SetResourceLinkDescription(id, args[1])
+}
+
+func resourceLinksRename(a *app, args []string) error {
+	args, err := parse(a.flagSet("resourcelinks rename"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.resourceLink(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.RenameResourceLink(id, args[1])
+}
+
+func resourceLinksDelete(a *app, args []string) error {
+	args, err := parse(a.flagSet("resourcelinks delete"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.resourceLink(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.DeleteResourceLink(id)
+}
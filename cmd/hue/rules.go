@@ -0,0 +1,94 @@
+package main
+
+import (
+	"github.com/mattvella07/hue"
+)
+
+var rulesResource = resource{
+	name:    "rules",
+	summary: "list and manage rules",
+	commands: []command{
+		{"list", "", "list all rules", rulesList},
+		{"get", "<rule>", "show a rule", rulesGet},
+		{"rename", "<rule> <name>", "rename a rule", rulesRename},
+		{"delete", "<rule>", "delete a rule", rulesDelete},
+	},
+}
+
+func rulesTable(rules []hue.Rule) table {
+	t := table{headers: []string{"ID", "NAME", "STATUS", "TRIGGERED", "LASTTRIGGERED"}}
+
+	for _, r := range rules {
+		t.add(itoa(r.ID), r.Name, r.Status, itoa(r.TimesTriggered), orDash(r.LastTriggered))
+	}
+
+	return t
+}
+
+// rule resolves a rule name or ID
+func (a *app) rule(name string) (int, error) {
+	id, err := a.h.ResolveRule(name)
+	return id, resolveError(err)
+}
+
+func rulesList(a *app, args []string) error {
+	_, err := parse(a.flagSet("rules list"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	rules, err := a.h.GetRules()
+	if err != nil {
+		return err
+	}
+
+	return a.print(rules, rulesTable(rules))
+}
+
+func rulesGet(a *app, args []string) error {
+	args, err := parse(a.flagSet("rules get"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.rule(args[0])
+	if err != nil {
+		return err
+	}
+
+	rule, err := a.h.GetRule(id)
+	if err != nil {
+		return err
+	}
+	rule.ID = id
+
+	return a.print(rule, rulesTable([]hue.Rule{rule}))
+}
+
+func rulesRename(a *app, args []string) error {
+	args, err := parse(a.flagSet("rules rename"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.rule(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.RenameRule(id, args[1])
+}
+
+func rulesDelete(a *app, args []string) error {
+	args, err := parse(a.flagSet("rules delete"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.rule(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.DeleteRule(id)
+}
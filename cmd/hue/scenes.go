@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/mattvella07/hue"
+)
+
+var scenesResource = resource{
+	name:    "scenes",
+	summary: "list, create, and recall scenes",
+	commands: []command{
+		{"list", "[-group group]", "list all scenes, or the scenes of a group", scenesList},
+		{"get", "<scene>", "show a scene", scenesGet},
+		{"recall", "<group> <scene> [-transition d]", "recall a scene in a group", scenesRecall},
+		{"create", "<name> [light...] [-group group]", "create a scene from the current state of lights or a group", scenesCreate},
+		{"lights", "<scene> <light...>", "set the lights in a scene", scenesLights},
+		{"rename", "<scene> <name>", "rename a scene", scenesRename},
+		{"delete", "<scene>", "delete a scene", scenesDelete},
+	},
+}
+
+func scenesTable(scenes []hue.Scene) table {
+	t := table{headers: []string{"ID", "NAME", "TYPE", "GROUP", "LIGHTS", "LASTUPDATED"}}
+
+	for _, s := range scenes {
+		t.add(s.ID, s.Name, s.Type, orDash(s.Group), list(s.Lights), orDash(s.LastUpdated))
+	}
+
+	return t
+}
+
+// scene resolves a scene name or ID
+func (a *app) scene(name string) (string, error) {
+	id, err := a.h.ResolveScene(name)
+	return id, resolveError(err)
+}
+
+func scenesList(a *app, args []string) error {
+	fs := a.flagSet("scenes list")
+	group := fs.String("group", "", "only list the scenes of the `group`")
+
+	_, err := parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	scenes, err := a.h.GetScenes()
+	if err != nil {
+		return err
+	}
+
+	if *group != "" {
+		id, err := a.group(*group)
+		if err != nil {
+			return err
+		}
+
+		inGroup := []hue.Scene{}
+		for _, s := range scenes {
+			if s.Group == strconv.Itoa(id) {
+				inGroup = append(inGroup, s)
+			}
+		}
+		scenes = inGroup
+	}
+
+	return a.print(scenes, scenesTable(scenes))
+}
+
+func scenesGet(a *app, args []string) error {
+	args, err := parse(a.flagSet("scenes get"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.scene(args[0])
+	if err != nil {
+		return err
+	}
+
+	scene, err := a.h.GetScene(id)
+	if err != nil {
+		return err
+	}
+	scene.ID = id
+
+	return a.print(scene, scenesTable([]hue.Scene{scene}))
+}
+
+func scenesRecall(a *app, args []string) error {
+	fs := a.flagSet("scenes recall")
+	transition := hue.SceneTransition
+	fs.Func("transition", "how long the change takes, instead of the transitions stored in the scene", func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if d < 0 {
+			return errors.New("transition must not be negative")
+		}
+
+		transition = d
+		return nil
+	})
+
+	args, err := parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	group, err := a.group(args[0])
+	if err != nil {
+		return err
+	}
+
+	scene, err := a.h.ResolveSceneInGroup(group, args[1])
+	if err != nil {
+		return resolveError(err)
+	}
+
+	return a.h.RecallScene(group, scene, transition)
+}
+
+func scenesCreate(a *app, args []string) error {
+	fs := a.flagSet("scenes create")
+	group := fs.String("group", "", "create the scene for the `group` instead of lights")
+
+	args, err := parse(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	if *group != "" {
+		if len(args) > 1 {
+			return usagef("lights can't be used with -group")
+		}
+
+		id, err := a.group(*group)
+		if err != nil {
+			return err
+		}
+
+		return a.h.CreateGroupScene(args[0], id, false, hue.SceneAppData{})
+	}
+
+	if len(args) < 2 {
+		return usagef("either lights or -group must be set")
+	}
+
+	lights, err := a.lights(args[1:])
+	if err != nil {
+		return err
+	}
+
+	return a.h.CreateLightScene(args[0], lights, false, hue.SceneAppData{})
+}
+
+func scenesLights(a *app, args []string) error {
+	args, err := parse(a.flagSet("scenes lights"), args, 2, -1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.scene(args[0])
+	if err != nil {
+		return err
+	}
+
+	lights, err := a.lights(args[1:])
+	if err != nil {
+		return err
+	}
+
+	return a.h.SetLightsInScene(id, lights)
+}
+
+func scenesRename(a *app, args []string) error {
+	args, err := parse(a.flagSet("scenes rename"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.scene(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.RenameScene(id, args[1])
+}
+
+func scenesDelete(a *app, args []string) error {
+	args, err := parse(a.flagSet("scenes delete"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.scene(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.DeleteScene(id)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattvella07/hue"
+)
+
+var schedulesResource = resource{
+	name:    "schedules",
+	summary: "list and manage schedules",
+	commands: []command{
+		{"list", "", "list all schedules", schedulesList},
+		{"get", "<schedule>", "show a schedule", schedulesGet},
+		{"create", "<name> <localtime> <group> <scene> [-description text] [-autodelete]", "create a schedule that recalls a scene", schedulesCreate},
+		{"enable", "<schedule>", "enable a schedule", schedulesEnable},
+		{"disable", "<schedule>", "disable a schedule", schedulesDisable},
+		{"describe", "<schedule> <description>", "set the description of a schedule", schedulesDescribe},
+		{"rename", "<schedule> <name>", "rename a schedule", schedulesRename},
+		{"delete", "<schedule>", "delete a schedule", schedulesDelete},
+	},
+}
+
+func schedulesTable(schedules []hue.Schedule) table {
+	t := table{headers: []string{"ID", "NAME", "STATUS", "TIME", "COMMAND"}}
+
+	for _, s := range schedules {
+		t.add(itoa(s.ID), s.Name, s.Status, orDash(s.Time), fmt.Sprintf("%s %s", s.Command.Method, s.Command.Address))
+	}
+
+	return t
+}
+
+// schedule resolves a schedule name or ID
+func (a *app) schedule(name string) (int, error) {
+	id, err := a.h.ResolveSchedule(name)
+	return id, resolveError(err)
+}
+
+func schedulesList(a *app, args []string) error {
+	_, err := parse(a.flagSet("schedules list"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	schedules, err := a.h.GetSchedules()
+	if err != nil {
+		return err
+	}
+
+	return a.print(schedules, schedulesTable(schedules))
+}
+
+func schedulesGet(a *app, args []string) error {
+	args, err := parse(a.flagSet("schedules get"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.schedule(args[0])
+	if err != nil {
+		return err
+	}
+
+	schedule, err := a.h.GetSchedule(id)
+	if err != nil {
+		return err
+	}
+	schedule.ID = id
+
+	return a.print(schedule, schedulesTable([]hue.Schedule{schedule}))
+}
+
+func schedulesCreate(a *app, args []string) error {
+	fs := a.flagSet("schedules create")
+	description := fs.String("description", "", "description of the schedule")
+	autodelete := fs.Bool("autodelete", false, "delete the schedule after it runs")
+
+	args, err := parse(fs, args, 4, 4)
+	if err != nil {
+		return err
+	}
+
+	group, err := a.group(args[2])
+	if err != nil {
+		return err
+	}
+
+	scene, err := a.h.ResolveSceneInGroup(group, args[3])
+	if err != nil {
+		return resolveError(err)
+	}
+
+	command := hue.ScheduleCommand{
+		Address: fmt.Sprintf("/api/%s/groups/%d/action", a.h.UserID, group),
+		Method:  "PUT",
+		Body:    hue.ScheduleCommandBody{Scene: scene},
+	}
+
+	return a.h.CreateSchedule(args[0], *description, command, args[1], "enabled", *autodelete, false)
+}
+
+func schedulesEnable(a *app, args []string) error {
+	return a.withSchedule("schedules enable", args, func(id int) error {
+		return a.h.SetScheduleStatus(id, "enabled")
+	})
+}
+
+func schedulesDisable(a *app, args []string) error {
+	return a.withSchedule("schedules disable", args, func(id int) error {
+		return a.h.SetScheduleStatus(id, "disabled")
+	})
+}
+
+func schedulesDelete(a *app, args []string) error {
+	return a.withSchedule("schedules delete", args, a.h.DeleteSchedule)
+}
+
+// withSchedule runs a command that takes a single schedule
+func (a *app) withSchedule(name string, args []string, fn func(schedule int) error) error {
+	args, err := parse(a.flagSet(name), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.schedule(args[0])
+	if err != nil {
+		return err
+	}
+
+	return fn(id)
+}
+
+func schedulesDescribe(a *app, args []string) error {
+	args, err := parse(a.flagSet("schedules describe"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.schedule(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.SetScheduleDescription(id, args[1])
+}
+
+func schedulesRename(a *app, args []string) error {
+	args, err := parse(a.flagSet("schedules rename"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.schedule(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.RenameSchedule(id, args[1])
+}
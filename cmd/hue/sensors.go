@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/mattvella07/hue"
+)
+
+var sensorsResource = resource{
+	name:    "sensors",
+	summary: "list and manage sensors",
+	commands: []command{
+		{"list", "", "list all sensors", sensorsList},
+		{"get", "<sensor>", "show a sensor", sensorsGet},
+		{"on", "<sensor>", "turn a sensor on", sensorsOn},
+		{"off", "<sensor>", "turn a sensor off", sensorsOff},
+		{"rename", "<sensor> <name>", "rename a sensor", sensorsRename},
+		{"delete", "<sensor>", "delete a sensor", sensorsDelete},
+		{"search", "[-timeout d]", "search for new sensors", sensorsSearch},
+		{"new", "", "list the sensors found by the last search", sensorsNew},
+	},
+}
+
+func sensorsTable(sensors []hue.Sensor) table {
+	t := table{headers: []string{"ID", "NAME", "TYPE", "ON", "MODEL", "LASTUPDATED"}}
+
+	for _, s := range sensors {
+		t.add(itoa(s.ID), s.Name, s.Type, onOff(s.Config.On), orDash(s.ModelID), orDash(s.State.LastUpdated))
+	}
+
+	return t
+}
+
+// sensor resolves a sensor name or ID
+func (a *app) sensor(name string) (int, error) {
+	id, err := a.h.ResolveSensor(name)
+	return id, resolveError(err)
+}
+
+func sensorsList(a *app, args []string) error {
+	_, err := parse(a.flagSet("sensors list"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	sensors, err := a.h.GetSensors()
+	if err != nil {
+		return err
+	}
+
+	return a.print(sensors, sensorsTable(sensors))
+}
+
+func sensorsGet(a *app, args []string) error {
+	args, err := parse(a.flagSet("sensors get"), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.sensor(args[0])
+	if err != nil {
+		return err
+	}
+
+	sensor, err := a.h.GetSensor(id)
+	if err != nil {
+		return err
+	}
+	sensor.ID = id
+
+	return a.print(sensor, sensorsTable([]hue.Sensor{sensor}))
+}
+
+func sensorsOn(a *app, args []string) error {
+	return a.withSensor("sensors on", args, a.h.TurnOnSensor)
+}
+
+func sensorsOff(a *app, args []string) error {
+	return a.withSensor("sensors off", args, a.h.TurnOffSensor)
+}
+
+func sensorsDelete(a *app, args []string) error {
+	return a.withSensor("sensors delete", args, a.h.DeleteSensor)
+}
+
+// withSensor runs a command that takes a single sensor
+func (a *app) withSensor(name string, args []string, fn func(sensor int) error) error {
+	args, err := parse(a.flagSet(name), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.sensor(args[0])
+	if err != nil {
+		return err
+	}
+
+	return fn(id)
+}
+
+func sensorsRename(a *app, args []string) error {
+	args, err := parse(a.flagSet("sensors rename"), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := a.sensor(args[0])
+	if err != nil {
+		return err
+	}
+
+	return a.h.RenameSensor(id, args[1])
+}
+
+func sensorsSearch(a *app, args []string) error {
+	fs := a.flagSet("sensors search")
+	timeout := fs.Duration("timeout", 60*time.Second, "how long to wait for the search to finish")
+
+	_, err := parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	err = a.h.FindNewSensors()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	newSensors, err := a.h.WaitForSensorSearch(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.printNewSensors(newSensors)
+}
+
+func sensorsNew(a *app, args []string) error {
+	_, err := parse(a.flagSet("sensors new"), args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	newSensors, err := a.h.GetNewSensors()
+	if err != nil {
+		return err
+	}
+
+	return a.printNewSensors(newSensors)
+}
+
+func (a *app) printNewSensors(newSensors hue.NewSensorResponse) error {
+	t := table{headers: []string{"ID", "NAME"}}
+	for _, s := range newSensors.NewSensors {
+		t.add(itoa(s.ID), s.Name)
+	}
+
+	return a.print(newSensors, t)
+}
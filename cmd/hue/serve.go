@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/mattvella07/hue/gateway"
+)
+
+var serveResource = resource{
+	name:    "serve",
+	summary: "serve a REST API and web dashboard for the bridge to local clients",
+	commands: []command{
+		{"", "-token client=token... [-listen address] [-rate n] [-burst n] [-ui=false] [-watch d]", "serve a REST API and web dashboard for the bridge to local clients", serve},
+	},
+}
+
+func serve(a *app, args []string) error {
+	config := gateway.Config{Tokens: map[string]string{}}
+
+	fs := a.flagSet("serve")
+	listen := fs.String("listen", "127.0.0.1:8080", "`address` to listen on")
+	fs.Float64Var(&config.RateLimit, "rate", 10, "requests per second each client may send")
+	fs.IntVar(&config.Burst, "burst", 0, "requests a client may send at once, defaults to -rate")
+	fs.BoolVar(&config.UI, "ui", true, "serve the web dashboard at /")
+	fs.DurationVar(&config.WatchInterval, "watch", 2*time.Second, "how often the bridge is polled for the live view")
+	fs.Func("token", "bearer `token` of a client, as client=token, may be repeated (env HUE_GATEWAY_TOKENS, comma separated)", func(value string) error {
+		return addToken(config.Tokens, value)
+	})
+
+	for _, value := range strings.Split(os.Getenv("HUE_GATEWAY_TOKENS"), ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+
+		err := addToken(config.Tokens, strings.TrimSpace(value))
+		if err != nil {
+			return usagef("HUE_GATEWAY_TOKENS: %s", err)
+		}
+	}
+
+	_, err := parse(fs, args, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	if len(config.Tokens) == 0 {
+		return usagef("at least one -token is required")
+	}
+
+	// Fail early if the bridge can't be reached or the user isn't authorized
+	_, err = a.h.GetLights()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:    *listen,
+		Handler: gateway.NewHandler(a.h, config),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Fprintf(a.stderr, "Serving the bridge on http://%s for %d clients\n", *listen, len(config.Tokens))
+	if config.UI {
+		fmt.Fprintf(a.stderr, "Open http://%s/ in a browser for the dashboard\n", *listen)
+	}
+
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// addToken adds a token of the form client=token
+func addToken(tokens map[string]string, value string) error {
+	client, token, ok := strings.Cut(value, "=")
+	if !ok || client == "" || token == "" {
+		return fmt.Errorf("token must be of the form client=token")
+	}
+
+	tokens[token] = client
+
+	return nil
+}
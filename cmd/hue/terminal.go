@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ANSI escape sequences used to draw full screen interfaces
+const (
+	escAltScreen  = "\x1b[?1049h"
+	escMainScreen = "\x1b[?1049l"
+	escHideCursor = "\x1b[?25l"
+	escShowCursor = "\x1b[?25h"
+	escClear      = "\x1b[H\x1b[2J"
+	escReverse    = "\x1b[7m"
+	escReset      = "\x1b[0m"
+)
+
+// terminal puts the terminal into raw mode so keys can be read as they are
+// pressed, and restores it when closed
+type terminal struct {
+	out   io.Writer
+	state string
+}
+
+// openTerminal switches the terminal to raw mode and the alternate screen.
+// stty is used so no dependencies are needed.
+func openTerminal(out io.Writer) (*terminal, error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("unable to control the terminal, is stdin a terminal? %w", err)
+	}
+
+	_, err = stty("raw", "-echo")
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprint(out, escAltScreen+escHideCursor)
+
+	return &terminal{out: out, state: state}, nil
+}
+
+// close restores the terminal to the state it was in when opened
+func (t *terminal) close() error {
+	fmt.Fprint(t.out, escShowCursor+escMainScreen)
+
+	_, err := stty(t.state)
+
+	return err
+}
+
+// draw replaces the contents of the screen with the lines
+func (t *terminal) draw(lines []string) {
+	fmt.Fprint(t.out, escClear+strings.Join(lines, "\r\n"))
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+
+	out, err := cmd.Output()
+
+	return strings.TrimSpace(string(out)), err
+}
+
+// parseKeys splits input read from a raw terminal into key names. Arrow keys
+// are named up, down, left, and right, and control keys enter, space, esc,
+// and ctrl-c. Other keys are returned as they are.
+func parseKeys(input []byte) []string {
+	keys := []string{}
+
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; c {
+		case 3:
+			keys = append(keys, "ctrl-c")
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case ' ':
+			keys = append(keys, "space")
+		case 0x1b:
+			if i+2 < len(input) && input[i+1] == '[' {
+				if name, ok := arrowKeys[input[i+2]]; ok {
+					keys = append(keys, name)
+					i += 2
+					continue
+				}
+			}
+			keys = append(keys, "esc")
+		default:
+			keys = append(keys, string(c))
+		}
+	}
+
+	return keys
+}
+
+var arrowKeys = map[byte]string{
+	'A': "up",
+	'B': "down",
+	'C': "right",
+	'D': "left",
+}
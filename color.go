@@ -0,0 +1,76 @@
+package hue
+
+import (
+	"math"
+)
+
+// RGBToXY converts an sRGB color to CIE xy coordinates, which can be used as
+// the xy of a light. Black is converted to the white point.
+func RGBToXY(r, g, b uint8) []float32 {
+	red := linearChannel(r)
+	green := linearChannel(g)
+	blue := linearChannel(b)
+
+	// Wide gamut conversion recommended by the Phillips Hue API
+	X := red*0.664511 + green*0.154324 + blue*0.162028
+	Y := red*0.283881 + green*0.668433 + blue*0.047685
+	Z := red*0.000088 + green*0.072310 + blue*0.986039
+
+	sum := X + Y + Z
+	if sum == 0 {
+		return []float32{0.3227, 0.329}
+	}
+
+	return []float32{roundXY(X / sum), roundXY(Y / sum)}
+}
+
+// XYToRGB converts CIE xy coordinates to the sRGB color at full brightness
+func XYToRGB(x, y float32) (uint8, uint8, uint8) {
+	if y == 0 {
+		return 255, 255, 255
+	}
+
+	X := float64(x) / float64(y)
+	Z := (1 - float64(x) - float64(y)) / float64(y)
+
+	r := X*1.656492 - 0.354851 - Z*0.255038
+	g := -X*0.707196 + 1.655397 + Z*0.036152
+	b := X*0.051713 - 0.121364 + Z*1.011530
+
+	// Scale so the brightest channel is at full intensity
+	max := math.Max(r, math.Max(g, b))
+	if max > 0 {
+		r, g, b = r/max, g/max, b/max
+	}
+
+	return gammaChannel(r), gammaChannel(g), gammaChannel(b)
+}
+
+// linearChannel removes the sRGB gamma correction from a channel
+func linearChannel(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// gammaChannel applies the sRGB gamma correction to a linear channel
+func gammaChannel(c float64) uint8 {
+	if c <= 0 {
+		return 0
+	}
+
+	if c <= 0.0031308 {
+		c *= 12.92
+	} else {
+		c = 1.055*math.Pow(c, 1/2.4) - 0.055
+	}
+
+	return uint8(math.Round(math.Min(1, c) * 255))
+}
+
+func roundXY(v float64) float32 {
+	return float32(math.Round(v*10000) / 10000)
+}
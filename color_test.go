@@ -0,0 +1,51 @@
+package hue
+
+import (
+	"testing"
+)
+
+func TestRGBToXY(t *testing.T) {
+	t.Run("Red", func(t *testing.T) {
+		xy := RGBToXY(255, 0, 0)
+
+		{
+			expected := []float32{0.7006, 0.2993}
+			if xy[0] != expected[0] || xy[1] != expected[1] {
+				t.Fatalf("Expected %v, got %v", expected, xy)
+			}
+		}
+	})
+
+	t.Run("Black", func(t *testing.T) {
+		xy := RGBToXY(0, 0, 0)
+
+		{
+			expected := []float32{0.3227, 0.329}
+			if xy[0] != expected[0] || xy[1] != expected[1] {
+				t.Fatalf("Expected %v, got %v", expected, xy)
+			}
+		}
+	})
+}
+
+func TestXYToRGB(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		for _, color := range [][3]uint8{{255, 0, 0}, {0, 255, 0}, {0, 0, 255}, {255, 128, 0}} {
+			xy := RGBToXY(color[0], color[1], color[2])
+			r, g, b := XYToRGB(xy[0], xy[1])
+
+			for i, c := range []uint8{r, g, b} {
+				if diff := int(c) - int(color[i]); diff < -20 || diff > 20 {
+					t.Fatalf("Expected %v, got %v", color, []uint8{r, g, b})
+				}
+			}
+		}
+	})
+
+	t.Run("Invalid y", func(t *testing.T) {
+		r, g, b := XYToRGB(0.3, 0)
+		if r != 255 || g != 255 || b != 255 {
+			t.Fatalf("Expected white, got %d %d %d", r, g, b)
+		}
+	})
+}
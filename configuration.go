@@ -1,11 +1,13 @@
 package hue
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"sort"
 	"strings"
+	"time"
 )
 
 // ConfigurationPortalState contains the data for the PortalState field in the
@@ -39,6 +41,7 @@ type ConfigurationWhitelist struct {
 // for the Phillips Hue configuration
 type Configuration struct {
 	Name             string                   `json:"name"`
+	BridgeID         string                   `json:"bridgeid"`
 	ZigbeeChannel    int                      `json:"zigbeechannel"`
 	Mac              string                   `json:"mac"`
 	DHCP             bool                     `json:"dhcp"`
@@ -60,6 +63,27 @@ type Configuration struct {
 	PortalState      ConfigurationPortalState `json:"portalstate"`
 }
 
+// configurationResponse is the configuration as returned by the Phillips Hue API,
+// where the whitelist is a map keyed by ID
+type configurationResponse struct {
+	Configuration
+	Whitelist map[string]ConfigurationWhitelist `json:"whitelist"`
+}
+
+type userCreateRequest struct {
+	DeviceType string `json:"devicetype"`
+}
+
+type userCreateResponse struct {
+	Success struct {
+		Username string `json:"username"`
+	} `json:"success"`
+}
+
+// pairPollInterval is how often Pair tries to create the user while waiting
+// for the link button to be pressed
+const pairPollInterval = time.Second
+
 // CreateUser creates a new user
 func (h *Connection) CreateUser(deviceType string) error {
 	// Error checking
@@ -67,18 +91,55 @@ func (h *Connection) CreateUser(deviceType string) error {
 		return errors.New("deviceType must not be empty")
 	}
 
-	reqBody := strings.NewReader(fmt.Sprintf("{\"devicetype\": \"%s\"}", deviceType))
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api", h.baseURL), reqBody)
+	err := h.execute("POST", apiRootPath, userCreateRequest{DeviceType: deviceType})
 	if err != nil {
 		return err
 	}
 
-	err = h.execute(req)
-	if err != nil {
-		return err
+	return nil
+}
+
+// Pair creates a new user and returns its username. The link button on the
+// bridge must be pressed before the user can be created, so Pair keeps trying
+// until it is pressed or the context is done. The Connection's UserID isn't
+// changed.
+func (h *Connection) Pair(ctx context.Context, deviceType string) (string, error) {
+	// Error checking
+	if strings.Trim(deviceType, " ") == "" {
+		return "", errors.New("deviceType must not be empty")
 	}
 
-	return nil
+	for {
+		resp, err := h.do(ctx, "POST", apiRootPath, userCreateRequest{DeviceType: deviceType})
+		if err != nil {
+			return "", err
+		}
+
+		if len(resp.Errors) == 0 {
+			userRes := []userCreateResponse{}
+
+			err = json.Unmarshal(resp.Body, &userRes)
+			if err != nil {
+				return "", err
+			}
+
+			if len(userRes) == 0 || userRes[0].Success.Username == "" {
+				return "", errors.New("Bridge did not return a username")
+			}
+
+			return userRes[0].Success.Username, nil
+		}
+
+		if !resp.Errors.HasType(ErrorLinkButtonNotPressed) {
+			return "", resp.Errors
+		}
+
+		// Keep reporting the link button error if the context is done
+		// before it's pressed
+		if sleepContext(ctx, pairPollInterval) != nil {
+			return "", resp.Errors
+		}
+	}
 }
 
 // GetConfiguration gets the Phillips Hue configuration
@@ -92,61 +153,28 @@ func (h *Connection) GetConfiguration() (Configuration, error) {
 		return Configuration{}, nil
 	}
 
-	// Create map to store JSON response
-	fullResponse := make(map[string]interface{})
+	// Decode the whitelist as a map of IDs to users alongside the rest
+	// of the configuration
+	fullResponse := configurationResponse{}
 
 	err = json.Unmarshal(data, &fullResponse)
 	if err != nil {
 		return Configuration{}, err
 	}
 
-	config := Configuration{}
-
-	// Create map to store Whitelist JSON response
-	whitelist := make(map[string]interface{})
-
-	whitelistData, err := json.Marshal(fullResponse["whitelist"])
-	if err != nil {
-		return Configuration{}, err
-	}
-
-	err = json.Unmarshal(whitelistData, &whitelist)
-	if err != nil {
-		return Configuration{}, err
-	}
-
-	// Loop through Whitelist slice and add to config
-	for key, val := range whitelist {
-		configWhitelist := ConfigurationWhitelist{}
-
-		w, err := json.Marshal(val)
-		if err != nil {
-			return Configuration{}, err
-		}
-
-		err = json.Unmarshal(w, &configWhitelist)
-		if err != nil {
-			return Configuration{}, err
-		}
+	config := fullResponse.Configuration
+	config.Whitelist = make([]ConfigurationWhitelist, 0, len(fullResponse.Whitelist))
 
+	for key, configWhitelist := range fullResponse.Whitelist {
 		configWhitelist.ID = key
 
 		config.Whitelist = append(config.Whitelist, configWhitelist)
 	}
 
-	// Remove whitelist data from JSON response so the rest can be
-	// Unmarshalled into a Configuration object
-	delete(fullResponse, "whitelist")
-
-	otherData, err := json.Marshal(fullResponse)
-	if err != nil {
-		return Configuration{}, err
-	}
-
-	err = json.Unmarshal(otherData, &config)
-	if err != nil {
-		return Configuration{}, err
-	}
+	// Sort by ID so the order is the same for every call
+	sort.Slice(config.Whitelist, func(i, j int) bool {
+		return config.Whitelist[i].ID < config.Whitelist[j].ID
+	})
 
 	return config, nil
 }
@@ -158,12 +186,7 @@ func (h *Connection) DeleteUser(user string) error {
 		return errors.New("User must not be empty")
 	}
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/config/whitelist/%s", h.baseURL, user), nil)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("DELETE", fmt.Sprintf("config/whitelist/%s", user), nil)
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,14 @@
 package hue
 
-import "testing"
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
 
 func TestCreateUser(t *testing.T) {
 	h, server := createTestConnection(1)
@@ -110,3 +118,100 @@ func TestDeleteUser(t *testing.T) {
 		}
 	})
 }
+
+func TestGetConfigurationWhitelist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "Phillips hue", "whitelist": {"xyz": {"name": "app#phone"}, "abc": {"name": "app#laptop", "create date": "2018-07-17T09:27:35"}}}`))
+	}))
+	defer server.Close()
+
+	h := Connection{
+		baseURL:       server.URL,
+		isInitialized: true,
+	}
+
+	config, err := h.GetConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		expected := "Phillips hue"
+		if config.Name != expected {
+			t.Fatalf("Expected Name to equal %s, got %s", expected, config.Name)
+		}
+	}
+
+	{
+		expected := 2
+		if len(config.Whitelist) != expected {
+			t.Fatalf("Expected %d whitelist entries, got %d", expected, len(config.Whitelist))
+		}
+	}
+
+	{
+		expected := ConfigurationWhitelist{ID: "abc", Name: "app#laptop", CreateDate: "2018-07-17T09:27:35"}
+		if config.Whitelist[0] != expected {
+			t.Fatalf("Expected first whitelist entry to equal %v, got %v", expected, config.Whitelist[0])
+		}
+	}
+}
+
+func TestPair(t *testing.T) {
+	// The link button is pressed after the first attempt
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api" {
+			t.Errorf("Expected POST /api, got %s %s", r.Method, r.URL.Path)
+		}
+
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Write([]byte(`[{"error": {"type": 101, "address": "", "description": "link button not pressed"}}]`))
+			return
+		}
+
+		w.Write([]byte(`[{"success": {"username": "abc123"}}]`))
+	}))
+	defer server.Close()
+
+	t.Run("Successful", func(t *testing.T) {
+		h := Connection{Address: strings.TrimPrefix(server.URL, "http://")}
+
+		username, err := h.Pair(context.Background(), "app#device")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := "abc123"
+			if username != expected {
+				t.Fatalf("Expected username to equal %s, got %s", expected, username)
+			}
+		}
+	})
+
+	t.Run("Link button not pressed", func(t *testing.T) {
+		atomic.StoreInt32(&attempts, 0)
+
+		h := Connection{UserID: "existing", Address: strings.TrimPrefix(server.URL, "http://")}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := h.Pair(ctx, "app#device")
+
+		bridgeErrors, ok := err.(BridgeErrors)
+		if !ok || !bridgeErrors.HasType(ErrorLinkButtonNotPressed) {
+			t.Fatalf("Expected link button error, got %v", err)
+		}
+	})
+
+	t.Run("Invalid deviceType", func(t *testing.T) {
+		h := Connection{Address: strings.TrimPrefix(server.URL, "http://")}
+
+		_, err := h.Pair(context.Background(), " ")
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+}
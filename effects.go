@@ -0,0 +1,565 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EffectTarget contains the lights and groups an effect runs on. Each light
+// and each group is driven as a separate target.
+type EffectTarget struct {
+	Lights []int
+	Groups []int
+}
+
+// EffectParams contains the parameters used to run an effect
+type EffectParams struct {
+	// Speed multiplies the rate of the effect. Values of 0 or less run the
+	// effect at normal speed.
+	Speed float64
+
+	// Palette contains the xy colors used by the effect. If empty, the
+	// effect's default palette is used.
+	Palette [][]float32
+
+	// Bri is the base brightness of the effect. If 0, the effect's default
+	// brightness is used.
+	Bri int
+}
+
+// EffectState contains the state of a single target for one frame of an effect
+type EffectState struct {
+	On  bool
+	Bri int
+	XY  []float32
+
+	// TransitionTime is the time taken to reach this state, in multiples of 100ms
+	TransitionTime int
+}
+
+// Effect is a software light effect run by the client. Step returns the state
+// of each target for the specified frame, along with how long to wait before
+// the next frame.
+type Effect struct {
+	Name           string
+	DefaultPalette [][]float32
+	DefaultBri     int
+	Step           func(frame, targets int, params EffectParams, rnd *rand.Rand) ([]EffectState, time.Duration)
+}
+
+// Built in software light effects
+var (
+	// EffectCandle flickers warm white like a candle
+	EffectCandle = Effect{
+		Name:           "candle",
+		DefaultPalette: [][]float32{{0.52, 0.41}},
+		DefaultBri:     120,
+		Step: func(frame, targets int, params EffectParams, rnd *rand.Rand) ([]EffectState, time.Duration) {
+			states := make([]EffectState, targets)
+			for i := range states {
+				states[i] = EffectState{
+					On:             true,
+					Bri:            jitter(params.Bri, 40, rnd),
+					XY:             params.Palette[0],
+					TransitionTime: 1 + rnd.Intn(2),
+				}
+			}
+
+			return states, time.Duration(150+rnd.Intn(250)) * time.Millisecond
+		},
+	}
+
+	// EffectFireplace flickers between the reds and oranges of a fire
+	EffectFireplace = Effect{
+		Name:           "fireplace",
+		DefaultPalette: [][]float32{{0.60, 0.38}, {0.55, 0.40}, {0.64, 0.33}},
+		DefaultBri:     150,
+		Step: func(frame, targets int, params EffectParams, rnd *rand.Rand) ([]EffectState, time.Duration) {
+			states := make([]EffectState, targets)
+			for i := range states {
+				states[i] = EffectState{
+					On:             true,
+					Bri:            jitter(params.Bri, 70, rnd),
+					XY:             params.Palette[rnd.Intn(len(params.Palette))],
+					TransitionTime: 3,
+				}
+			}
+
+			return states, time.Duration(300+rnd.Intn(400)) * time.Millisecond
+		},
+	}
+
+	// EffectBreathe slowly raises and lowers the brightness
+	EffectBreathe = Effect{
+		Name:           "breathe",
+		DefaultPalette: [][]float32{{0.3227, 0.329}},
+		DefaultBri:     254,
+		Step: func(frame, targets int, params EffectParams, rnd *rand.Rand) ([]EffectState, time.Duration) {
+			// One breath takes 8 frames
+			level := (1 - math.Cos(2*math.Pi*float64(frame%8)/8)) / 2
+
+			states := make([]EffectState, targets)
+			for i := range states {
+				states[i] = EffectState{
+					On:             true,
+					Bri:            clampBri(1 + int(level*float64(params.Bri-1))),
+					XY:             params.Palette[0],
+					TransitionTime: 5,
+				}
+			}
+
+			return states, 500 * time.Millisecond
+		},
+	}
+
+	// EffectStrobe flashes all targets on and off
+	EffectStrobe = Effect{
+		Name:           "strobe",
+		DefaultPalette: [][]float32{{0.3227, 0.329}},
+		DefaultBri:     254,
+		Step: func(frame, targets int, params EffectParams, rnd *rand.Rand) ([]EffectState, time.Duration) {
+			states := make([]EffectState, targets)
+			for i := range states {
+				states[i] = EffectState{
+					On:  frame%2 == 0,
+					Bri: params.Bri,
+					XY:  params.Palette[(frame/2)%len(params.Palette)],
+				}
+			}
+
+			return states, 200 * time.Millisecond
+		},
+	}
+
+	// EffectPolice alternates red and blue between neighbouring targets
+	EffectPolice = Effect{
+		Name:           "police",
+		DefaultPalette: [][]float32{{0.675, 0.322}, {0.167, 0.04}},
+		DefaultBri:     254,
+		Step: func(frame, targets int, params EffectParams, rnd *rand.Rand) ([]EffectState, time.Duration) {
+			states := make([]EffectState, targets)
+			for i := range states {
+				states[i] = EffectState{
+					On:  true,
+					Bri: params.Bri,
+					XY:  params.Palette[(frame+i)%len(params.Palette)],
+				}
+			}
+
+			return states, 500 * time.Millisecond
+		},
+	}
+
+	// EffectRainbowChase moves the colors of the palette along the targets
+	EffectRainbowChase = Effect{
+		Name: "rainbow",
+		DefaultPalette: [][]float32{
+			{0.675, 0.322},
+			{0.56, 0.41},
+			{0.44, 0.52},
+			{0.2, 0.7},
+			{0.17, 0.35},
+			{0.167, 0.04},
+			{0.28, 0.13},
+		},
+		DefaultBri: 254,
+		Step: func(frame, targets int, params EffectParams, rnd *rand.Rand) ([]EffectState, time.Duration) {
+			states := make([]EffectState, targets)
+			for i := range states {
+				states[i] = EffectState{
+					On:             true,
+					Bri:            params.Bri,
+					XY:             params.Palette[(frame+i)%len(params.Palette)],
+					TransitionTime: 10,
+				}
+			}
+
+			return states, time.Second
+		},
+	}
+
+	// EffectLightning keeps the targets dim and occasionally flashes them
+	EffectLightning = Effect{
+		Name:           "lightning",
+		DefaultPalette: [][]float32{{0.17, 0.2}, {0.3227, 0.329}},
+		DefaultBri:     254,
+		Step: func(frame, targets int, params EffectParams, rnd *rand.Rand) ([]EffectState, time.Duration) {
+			flash := rnd.Intn(8) == 0
+
+			states := make([]EffectState, targets)
+			for i := range states {
+				if flash && rnd.Intn(2) == 0 {
+					states[i] = EffectState{
+						On:  true,
+						Bri: params.Bri,
+						XY:  params.Palette[len(params.Palette)-1],
+					}
+				} else {
+					states[i] = EffectState{
+						On:             true,
+						Bri:            1,
+						XY:             params.Palette[0],
+						TransitionTime: 2,
+					}
+				}
+			}
+
+			if flash {
+				return states, time.Duration(100+rnd.Intn(200)) * time.Millisecond
+			}
+
+			return states, time.Duration(500+rnd.Intn(2500)) * time.Millisecond
+		},
+	}
+)
+
+// Effects contains all built in effects by name
+var Effects = map[string]Effect{
+	EffectCandle.Name:       EffectCandle,
+	EffectFireplace.Name:    EffectFireplace,
+	EffectBreathe.Name:      EffectBreathe,
+	EffectStrobe.Name:       EffectStrobe,
+	EffectPolice.Name:       EffectPolice,
+	EffectRainbowChase.Name: EffectRainbowChase,
+	EffectLightning.Name:    EffectLightning,
+}
+
+type effectCommand struct {
+	On             bool      `json:"on"`
+	Bri            int       `json:"bri,omitempty"`
+	XY             []float32 `json:"xy,omitempty"`
+	TransitionTime int       `json:"transitiontime"`
+}
+
+type restoreCommand struct {
+	On             bool      `json:"on"`
+	Bri            int       `json:"bri,omitempty"`
+	XY             []float32 `json:"xy,omitempty"`
+	CT             int       `json:"ct,omitempty"`
+	Hue            *int      `json:"hue,omitempty"`
+	Sat            *int      `json:"sat,omitempty"`
+	TransitionTime *int      `json:"transitiontime,omitempty"`
+}
+
+// EffectRunner controls an effect running in its own goroutine
+type EffectRunner struct {
+	h       *Connection
+	effect  Effect
+	params  EffectParams
+	target  EffectTarget
+	saved   map[int]lightState
+	cancel  context.CancelFunc
+	done    chan struct{}
+	stop    sync.Once
+	stopErr error
+
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{}
+	err    error
+}
+
+// StartEffect starts running the specified effect on the target lights and groups.
+// The current state of every affected light is saved so it can be restored when
+// the effect is stopped.
+func (h *Connection) StartEffect(effect Effect, target EffectTarget, params EffectParams) (*EffectRunner, error) {
+	// Error checking
+	if effect.Step == nil {
+		return nil, errors.New("Effect must not be empty")
+	}
+
+	if len(target.Lights) == 0 && len(target.Groups) == 0 {
+		return nil, errors.New("Target must contain at least one light or group")
+	}
+
+	if len(params.Palette) == 0 {
+		params.Palette = effect.DefaultPalette
+	}
+
+	if len(params.Palette) == 0 {
+		return nil, errors.New("Palette must not be empty")
+	}
+
+	for _, xy := range params.Palette {
+		if len(xy) != 2 || xy[0] < 0 || xy[0] > 1 || xy[1] < 0 || xy[1] > 1 {
+			return nil, errors.New("Invalid palette: each color must contain an x and y value between 0 and 1")
+		}
+	}
+
+	if params.Bri == 0 {
+		params.Bri = effect.DefaultBri
+	}
+
+	if params.Bri < 1 || params.Bri > 254 {
+		return nil, errors.New("Invalid brightness value: bri must be between 1 and 254")
+	}
+
+	if params.Speed <= 0 {
+		params.Speed = 1
+	}
+
+	saved, err := h.saveEffectTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &EffectRunner{
+		h:      h,
+		effect: effect,
+		params: params,
+		target: target,
+		saved:  saved,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go r.run(ctx)
+
+	return r, nil
+}
+
+// Pause pauses the effect, leaving the lights in their current state
+func (r *EffectRunner) Pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.paused {
+		r.paused = true
+		r.resume = make(chan struct{})
+	}
+}
+
+// Resume resumes a paused effect
+func (r *EffectRunner) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.paused {
+		r.paused = false
+		close(r.resume)
+	}
+}
+
+// Paused returns true if the effect is paused
+func (r *EffectRunner) Paused() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.paused
+}
+
+// Done returns a channel that's closed once the effect stops running, either
+// because Stop was called or because a command failed
+func (r *EffectRunner) Done() <-chan struct{} {
+	return r.done
+}
+
+// Stop stops the effect and restores every affected light to the state it was
+// in before the effect started. It returns the error that stopped the effect
+// early, if any, or the error from restoring the lights.
+func (r *EffectRunner) Stop() error {
+	r.stop.Do(func() {
+		r.cancel()
+		<-r.done
+
+		r.mu.Lock()
+		r.stopErr = r.err
+		r.mu.Unlock()
+
+		err := r.h.restoreLights(r.saved)
+		if r.stopErr == nil {
+			r.stopErr = err
+		}
+	})
+
+	return r.stopErr
+}
+
+func (r *EffectRunner) run(ctx context.Context) {
+	defer close(r.done)
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	targets := len(r.target.Lights) + len(r.target.Groups)
+
+	for frame := 0; ; frame++ {
+		err := r.waitIfPaused(ctx)
+		if err != nil {
+			return
+		}
+
+		states, delay := r.effect.Step(frame, targets, r.params, rnd)
+
+		for i, state := range states {
+			state.TransitionTime = int(float64(state.TransitionTime) / r.params.Speed)
+
+			err = r.send(ctx, i, state)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.mu.Lock()
+					r.err = err
+					r.mu.Unlock()
+				}
+
+				return
+			}
+		}
+
+		err = sleepContext(ctx, time.Duration(float64(delay)/r.params.Speed))
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (r *EffectRunner) waitIfPaused(ctx context.Context) error {
+	r.mu.Lock()
+	paused, resume := r.paused, r.resume
+	r.mu.Unlock()
+
+	if !paused {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resume:
+		return nil
+	}
+}
+
+// send sends the state to the target at the specified index, where lights
+// come before groups
+func (r *EffectRunner) send(ctx context.Context, index int, state EffectState) error {
+	cmd := newEffectCommand(state)
+
+	if index < len(r.target.Lights) {
+		return r.h.changeLightStateContext(ctx, r.target.Lights[index], cmd)
+	}
+
+	return r.h.updateGroupContext(ctx, r.target.Groups[index-len(r.target.Lights)], "state", cmd)
+}
+
+// newEffectCommand returns the request body that sets a target to the state
+func newEffectCommand(state EffectState) effectCommand {
+	cmd := effectCommand{
+		On:             state.On,
+		TransitionTime: state.TransitionTime,
+	}
+
+	if state.On {
+		cmd.Bri = state.Bri
+		cmd.XY = state.XY
+	}
+
+	return cmd
+}
+
+// saveEffectTarget returns the current state of every light affected by the target
+func (h *Connection) saveEffectTarget(target EffectTarget) (map[int]lightState, error) {
+	lights := append([]int{}, target.Lights...)
+
+	for _, group := range target.Groups {
+		g, err := h.GetGroup(group)
+		if err != nil {
+			return nil, fmt.Errorf("Group %d not found", group)
+		}
+
+		for _, l := range g.Lights {
+			id, err := strconv.Atoi(l)
+			if err != nil {
+				return nil, err
+			}
+
+			lights = append(lights, id)
+		}
+	}
+
+	saved := make(map[int]lightState)
+	for _, light := range lights {
+		if _, ok := saved[light]; ok {
+			continue
+		}
+
+		l, err := h.GetLight(light)
+		if err != nil {
+			return nil, fmt.Errorf("Light %d not found", light)
+		}
+
+		saved[light] = l.State
+	}
+
+	return saved, nil
+}
+
+// restoreLights sets each light back to its saved state. The bridge only
+// accepts brightness and color while a light is on, so dimmable lights that were
+// off are instantly set back to their brightness and color, then turned off.
+func (h *Connection) restoreLights(saved map[int]lightState) error {
+	var firstErr error
+
+	for light, state := range saved {
+		cmd := restoreCommand{
+			On:  true,
+			Bri: state.Bri,
+		}
+
+		switch state.ColorMode {
+		case "xy":
+			cmd.XY = state.XY
+		case "ct":
+			cmd.CT = state.CT
+		case "hs":
+			cmd.Hue = &state.Hue
+			cmd.Sat = &state.Sat
+		}
+
+		var err error
+		switch {
+		case state.On:
+			err = h.changeLightState(light, cmd)
+		case state.Bri != 0:
+			instant := 0
+			cmd.TransitionTime = &instant
+
+			err = h.changeLightState(light, cmd)
+			if err == nil {
+				err = h.changeLightState(light, onRequest{On: false})
+			}
+		default:
+			// Lights without brightness, such as plugs, have nothing else to restore
+			err = h.changeLightState(light, onRequest{On: false})
+		}
+
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// jitter returns a random brightness within spread of bri
+func jitter(bri, spread int, rnd *rand.Rand) int {
+	return clampBri(bri - spread + rnd.Intn(2*spread+1))
+}
+
+func clampBri(bri int) int {
+	if bri < 1 {
+		return 1
+	}
+
+	if bri > 254 {
+		return 254
+	}
+
+	return bri
+}
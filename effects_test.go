@@ -0,0 +1,206 @@
+package hue
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStartEffect(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Start, pause, resume and stop", func(t *testing.T) {
+		r, err := h.StartEffect(EffectCandle, EffectTarget{Lights: []int{1}, Groups: []int{1}}, EffectParams{Speed: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+
+		r.Pause()
+		if !r.Paused() {
+			t.Fatal("Expected effect to be paused")
+		}
+
+		r.Resume()
+		if r.Paused() {
+			t.Fatal("Expected effect to be resumed")
+		}
+
+		err = r.Stop()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-r.Done():
+		default:
+			t.Fatal("Expected effect to be done")
+		}
+	})
+
+	t.Run("Empty target", func(t *testing.T) {
+		_, err := h.StartEffect(EffectCandle, EffectTarget{}, EffectParams{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Target must contain at least one light or group"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Light doesn't exist", func(t *testing.T) {
+		_, err := h.StartEffect(EffectCandle, EffectTarget{Lights: []int{3}}, EffectParams{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Light 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid palette", func(t *testing.T) {
+		_, err := h.StartEffect(EffectPolice, EffectTarget{Lights: []int{1}}, EffectParams{Palette: [][]float32{{2, 0.3}}})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Invalid palette: each color must contain an x and y value between 0 and 1"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+}
+
+func TestEffectRestore(t *testing.T) {
+	states := map[string]lightState{
+		"/lights/1": {On: true, Bri: 120, XY: []float32{0.5, 0.4}, CT: 300, ColorMode: "xy", Reachable: true},
+		"/lights/2": {On: true, Bri: 200, XY: []float32{0.4, 0.4}, CT: 366, ColorMode: "ct", Reachable: true},
+		"/lights/3": {On: true, Bri: 80, Hue: 46920, Sat: 254, CT: 153, ColorMode: "hs", Reachable: true},
+		"/lights/4": {On: false, Bri: 254, XY: []float32{0.3, 0.3}, ColorMode: "xy", Reachable: true},
+	}
+
+	h, server, requests := createSequenceConnection(func(url string) interface{} {
+		state, ok := states[url]
+		if !ok {
+			return nil
+		}
+
+		return Light{State: state, Name: "Effect light"}
+	})
+	defer server.Close()
+
+	r, err := h.StartEffect(EffectCandle, EffectTarget{Lights: []int{1, 2, 3, 4}}, EffectParams{Speed: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	err = r.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The last commands sent to each light restore it
+	sent := map[string][]string{}
+	for _, req := range requests() {
+		sent[req.path] = append(sent[req.path], req.body)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{"xy", "/lights/1/state", []string{`{"on":true,"bri":120,"xy":[0.5,0.4]}`}},
+		{"ct", "/lights/2/state", []string{`{"on":true,"bri":200,"ct":366}`}},
+		{"hs", "/lights/3/state", []string{`{"on":true,"bri":80,"hue":46920,"sat":254}`}},
+		{"Off", "/lights/4/state", []string{`{"on":true,"bri":254,"xy":[0.3,0.3],"transitiontime":0}`, `{"on":false}`}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bodies := sent[tc.path]
+			if len(bodies) < len(tc.expected) {
+				t.Fatalf("Expected at least %d commands, got %v", len(tc.expected), bodies)
+			}
+
+			got := bodies[len(bodies)-len(tc.expected):]
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("Expected restore commands %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestEffectFailure(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	r, err := h.StartEffect(EffectStrobe, EffectTarget{Lights: []int{1}}, EffectParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commands now fail, which stops the effect
+	server.Close()
+
+	select {
+	case <-r.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected effect to stop")
+	}
+
+	err = r.Stop()
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+}
+
+func TestBuiltInEffects(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for name, effect := range Effects {
+		t.Run(name, func(t *testing.T) {
+			params := EffectParams{
+				Palette: effect.DefaultPalette,
+				Bri:     effect.DefaultBri,
+				Speed:   1,
+			}
+
+			for frame := 0; frame < 20; frame++ {
+				states, delay := effect.Step(frame, 3, params, rnd)
+
+				{
+					expected := 3
+					if len(states) != expected {
+						t.Fatalf("Expected %d states, got %d", expected, len(states))
+					}
+				}
+
+				if delay <= 0 {
+					t.Fatalf("Expected a positive delay, got %s", delay)
+				}
+
+				for _, state := range states {
+					if state.On && (state.Bri < 1 || state.Bri > 254) {
+						t.Fatalf("Expected Bri to be between 1 and 254, got %d", state.Bri)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package hue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrDiscovery is returned, wrapping the cause, when the bridge can't be
+// discovered. Use errors.Is to check for it.
+var ErrDiscovery = errors.New("GetBridgeIPAddress Error")
+
+// Error types returned by the Phillips Hue API
+const (
+	ErrorUnauthorizedUser        = 1
+	ErrorInvalidJSON             = 2
+	ErrorResourceNotAvailable    = 3
+	ErrorMethodNotAvailable      = 4
+	ErrorMissingParameters       = 5
+	ErrorParameterNotAvailable   = 6
+	ErrorInvalidValue            = 7
+	ErrorParameterNotModifiable  = 8
+	ErrorTooManyItems            = 11
+	ErrorPortalConnectionNeeded  = 12
+	ErrorLinkButtonNotPressed    = 101
+	ErrorDeviceIsOff             = 201
+	ErrorGroupTableFull          = 301
+	ErrorDeviceGroupTableFull    = 302
+	ErrorDeviceUnreachable       = 304
+	ErrorSceneCouldNotBeCreated  = 402
+	ErrorSensorListFull          = 501
+	ErrorRuleEngineFull          = 601
+	ErrorConditionError          = 607
+	ErrorActionError             = 608
+	ErrorScheduleListFull        = 701
+	ErrorScheduleTimezoneInvalid = 702
+	ErrorInternal                = 901
+)
+
+// BridgeError is a single error returned by the Phillips Hue API
+type BridgeError struct {
+	Type        int    `json:"type"`
+	Address     string `json:"address"`
+	Description string `json:"description"`
+}
+
+func (e BridgeError) Error() string {
+	return fmt.Sprintf("Error: %s", e.Description)
+}
+
+// BridgeErrors contains all errors the Phillips Hue API returned for a single
+// request. Use errors.As to inspect the type of each error.
+type BridgeErrors []BridgeError
+
+func (e BridgeErrors) Error() string {
+	errMsg := ""
+	for _, bridgeErr := range e {
+		errMsg += fmt.Sprintf("%s\n", bridgeErr.Description)
+	}
+
+	return fmt.Sprintf("Error: %s", errMsg)
+}
+
+// HasType returns true if any of the errors has the specified type
+func (e BridgeErrors) HasType(errorType int) bool {
+	for _, bridgeErr := range e {
+		if bridgeErr.Type == errorType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseBridgeErrors returns the errors in a response from the Phillips Hue API.
+// Responses that aren't a list of results don't contain any errors.
+func parseBridgeErrors(data []byte) BridgeErrors {
+	results := []struct {
+		Error *BridgeError `json:"error"`
+	}{}
+
+	err := json.Unmarshal(data, &results)
+	if err != nil {
+		return nil
+	}
+
+	var bridgeErrs BridgeErrors
+	for _, result := range results {
+		if result.Error != nil && result.Error.Description != "" {
+			bridgeErrs = append(bridgeErrs, *result.Error)
+		}
+	}
+
+	return bridgeErrs
+}
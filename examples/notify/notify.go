@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/mattvella07/hue"
+)
+
+func main() {
+	//Create connection using Hue User ID
+	h := hue.Connection{
+		UserID: os.Getenv("hueUserID"),
+	}
+
+	//Flash light 1 and group 1 when a webhook POSTs to
+	//http://localhost:8080/notify/failure, /success, or /doorbell
+	target := hue.EffectTarget{
+		Lights: []int{1},
+		Groups: []int{1},
+	}
+
+	http.Handle("/notify/", http.StripPrefix("/notify", h.NotifyHandler(context.Background(), target, nil, func(err error) {
+		log.Println(err)
+	})))
+
+	log.Fatalln(http.ListenAndServe("localhost:8080", nil))
+}
@@ -0,0 +1,176 @@
+package hue
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// metricSample is a single value of a metric with its labels
+type metricSample struct {
+	labels string
+	value  float64
+}
+
+// metricFamily contains the samples of a metric in the order they're exported
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string
+	samples []metricSample
+}
+
+func (f *metricFamily) add(value float64, labels ...string) {
+	f.samples = append(f.samples, metricSample{labels: formatLabels(labels...), value: value})
+}
+
+func (f *metricFamily) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+
+	for _, s := range f.samples {
+		if s.labels == "" {
+			fmt.Fprintf(b, "%s %s\n", f.name, formatFloat(s.value))
+			continue
+		}
+
+		fmt.Fprintf(b, "%s{%s} %s\n", f.name, s.labels, formatFloat(s.value))
+	}
+}
+
+// ExportState gets the state of every light, group, sensor, and rule and
+// returns it as metrics in the Prometheus text format. Metrics are labelled
+// with the ID and name of each resource. If the bridge can't be read, only
+// hue_up is returned, with a value of 0, along with the error.
+func (h *Connection) ExportState() (string, error) {
+	up := &metricFamily{name: "hue_up", help: "Whether the Hue bridge could be read.", kind: "gauge"}
+
+	families, err := h.stateMetrics()
+
+	var b strings.Builder
+
+	if err != nil {
+		up.add(0)
+		up.write(&b)
+
+		return b.String(), err
+	}
+
+	up.add(1)
+	up.write(&b)
+
+	for _, f := range families {
+		f.write(&b)
+	}
+
+	return b.String(), nil
+}
+
+// ExporterHandler returns an http.Handler that serves the state of the bridge
+// in the Prometheus text format, reading it from the bridge on every request
+func (h *Connection) ExporterHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out, _ := h.ExportState()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(out))
+	})
+}
+
+func (h *Connection) stateMetrics() ([]*metricFamily, error) {
+	lights, err := h.GetLights()
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := h.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	sensors, err := h.GetSensors()
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := h.GetRules()
+	if err != nil {
+		return nil, err
+	}
+
+	lightOn := &metricFamily{name: "hue_light_on", help: "Whether the light is on.", kind: "gauge"}
+	lightReachable := &metricFamily{name: "hue_light_reachable", help: "Whether the light can be reached by the bridge.", kind: "gauge"}
+	lightBri := &metricFamily{name: "hue_light_brightness", help: "Brightness of the light, from 1 to 254.", kind: "gauge"}
+	lightCT := &metricFamily{name: "hue_light_color_temperature_mireds", help: "Color temperature of the light in mireds.", kind: "gauge"}
+
+	for _, l := range lights {
+		id := strconv.Itoa(l.ID)
+
+		lightOn.add(boolValue(l.State.On), "id", id, "name", l.Name)
+		lightReachable.add(boolValue(l.State.Reachable), "id", id, "name", l.Name)
+		if l.State.Bri != 0 {
+			lightBri.add(float64(l.State.Bri), "id", id, "name", l.Name)
+		}
+		if l.State.CT != 0 {
+			lightCT.add(float64(l.State.CT), "id", id, "name", l.Name)
+		}
+	}
+
+	groupAnyOn := &metricFamily{name: "hue_group_any_on", help: "Whether any light in the group is on.", kind: "gauge"}
+	groupAllOn := &metricFamily{name: "hue_group_all_on", help: "Whether every light in the group is on.", kind: "gauge"}
+
+	for _, g := range groups {
+		id := strconv.Itoa(g.ID)
+
+		groupAnyOn.add(boolValue(g.State.AnyOn), "id", id, "name", g.Name, "type", string(g.Type))
+		groupAllOn.add(boolValue(g.State.AllOn), "id", id, "name", g.Name, "type", string(g.Type))
+	}
+
+	temperature := &metricFamily{name: "hue_sensor_temperature_celsius", help: "Temperature reported by the sensor in degrees Celsius.", kind: "gauge"}
+	lightLevel := &metricFamily{name: "hue_sensor_light_level_lux", help: "Light level reported by the sensor in lux.", kind: "gauge"}
+	presence := &metricFamily{name: "hue_sensor_presence", help: "Whether the sensor detects motion.", kind: "gauge"}
+	battery := &metricFamily{name: "hue_sensor_battery_percent", help: "Remaining battery of the sensor in percent.", kind: "gauge"}
+	daylight := &metricFamily{name: "hue_sensor_daylight", help: "Whether the sensor reports daylight.", kind: "gauge"}
+
+	for _, s := range sensors {
+		id := strconv.Itoa(s.ID)
+
+		switch strings.TrimPrefix(strings.TrimPrefix(s.Type, "ZLL"), "CLIP") {
+		case "Temperature":
+			temperature.add(s.State.Celsius(), "id", id, "name", s.Name)
+		case "LightLevel":
+			lightLevel.add(math.Round(s.State.Lux()*10)/10, "id", id, "name", s.Name)
+			daylight.add(boolValue(s.State.Daylight), "id", id, "name", s.Name)
+		case "Presence":
+			presence.add(boolValue(s.State.Presence), "id", id, "name", s.Name)
+		case "Daylight":
+			daylight.add(boolValue(s.State.Daylight), "id", id, "name", s.Name)
+		}
+
+		if s.Config.Battery != 0 {
+			battery.add(float64(s.Config.Battery), "id", id, "name", s.Name)
+		}
+	}
+
+	triggered := &metricFamily{name: "hue_rule_triggered_total", help: "Times the rule has been triggered.", kind: "counter"}
+
+	for _, r := range rules {
+		triggered.add(float64(r.TimesTriggered), "id", strconv.Itoa(r.ID), "name", r.Name)
+	}
+
+	return []*metricFamily{
+		lightOn, lightReachable, lightBri, lightCT,
+		groupAnyOn, groupAllOn,
+		temperature, lightLevel, presence, battery, daylight,
+		triggered,
+	}, nil
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
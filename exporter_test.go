@@ -0,0 +1,81 @@
+package hue
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportState(t *testing.T) {
+	responses := map[string]string{
+		"lights": `{"1":{"name":"Desk","state":{"on":true,"bri":200,"ct":366,"reachable":true}},"2":{"name":"Porch","state":{"on":false,"reachable":false}}}`,
+		"groups": `{"1":{"name":"Office","type":"Room","state":{"any_on":true,"all_on":false}}}`,
+		"sensors": `{
+			"1":{"name":"Hall temperature","type":"ZLLTemperature","state":{"temperature":2150},"config":{"battery":80}},
+			"2":{"name":"Hall light","type":"ZLLLightLevel","state":{"lightlevel":10001,"daylight":true}},
+			"3":{"name":"Hall motion","type":"ZLLPresence","state":{"presence":true},"config":{"battery":80}},
+			"4":{"name":"Daylight","type":"Daylight","state":{"daylight":false}}
+		}`,
+		"rules": `{"10":{"name":"Motion \"on\"","timestriggered":7},"2":{"name":"Switch","timestriggered":0}}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.URL.Path, "/")
+		fmt.Fprint(w, responses[parts[len(parts)-1]])
+	}))
+	defer server.Close()
+
+	h := &Connection{UserID: "user", Address: strings.TrimPrefix(server.URL, "http://")}
+
+	rec := httptest.NewRecorder()
+	h.ExporterHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+
+	expected := []string{
+		`hue_up 1`,
+		`# TYPE hue_light_on gauge`,
+		`hue_light_on{id="1",name="Desk"} 1`,
+		`hue_light_on{id="2",name="Porch"} 0`,
+		`hue_light_reachable{id="2",name="Porch"} 0`,
+		`hue_light_brightness{id="1",name="Desk"} 200`,
+		`hue_light_color_temperature_mireds{id="1",name="Desk"} 366`,
+		`hue_group_any_on{id="1",name="Office",type="Room"} 1`,
+		`hue_group_all_on{id="1",name="Office",type="Room"} 0`,
+		`hue_sensor_temperature_celsius{id="1",name="Hall temperature"} 21.5`,
+		`hue_sensor_light_level_lux{id="2",name="Hall light"} 10`,
+		`hue_sensor_presence{id="3",name="Hall motion"} 1`,
+		`hue_sensor_battery_percent{id="1",name="Hall temperature"} 80`,
+		`hue_sensor_daylight{id="2",name="Hall light"} 1`,
+		`hue_sensor_daylight{id="4",name="Daylight"} 0`,
+		`# TYPE hue_rule_triggered_total counter`,
+		`hue_rule_triggered_total{id="2",name="Switch"} 0` + "\n" + `hue_rule_triggered_total{id="10",name="Motion \"on\""} 7`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Fatalf("Expected output to contain %s, got:\n%s", line, out)
+		}
+	}
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Unexpected content type %s", ct)
+	}
+
+	t.Run("Unreachable", func(t *testing.T) {
+		server.Close()
+
+		out, err := h.ExportState()
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "# HELP hue_up Whether the Hue bridge could be read.\n# TYPE hue_up gauge\nhue_up 0\n"
+			if out != expected {
+				t.Fatalf("Expected %q, got %q", expected, out)
+			}
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// maxTransitionTime is the longest transition the bridge supports in a single
+// command. The transitiontime attribute is a uint16 in multiples of 100ms.
+const maxTransitionTime = 65535 * 100 * time.Millisecond
+
+// fadeEasingSegments is the number of commands used to approximate an eased
+// keyframe. Fewer are used if the keyframe is too short for the rate limit.
+const fadeEasingSegments = 20
+
+// Easing maps the progress of a fade keyframe, from 0 to 1, onto the
+// progress of the light's state, from 0 to 1
+type Easing func(t float64) float64
+
+// Easing curves that can be used for a FadeKeyframe
+var (
+	// EaseLinear changes the state at a constant rate
+	EaseLinear Easing = func(t float64) float64 { return t }
+
+	// EaseIn starts slowly and speeds up towards the end
+	EaseIn Easing = func(t float64) float64 { return t * t }
+
+	// EaseOut starts quickly and slows down towards the end
+	EaseOut Easing = func(t float64) float64 { return t * (2 - t) }
+
+	// EaseInOut starts and ends slowly
+	EaseInOut Easing = func(t float64) float64 { return (1 - math.Cos(math.Pi*t)) / 2 }
+)
+
+// FadeKeyframe contains the target state for a single step of a fade.
+// Fields left at their zero value keep the value from the previous keyframe.
+type FadeKeyframe struct {
+	XY       []float32
+	Bri      int
+	CT       int
+	Duration time.Duration
+	Easing   Easing
+}
+
+type fadeState struct {
+	On             bool      `json:"on"`
+	Bri            int       `json:"bri,omitempty"`
+	XY             []float32 `json:"xy,omitempty"`
+	CT             int       `json:"ct,omitempty"`
+	TransitionTime int       `json:"transitiontime"`
+}
+
+// FadeLight fades the specified Phillips Hue light through each of the keyframes
+// in order. Fades may be longer than the bridge's transitiontime limit and may
+// use an easing curve. FadeLight blocks until the fade completes or the context
+// is done.
+func (h *Connection) FadeLight(ctx context.Context, light int, keyframes []FadeKeyframe) error {
+	// Error checking
+	err := validateKeyframes(keyframes)
+	if err != nil {
+		return err
+	}
+
+	current, err := h.GetLight(light)
+	if err != nil {
+		return fmt.Errorf("Light %d not found", light)
+	}
+
+	start := fadeState{
+		On:  true,
+		Bri: current.State.Bri,
+		XY:  current.State.XY,
+		CT:  current.State.CT,
+	}
+
+	return h.fade(ctx, start, keyframes, lightCommandInterval, func(state fadeState) error {
+		return h.changeLightStateContext(ctx, light, state)
+	})
+}
+
+// FadeGroup fades all lights in the specified Phillips Hue group through each of
+// the keyframes in order. Fades may be longer than the bridge's transitiontime
+// limit and may use an easing curve. FadeGroup blocks until the fade completes
+// or the context is done.
+func (h *Connection) FadeGroup(ctx context.Context, group int, keyframes []FadeKeyframe) error {
+	// Error checking
+	err := validateKeyframes(keyframes)
+	if err != nil {
+		return err
+	}
+
+	current, err := h.GetGroup(group)
+	if err != nil {
+		return fmt.Errorf("Group %d not found", group)
+	}
+
+	start := fadeState{
+		On:  true,
+		Bri: current.Action.Bri,
+		XY:  current.Action.XY,
+		CT:  current.Action.CT,
+	}
+
+	return h.fade(ctx, start, keyframes, groupCommandInterval, func(state fadeState) error {
+		return h.updateGroupContext(ctx, group, "state", state)
+	})
+}
+
+func (h *Connection) fade(ctx context.Context, start fadeState, keyframes []FadeKeyframe, minStep time.Duration, send func(state fadeState) error) error {
+	from := start
+
+	for _, k := range keyframes {
+		to := from
+		if k.Bri != 0 {
+			to.Bri = k.Bri
+		}
+		if len(k.XY) == 2 {
+			to.XY = k.XY
+			to.CT = 0
+		}
+		if k.CT != 0 {
+			to.CT = k.CT
+			to.XY = nil
+		}
+
+		easing := k.Easing
+		if easing == nil {
+			easing = EaseLinear
+		}
+
+		segments := fadeSegments(k.Duration, minStep, k.Easing != nil)
+		segmentDuration := k.Duration / time.Duration(segments)
+
+		for i := 1; i <= segments; i++ {
+			state := interpolateFadeState(from, to, easing(float64(i)/float64(segments)))
+			state.TransitionTime = int(segmentDuration / (100 * time.Millisecond))
+
+			err := send(state)
+			if err != nil {
+				return err
+			}
+
+			err = sleepContext(ctx, segmentDuration)
+			if err != nil {
+				return err
+			}
+		}
+
+		from = to
+	}
+
+	return nil
+}
+
+// fadeSegments returns the number of commands needed for a keyframe of the
+// specified duration
+func fadeSegments(duration, minStep time.Duration, eased bool) int {
+	segments := 1
+	if eased {
+		segments = fadeEasingSegments
+		if fit := int(duration / minStep); fit < segments {
+			segments = fit
+		}
+	}
+
+	// Each command must fit within the bridge's transitiontime limit
+	if needed := int((duration + maxTransitionTime - 1) / maxTransitionTime); needed > segments {
+		segments = needed
+	}
+
+	if segments < 1 {
+		segments = 1
+	}
+
+	return segments
+}
+
+func interpolateFadeState(from, to fadeState, progress float64) fadeState {
+	state := fadeState{
+		On:  true,
+		Bri: from.Bri + int(math.Round(float64(to.Bri-from.Bri)*progress)),
+		XY:  to.XY,
+		CT:  to.CT,
+	}
+
+	// Colors can only be interpolated when both ends use the same color mode
+	if len(from.XY) == 2 && len(to.XY) == 2 {
+		state.XY = []float32{
+			from.XY[0] + float32(float64(to.XY[0]-from.XY[0])*progress),
+			from.XY[1] + float32(float64(to.XY[1]-from.XY[1])*progress),
+		}
+	}
+
+	if from.CT != 0 && to.CT != 0 {
+		state.CT = from.CT + int(math.Round(float64(to.CT-from.CT)*progress))
+	}
+
+	return state
+}
+
+func validateKeyframes(keyframes []FadeKeyframe) error {
+	if len(keyframes) == 0 {
+		return errors.New("Keyframes must not be empty")
+	}
+
+	for i, k := range keyframes {
+		if k.Duration < 0 {
+			return fmt.Errorf("Invalid keyframe %d: duration must not be negative", i)
+		}
+
+		if k.Bri < 0 || k.Bri > 254 {
+			return fmt.Errorf("Invalid keyframe %d: bri must be between 1 and 254", i)
+		}
+
+		if k.XY != nil {
+			if len(k.XY) != 2 {
+				return fmt.Errorf("Invalid keyframe %d: xy must contain exactly 2 values", i)
+			}
+
+			if k.XY[0] < 0 || k.XY[0] > 1 || k.XY[1] < 0 || k.XY[1] > 1 {
+				return fmt.Errorf("Invalid keyframe %d: x and y must be between 0 and 1", i)
+			}
+		}
+
+		if k.CT != 0 && (k.CT < 153 || k.CT > 500) {
+			return fmt.Errorf("Invalid keyframe %d: ct must be between 153 and 500", i)
+		}
+
+		if k.XY != nil && k.CT != 0 {
+			return fmt.Errorf("Invalid keyframe %d: only one of xy and ct can be set", i)
+		}
+	}
+
+	return nil
+}
+
+// sleepContext pauses for the specified duration, or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
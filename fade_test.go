@@ -0,0 +1,154 @@
+package hue
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFadeLight(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Successful fade", func(t *testing.T) {
+		keyframes := []FadeKeyframe{
+			{Bri: 200, XY: []float32{0.3, 0.3}},
+			{Bri: 50, CT: 300},
+		}
+
+		err := h.FadeLight(context.Background(), 1, keyframes)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Light doesn't exist", func(t *testing.T) {
+		err := h.FadeLight(context.Background(), 3, []FadeKeyframe{{Bri: 100}})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Light 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("No keyframes", func(t *testing.T) {
+		err := h.FadeLight(context.Background(), 1, nil)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Keyframes must not be empty"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid keyframe", func(t *testing.T) {
+		err := h.FadeLight(context.Background(), 1, []FadeKeyframe{{Bri: 100}, {Bri: 300}})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Invalid keyframe 1: bri must be between 1 and 254"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := h.FadeLight(ctx, 1, []FadeKeyframe{{Bri: 100, Duration: time.Second}})
+		if err != context.Canceled {
+			t.Fatalf("Expected error to equal %v, got %v", context.Canceled, err)
+		}
+	})
+}
+
+func TestFadeGroup(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Successful fade", func(t *testing.T) {
+		err := h.FadeGroup(context.Background(), 1, []FadeKeyframe{{Bri: 200}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Group doesn't exist", func(t *testing.T) {
+		err := h.FadeGroup(context.Background(), 3, []FadeKeyframe{{Bri: 100}})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Group 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+}
+
+func TestFadeSegments(t *testing.T) {
+	t.Run("Linear", func(t *testing.T) {
+		expected := 1
+		if segments := fadeSegments(time.Hour, lightCommandInterval, false); segments != expected {
+			t.Fatalf("Expected %d segments, got %d", expected, segments)
+		}
+	})
+
+	t.Run("Longer than transitiontime limit", func(t *testing.T) {
+		expected := 3
+		if segments := fadeSegments(5*time.Hour, lightCommandInterval, false); segments != expected {
+			t.Fatalf("Expected %d segments, got %d", expected, segments)
+		}
+	})
+
+	t.Run("Eased", func(t *testing.T) {
+		expected := fadeEasingSegments
+		if segments := fadeSegments(time.Minute, lightCommandInterval, true); segments != expected {
+			t.Fatalf("Expected %d segments, got %d", expected, segments)
+		}
+	})
+
+	t.Run("Eased but limited by rate", func(t *testing.T) {
+		expected := 3
+		if segments := fadeSegments(3*time.Second, groupCommandInterval, true); segments != expected {
+			t.Fatalf("Expected %d segments, got %d", expected, segments)
+		}
+	})
+}
+
+func TestInterpolateFadeState(t *testing.T) {
+	from := fadeState{Bri: 100, XY: []float32{0.2, 0.2}}
+	to := fadeState{Bri: 200, XY: []float32{0.4, 0.6}}
+
+	state := interpolateFadeState(from, to, 0.5)
+
+	{
+		expected := 150
+		if state.Bri != expected {
+			t.Fatalf("Expected Bri to equal %d, got %d", expected, state.Bri)
+		}
+	}
+
+	{
+		expected := []float32{0.3, 0.4}
+		if math.Abs(float64(state.XY[0]-expected[0])) > 0.0001 || math.Abs(float64(state.XY[1]-expected[1])) > 0.0001 {
+			t.Fatalf("Expected XY to equal %v, got %v", expected, state.XY)
+		}
+	}
+}
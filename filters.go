@@ -0,0 +1,217 @@
+package hue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LightFilter returns true if the light should be included in a listing
+type LightFilter func(light Light) bool
+
+// GroupFilter returns true if the group should be included in a listing
+type GroupFilter func(group Group) bool
+
+// SensorFilter returns true if the sensor should be included in a listing
+type SensorFilter func(sensor Sensor) bool
+
+// LightIsOn includes lights that are on
+func LightIsOn() LightFilter {
+	return func(light Light) bool {
+		return light.State.On
+	}
+}
+
+// LightIsOff includes lights that are off
+func LightIsOff() LightFilter {
+	return func(light Light) bool {
+		return !light.State.On
+	}
+}
+
+// LightIsReachable includes lights the bridge can reach
+func LightIsReachable() LightFilter {
+	return func(light Light) bool {
+		return light.State.Reachable
+	}
+}
+
+// LightIsUnreachable includes lights the bridge can't reach
+func LightIsUnreachable() LightFilter {
+	return func(light Light) bool {
+		return !light.State.Reachable
+	}
+}
+
+// LightModel includes lights with the specified model ID
+func LightModel(modelID string) LightFilter {
+	return func(light Light) bool {
+		return strings.EqualFold(light.ModelID, modelID)
+	}
+}
+
+// LightType includes lights of the specified type, for example Extended color light
+func LightType(lightType string) LightFilter {
+	return func(light Light) bool {
+		return strings.EqualFold(light.Type, lightType)
+	}
+}
+
+// LightArchetype includes lights with the specified archetype, for example sultanbulb
+func LightArchetype(archetype string) LightFilter {
+	return func(light Light) bool {
+		return strings.EqualFold(light.Config.ArcheType, archetype)
+	}
+}
+
+// LightInGroup includes lights that are in the specified group
+func LightInGroup(group Group) LightFilter {
+	return func(light Light) bool {
+		for _, l := range group.Lights {
+			if l == strconv.Itoa(light.ID) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// GroupHasType includes groups of the specified type, for example Room
+func GroupHasType(groupType GroupType) GroupFilter {
+	return func(group Group) bool {
+		return strings.EqualFold(string(group.Type), string(groupType))
+	}
+}
+
+// GroupHasClass includes groups of the specified class, for example Kitchen
+func GroupHasClass(class RoomClass) GroupFilter {
+	return func(group Group) bool {
+		return strings.EqualFold(string(group.Class), string(class))
+	}
+}
+
+// GroupAnyOn includes groups with at least one light on
+func GroupAnyOn() GroupFilter {
+	return func(group Group) bool {
+		return group.State.AnyOn
+	}
+}
+
+// SensorType includes sensors of the specified type, for example ZLLPresence
+func SensorType(sensorType string) SensorFilter {
+	return func(sensor Sensor) bool {
+		return strings.EqualFold(sensor.Type, sensorType)
+	}
+}
+
+// SensorModel includes sensors with the specified model ID
+func SensorModel(modelID string) SensorFilter {
+	return func(sensor Sensor) bool {
+		return strings.EqualFold(sensor.ModelID, modelID)
+	}
+}
+
+// FilterLights returns the lights that match all of the filters
+func FilterLights(lights []Light, filters ...LightFilter) []Light {
+	filtered := []Light{}
+
+	for _, light := range lights {
+		include := true
+		for _, f := range filters {
+			if !f(light) {
+				include = false
+				break
+			}
+		}
+
+		if include {
+			filtered = append(filtered, light)
+		}
+	}
+
+	return filtered
+}
+
+// FilterGroups returns the groups that match all of the filters
+func FilterGroups(groups []Group, filters ...GroupFilter) []Group {
+	filtered := []Group{}
+
+	for _, group := range groups {
+		include := true
+		for _, f := range filters {
+			if !f(group) {
+				include = false
+				break
+			}
+		}
+
+		if include {
+			filtered = append(filtered, group)
+		}
+	}
+
+	return filtered
+}
+
+// FilterSensors returns the sensors that match all of the filters
+func FilterSensors(sensors []Sensor, filters ...SensorFilter) []Sensor {
+	filtered := []Sensor{}
+
+	for _, sensor := range sensors {
+		include := true
+		for _, f := range filters {
+			if !f(sensor) {
+				include = false
+				break
+			}
+		}
+
+		if include {
+			filtered = append(filtered, sensor)
+		}
+	}
+
+	return filtered
+}
+
+// FindLights gets all Phillips Hue lights that match all of the filters, sorted by ID
+func (h *Connection) FindLights(filters ...LightFilter) ([]Light, error) {
+	lights, err := h.GetLights()
+	if err != nil {
+		return []Light{}, err
+	}
+
+	return FilterLights(lights, filters...), nil
+}
+
+// FindGroups gets all Phillips Hue groups that match all of the filters, sorted by ID
+func (h *Connection) FindGroups(filters ...GroupFilter) ([]Group, error) {
+	groups, err := h.GetGroups()
+	if err != nil {
+		return []Group{}, err
+	}
+
+	return FilterGroups(groups, filters...), nil
+}
+
+// FindSensors gets all Phillips Hue sensors that match all of the filters, sorted by ID
+func (h *Connection) FindSensors(filters ...SensorFilter) ([]Sensor, error) {
+	sensors, err := h.GetSensors()
+	if err != nil {
+		return []Sensor{}, err
+	}
+
+	return FilterSensors(sensors, filters...), nil
+}
+
+// GetLightsInGroup gets all Phillips Hue lights in the specified group, for
+// example a room, sorted by ID
+func (h *Connection) GetLightsInGroup(group int, filters ...LightFilter) ([]Light, error) {
+	g, err := h.GetGroup(group)
+	if err != nil {
+		return []Light{}, fmt.Errorf("Group %d not found", group)
+	}
+
+	return h.FindLights(append([]LightFilter{LightInGroup(g)}, filters...)...)
+}
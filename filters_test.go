@@ -0,0 +1,179 @@
+package hue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSortedListings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"10": {"name": "Ten"}, "2": {"name": "Two"}, "1": {"name": "One"}, "7": {"name": "Seven"}}`))
+	}))
+	defer server.Close()
+
+	h := Connection{
+		baseURL:       server.URL,
+		isInitialized: true,
+	}
+
+	t.Run("Lights", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			lights, err := h.GetLights()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := []int{1, 2, 7, 10}
+			if len(lights) != len(expected) {
+				t.Fatalf("Expected %d lights, got %d", len(expected), len(lights))
+			}
+
+			for j, light := range lights {
+				if light.ID != expected[j] {
+					t.Fatalf("Expected light %d to have ID %d, got %d", j, expected[j], light.ID)
+				}
+			}
+		}
+	})
+
+	t.Run("Groups", func(t *testing.T) {
+		groups, err := h.GetGroups()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []int{1, 2, 7, 10}
+		if len(groups) != len(expected) {
+			t.Fatalf("Expected %d groups, got %d", len(expected), len(groups))
+		}
+
+		for j, group := range groups {
+			if group.ID != expected[j] {
+				t.Fatalf("Expected group %d to have ID %d, got %d", j, expected[j], group.ID)
+			}
+		}
+	})
+
+	t.Run("Scenes", func(t *testing.T) {
+		scenes, err := h.GetScenes()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{"1", "10", "2", "7"}
+		if len(scenes) != len(expected) {
+			t.Fatalf("Expected %d scenes, got %d", len(expected), len(scenes))
+		}
+
+		for j, scene := range scenes {
+			if scene.ID != expected[j] {
+				t.Fatalf("Expected scene %d to have ID %s, got %s", j, expected[j], scene.ID)
+			}
+		}
+	})
+}
+
+func TestFindLights(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Filters match", func(t *testing.T) {
+		lights, err := h.FindLights(LightIsOff(), LightIsReachable(), LightModel("lct016"), LightArchetype("sultanbulb"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 1
+		if len(lights) != expected {
+			t.Fatalf("Expected %d lights, got %d", expected, len(lights))
+		}
+	})
+
+	t.Run("Filters don't match", func(t *testing.T) {
+		lights, err := h.FindLights(LightIsOn())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 0
+		if len(lights) != expected {
+			t.Fatalf("Expected %d lights, got %d", expected, len(lights))
+		}
+	})
+}
+
+func TestGetLightsInGroup(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Group exists", func(t *testing.T) {
+		lights, err := h.GetLightsInGroup(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 1
+		if len(lights) != expected {
+			t.Fatalf("Expected %d lights, got %d", expected, len(lights))
+		}
+	})
+
+	t.Run("Group doesn't exist", func(t *testing.T) {
+		_, err := h.GetLightsInGroup(3)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Group 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+}
+
+func TestFindGroups(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	{
+		groups, err := h.FindGroups(GroupHasType(GroupTypeLightGroup))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 1
+		if len(groups) != expected {
+			t.Fatalf("Expected %d groups, got %d", expected, len(groups))
+		}
+	}
+
+	{
+		groups, err := h.FindGroups(GroupHasType(GroupTypeRoom), GroupHasClass(ClassKitchen))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 0
+		if len(groups) != expected {
+			t.Fatalf("Expected %d groups, got %d", expected, len(groups))
+		}
+	}
+}
+
+func TestFindSensors(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	sensors, err := h.FindSensors(SensorType("Daylight"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := 1
+	if len(sensors) != expected {
+		t.Fatalf("Expected %d sensors, got %d", expected, len(sensors))
+	}
+}
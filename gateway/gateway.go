@@ -0,0 +1,647 @@
+// Package gateway serves a REST API over a hue.Connection, so several local
+// clients can use a bridge without each one pairing with it.
+//
+// Lights, groups, scenes, and sensors can be referred to by name or ID in
+// paths. Every client authenticates with a bearer token and is rate limited
+// separately, and all clients share the Connection and its rate limits.
+//
+//	GET  /lights                           list all lights
+//	GET  /lights/{light}                   get a light
+//	PUT  /lights/{light}/state             change the state of a light
+//	GET  /groups                           list all groups
+//	GET  /groups/{group}                   get a group
+//	PUT  /groups/{group}/state             change the state of all lights in a group
+//	GET  /groups/{group}/scenes            list the scenes of a group
+//	POST /groups/{group}/scenes/{scene}    recall a scene in a group
+//	GET  /scenes                           list all scenes
+//	GET  /scenes/{scene}                   get a scene
+//	GET  /sensors                          list all sensors
+//	GET  /sensors/{sensor}                 get a sensor
+//	POST /batch                            send several requests at once
+//	GET  /events                           receive a hue.Snapshot over a WebSocket whenever anything changes
+//
+// Errors are returned as a JSON object with an error field containing an
+// Error.
+//
+// If enabled, a web dashboard is served at / for controlling rooms and lights
+// from a browser. It asks for a token, and is live updated over /events.
+// Browsers can't set headers on WebSockets, so the token may also be sent as
+// the access_token query parameter to /events.
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattvella07/hue"
+)
+
+// Error types returned by the gateway
+const (
+	ErrorUnauthorized      = "unauthorized"
+	ErrorRateLimited       = "rate_limited"
+	ErrorInvalidRequest    = "invalid_request"
+	ErrorNotFound          = "not_found"
+	ErrorAmbiguousName     = "ambiguous_name"
+	ErrorBridge            = "bridge_error"
+	ErrorBridgeUnreachable = "bridge_unreachable"
+)
+
+// maxBodySize is the largest request body accepted, in bytes
+const maxBodySize = 1 << 20
+
+// maxBatchSize is the largest number of requests in a single batch
+const maxBatchSize = 50
+
+// Config contains the settings of a gateway
+type Config struct {
+	// Tokens maps each bearer token to the name of the client using it.
+	// Requests without one of the tokens are rejected.
+	Tokens map[string]string
+
+	// RateLimit is the number of requests per second each client may send.
+	// If 0, 10 requests per second are allowed.
+	RateLimit float64
+
+	// Burst is the number of requests a client may send at once before it's
+	// limited. If 0, RateLimit rounded up is used.
+	Burst int
+
+	// UI serves the web dashboard at /
+	UI bool
+
+	// WatchInterval is how often the bridge is polled for changes while any
+	// client is connected to /events. If 0, it's polled every 2 seconds.
+	WatchInterval time.Duration
+}
+
+// Error is returned for every request that fails
+type Error struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+
+	// IDs contains the matching resources when a name is ambiguous
+	IDs []string `json:"ids,omitempty"`
+
+	// BridgeErrors contains the errors returned by the bridge
+	BridgeErrors hue.BridgeErrors `json:"bridge_errors,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type errorResponse struct {
+	Error *Error `json:"error"`
+}
+
+// State is the body of a request to change the state of a light or group.
+// Setting Bri, XY, RGB, or CT turns the lights on.
+type State struct {
+	On  *bool     `json:"on,omitempty"`
+	Bri int       `json:"bri,omitempty"`
+	XY  []float32 `json:"xy,omitempty"`
+	CT  int       `json:"ct,omitempty"`
+
+	// RGB is a hex color such as #ff8000, which is converted to xy
+	RGB string `json:"rgb,omitempty"`
+
+	// TransitionMS is how long the change takes in milliseconds
+	TransitionMS int `json:"transition_ms,omitempty"`
+}
+
+// BatchRequest is a single request in a batch
+type BatchRequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResponse is the response to a single request in a batch
+type BatchResponse struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type batch struct {
+	Requests []BatchRequest `json:"requests"`
+}
+
+type batchResult struct {
+	Responses []BatchResponse `json:"responses"`
+}
+
+// Handler serves the REST API
+type Handler struct {
+	h       *hue.Connection
+	config  Config
+	mux     *http.ServeMux
+	limiter *clientLimiter
+
+	watchMu       sync.Mutex
+	watchInterval time.Duration
+	watcher       *hue.Watcher
+	watchers      int
+}
+
+// NewHandler creates a Handler that sends all requests to the bridge through
+// the Connection
+func NewHandler(h *hue.Connection, config Config) *Handler {
+	rate := config.RateLimit
+	if rate <= 0 {
+		rate = 10
+	}
+
+	burst := config.Burst
+	if burst <= 0 {
+		burst = int(rate + 0.999)
+	}
+
+	watchInterval := config.WatchInterval
+	if watchInterval <= 0 {
+		watchInterval = 2 * time.Second
+	}
+
+	g := &Handler{
+		h:             h,
+		config:        config,
+		mux:           http.NewServeMux(),
+		limiter:       newClientLimiter(rate, burst),
+		watchInterval: watchInterval,
+	}
+
+	g.mux.HandleFunc("GET /lights", g.getLights)
+	g.mux.HandleFunc("GET /lights/{light}", g.getLight)
+	g.mux.HandleFunc("PUT /lights/{light}/state", g.setLightState)
+	g.mux.HandleFunc("GET /groups", g.getGroups)
+	g.mux.HandleFunc("GET /groups/{group}", g.getGroup)
+	g.mux.HandleFunc("PUT /groups/{group}/state", g.setGroupState)
+	g.mux.HandleFunc("GET /groups/{group}/scenes", g.getGroupScenes)
+	g.mux.HandleFunc("POST /groups/{group}/scenes/{scene}", g.recallScene)
+	g.mux.HandleFunc("GET /scenes", g.getScenes)
+	g.mux.HandleFunc("GET /scenes/{scene}", g.getScene)
+	g.mux.HandleFunc("GET /sensors", g.getSensors)
+	g.mux.HandleFunc("GET /sensors/{sensor}", g.getSensor)
+
+	return g
+}
+
+// ServeHTTP authenticates the client, then serves the request
+func (g *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.config.UI && isUIRequest(r) {
+		g.serveUI(w, r)
+		return
+	}
+
+	client, ok := g.client(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="hue"`)
+		writeError(w, http.StatusUnauthorized, &Error{Type: ErrorUnauthorized, Message: "A valid bearer token is required"})
+		return
+	}
+
+	if r.URL.Path == "/batch" {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			writeError(w, http.StatusMethodNotAllowed, &Error{Type: ErrorInvalidRequest, Message: "Method must be POST"})
+			return
+		}
+
+		g.batch(client, w, r)
+		return
+	}
+
+	if r.Method == "GET" && r.URL.Path == "/events" {
+		if g.limit(client, w) {
+			g.events(w, r)
+		}
+		return
+	}
+
+	g.serve(client, w, r)
+}
+
+// client returns the name of the client whose token is in the request
+func (g *Handler) client(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && r.URL.Path == "/events" {
+		token, ok = r.URL.Query().Get("access_token"), true
+	}
+	if !ok || token == "" {
+		return "", false
+	}
+
+	for t, client := range g.config.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return client, true
+		}
+	}
+
+	return "", false
+}
+
+// limit returns true if the client may send a request now, and otherwise
+// writes an error
+func (g *Handler) limit(client string, w http.ResponseWriter) bool {
+	allowed, retryAfter := g.limiter.allow(client)
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		writeError(w, http.StatusTooManyRequests, &Error{Type: ErrorRateLimited, Message: fmt.Sprintf("Client %s is sending too many requests", client)})
+	}
+
+	return allowed
+}
+
+// serve rate limits a single request from the client and routes it
+func (g *Handler) serve(client string, w http.ResponseWriter, r *http.Request) {
+	if !g.limit(client, w) {
+		return
+	}
+
+	if _, pattern := g.mux.Handler(r); pattern == "" {
+		writeError(w, http.StatusNotFound, &Error{Type: ErrorNotFound, Message: fmt.Sprintf("%s %s not found", r.Method, r.URL.Path)})
+		return
+	}
+
+	g.mux.ServeHTTP(w, r)
+}
+
+// batch serves each request in a batch in order. Each request is rate limited
+// separately, and a failed request doesn't stop the rest.
+func (g *Handler) batch(client string, w http.ResponseWriter, r *http.Request) {
+	b := batch{}
+	if !decode(w, r, &b) {
+		return
+	}
+
+	if len(b.Requests) > maxBatchSize {
+		writeError(w, http.StatusBadRequest, &Error{Type: ErrorInvalidRequest, Message: fmt.Sprintf("Batch must contain at most %d requests", maxBatchSize)})
+		return
+	}
+
+	result := batchResult{Responses: []BatchResponse{}}
+
+	for _, br := range b.Requests {
+		req, err := http.NewRequestWithContext(r.Context(), strings.ToUpper(br.Method), br.Path, strings.NewReader(string(br.Body)))
+		if err != nil || !strings.HasPrefix(br.Path, "/") {
+			body, _ := json.Marshal(errorResponse{Error: &Error{Type: ErrorInvalidRequest, Message: fmt.Sprintf("Invalid request %s %s", br.Method, br.Path)}})
+			result.Responses = append(result.Responses, BatchResponse{Status: http.StatusBadRequest, Body: body})
+			continue
+		}
+
+		rec := &recorder{header: http.Header{}}
+		g.serve(client, rec, req)
+
+		result.Responses = append(result.Responses, BatchResponse{Status: rec.status, Body: rec.body})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (g *Handler) getLights(w http.ResponseWriter, r *http.Request) {
+	lights, err := g.h.GetLights()
+	g.respond(w, lights, err)
+}
+
+func (g *Handler) getLight(w http.ResponseWriter, r *http.Request) {
+	light, err := g.h.ResolveLight(r.PathValue("light"))
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	l, err := g.h.GetLight(light)
+	l.ID = light
+	g.respond(w, l, err)
+}
+
+func (g *Handler) setLightState(w http.ResponseWriter, r *http.Request) {
+	state := State{}
+	if !decode(w, r, &state) {
+		return
+	}
+
+	light, err := g.h.ResolveLight(r.PathValue("light"))
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	err = setState(state, stateSetters{
+		on:  func() error { return g.h.TurnOnLight(light) },
+		off: func() error { return g.h.TurnOffLight(light) },
+		set: func(u hue.LightStateUpdate) error { return g.h.SetLightState(light, u) },
+	})
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	l, err := g.h.GetLight(light)
+	l.ID = light
+	g.respond(w, l, err)
+}
+
+func (g *Handler) getGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := g.h.GetGroups()
+	g.respond(w, groups, err)
+}
+
+func (g *Handler) getGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := g.h.ResolveGroup(r.PathValue("group"))
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	grp, err := g.h.GetGroup(group)
+	grp.ID = group
+	g.respond(w, grp, err)
+}
+
+func (g *Handler) setGroupState(w http.ResponseWriter, r *http.Request) {
+	state := State{}
+	if !decode(w, r, &state) {
+		return
+	}
+
+	group, err := g.h.ResolveGroup(r.PathValue("group"))
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	err = setState(state, stateSetters{
+		on:  func() error { return g.h.TurnOnGroup(group) },
+		off: func() error { return g.h.TurnOffGroup(group) },
+		set: func(u hue.LightStateUpdate) error {
+			return g.h.SetGroupAction(group, hue.GroupActionUpdate{On: u.On, Bri: u.Bri, XY: u.XY, CT: u.CT, TransitionTime: u.TransitionTime})
+		},
+	})
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	grp, err := g.h.GetGroup(group)
+	grp.ID = group
+	g.respond(w, grp, err)
+}
+
+func (g *Handler) getGroupScenes(w http.ResponseWriter, r *http.Request) {
+	group, err := g.h.ResolveGroup(r.PathValue("group"))
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	scenes, err := g.h.GetScenes()
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	inGroup := []hue.Scene{}
+	for _, s := range scenes {
+		if s.Group == strconv.Itoa(group) {
+			inGroup = append(inGroup, s)
+		}
+	}
+
+	g.respond(w, inGroup, nil)
+}
+
+func (g *Handler) recallScene(w http.ResponseWriter, r *http.Request) {
+	group, err := g.h.ResolveGroup(r.PathValue("group"))
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	scene, err := g.h.ResolveSceneInGroup(group, r.PathValue("scene"))
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	err = g.h.RecallScene(group, scene, hue.SceneTransition)
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	grp, err := g.h.GetGroup(group)
+	grp.ID = group
+	g.respond(w, grp, err)
+}
+
+func (g *Handler) getScenes(w http.ResponseWriter, r *http.Request) {
+	scenes, err := g.h.GetScenes()
+	g.respond(w, scenes, err)
+}
+
+func (g *Handler) getScene(w http.ResponseWriter, r *http.Request) {
+	scene, err := g.h.ResolveScene(r.PathValue("scene"))
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	s, err := g.h.GetScene(scene)
+	s.ID = scene
+	g.respond(w, s, err)
+}
+
+func (g *Handler) getSensors(w http.ResponseWriter, r *http.Request) {
+	sensors, err := g.h.GetSensors()
+	g.respond(w, sensors, err)
+}
+
+func (g *Handler) getSensor(w http.ResponseWriter, r *http.Request) {
+	sensor, err := g.h.ResolveSensor(r.PathValue("sensor"))
+	if err != nil {
+		g.respond(w, nil, err)
+		return
+	}
+
+	s, err := g.h.GetSensor(sensor)
+	s.ID = sensor
+	g.respond(w, s, err)
+}
+
+// stateSetters change the state of a light or group
+type stateSetters struct {
+	on  func() error
+	off func() error
+	set func(update hue.LightStateUpdate) error
+}
+
+// maxTransitionMS is the longest transition the bridge supports in a single
+// state update
+const maxTransitionMS = 65535 * 100
+
+// setState changes the state of a light or group. Lights are turned off if on
+// is false, and otherwise brightness and color are changed with a single state
+// update taking the transition, which the bridge carries out.
+func setState(state State, setters stateSetters) error {
+	if state.RGB != "" {
+		if state.XY != nil {
+			return &Error{Type: ErrorInvalidRequest, Message: "xy and rgb can't both be set"}
+		}
+
+		xy, err := parseRGB(state.RGB)
+		if err != nil {
+			return err
+		}
+		state.XY = xy
+	}
+
+	hasColor := state.Bri != 0 || state.XY != nil || state.CT != 0
+
+	switch {
+	case state.On != nil && !*state.On:
+		if hasColor {
+			return &Error{Type: ErrorInvalidRequest, Message: "bri, xy, rgb, and ct can't be set when on is false"}
+		}
+		return setters.off()
+	case hasColor:
+		if state.TransitionMS < 0 || state.TransitionMS > maxTransitionMS {
+			return &Error{Type: ErrorInvalidRequest, Message: fmt.Sprintf("transition_ms must be between 0 and %d", maxTransitionMS)}
+		}
+
+		on := true
+		transitionTime := (state.TransitionMS + 50) / 100
+		update := hue.LightStateUpdate{On: &on, XY: state.XY, TransitionTime: &transitionTime}
+		if state.Bri != 0 {
+			update.Bri = &state.Bri
+		}
+		if state.CT != 0 {
+			update.CT = &state.CT
+		}
+
+		return setters.set(update)
+	case state.On != nil:
+		return setters.on()
+	}
+
+	return &Error{Type: ErrorInvalidRequest, Message: "State must contain at least one of on, bri, xy, rgb, or ct"}
+}
+
+// parseRGB converts a hex color of the form #rrggbb to xy
+func parseRGB(value string) ([]float32, error) {
+	hex := strings.TrimPrefix(value, "#")
+
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return nil, &Error{Type: ErrorInvalidRequest, Message: fmt.Sprintf("Invalid rgb value %s: must be of the form #rrggbb", value)}
+	}
+
+	return hue.RGBToXY(uint8(n>>16), uint8(n>>8), uint8(n)), nil
+}
+
+// respond writes the value, or the error if there is one
+func (g *Handler) respond(w http.ResponseWriter, value interface{}, err error) {
+	if err != nil {
+		status, e := toError(err)
+		writeError(w, status, e)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, value)
+}
+
+// toError converts an error from the library to an Error and its HTTP status
+func toError(err error) (int, *Error) {
+	var gatewayErr *Error
+	var notFoundErr *hue.NotFoundError
+	var ambiguousErr *hue.AmbiguousNameError
+	var bridgeErrs hue.BridgeErrors
+	var urlErr *url.Error
+	var opErr *net.OpError
+
+	switch {
+	case errors.As(err, &gatewayErr):
+		return http.StatusBadRequest, gatewayErr
+	case errors.As(err, &notFoundErr):
+		return http.StatusNotFound, &Error{Type: ErrorNotFound, Message: err.Error()}
+	case errors.As(err, &ambiguousErr):
+		return http.StatusConflict, &Error{Type: ErrorAmbiguousName, Message: err.Error(), IDs: ambiguousErr.IDs}
+	case errors.As(err, &bridgeErrs):
+		status := http.StatusBadGateway
+		if bridgeErrs.HasType(hue.ErrorResourceNotAvailable) {
+			status = http.StatusNotFound
+		}
+
+		return status, &Error{Type: ErrorBridge, Message: strings.TrimSpace(err.Error()), BridgeErrors: bridgeErrs}
+	case errors.As(err, &urlErr), errors.As(err, &opErr), errors.Is(err, hue.ErrDiscovery):
+		return http.StatusServiceUnavailable, &Error{Type: ErrorBridgeUnreachable, Message: err.Error()}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable, &Error{Type: ErrorBridgeUnreachable, Message: err.Error()}
+	}
+
+	// The library validates arguments before sending them to the bridge
+	return http.StatusBadRequest, &Error{Type: ErrorInvalidRequest, Message: err.Error()}
+}
+
+// decode decodes the JSON body of the request, and writes an error if it's
+// invalid
+func decode(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	d := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	d.DisallowUnknownFields()
+
+	err := d.Decode(value)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, &Error{Type: ErrorInvalidRequest, Message: fmt.Sprintf("Invalid JSON body: %s", err)})
+		return false
+	}
+
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, err *Error) {
+	writeJSON(w, status, errorResponse{Error: err})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(value)
+}
+
+// recorder captures the response to a request in a batch
+type recorder struct {
+	header http.Header
+	status int
+	body   []byte
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body = append(r.body, data...)
+
+	return len(data), nil
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
@@ -0,0 +1,460 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattvella07/hue"
+	"github.com/mattvella07/hue/huetest"
+)
+
+const testToken = "secret"
+
+// createTestGateway creates a gateway in front of an emulated bridge with a
+// few lights and a room
+func createTestGateway(config Config) (*Handler, *huetest.Server) {
+	s := huetest.NewServer()
+	s.Bridge.AddLight("Desk", huetest.ExtendedColorLight)
+	s.Bridge.AddLight("Floor lamp", huetest.ColorTemperatureLight)
+	s.Bridge.AddGroup("Office", "Room", []string{"1", "2"})
+	s.Bridge.AddSensor("Motion", huetest.PresenceSensor)
+
+	if config.Tokens == nil {
+		config.Tokens = map[string]string{testToken: "tablet"}
+	}
+
+	return NewHandler(s.Connection(), config), s
+}
+
+// request sends a request to the gateway and decodes the response into value,
+// if it isn't nil
+func request(t *testing.T, g http.Handler, method, path string, body interface{}, value interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := httptest.NewRequest(method, path, bytes.NewReader(data))
+	r.Header.Set("Authorization", "Bearer "+testToken)
+
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+
+	if value != nil {
+		err := json.Unmarshal(w.Body.Bytes(), value)
+		if err != nil {
+			t.Fatalf("Unable to decode %s: %s", w.Body.String(), err)
+		}
+	}
+
+	return w
+}
+
+// expectError checks the status and type of an error response
+func expectError(t *testing.T, w *httptest.ResponseRecorder, status int, errorType string) *Error {
+	t.Helper()
+
+	resp := errorResponse{}
+
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil || resp.Error == nil {
+		t.Fatalf("Expected an error, got %d %s", w.Code, w.Body.String())
+	}
+
+	if w.Code != status || resp.Error.Type != errorType {
+		t.Fatalf("Expected %d %s, got %d %+v", status, errorType, w.Code, resp.Error)
+	}
+
+	return resp.Error
+}
+
+func TestLights(t *testing.T) {
+	g, s := createTestGateway(Config{})
+	defer s.Close()
+
+	lights := []hue.Light{}
+	request(t, g, "GET", "/lights", nil, &lights)
+
+	{
+		expected := 2
+		if len(lights) != expected {
+			t.Fatalf("Expected %d lights, got %d", expected, len(lights))
+		}
+	}
+
+	t.Run("By name", func(t *testing.T) {
+		light := hue.Light{}
+		request(t, g, "GET", "/lights/floor%20lamp", nil, &light)
+
+		if light.ID != 2 {
+			t.Fatalf("Expected light 2, got %+v", light)
+		}
+	})
+
+	t.Run("Set state", func(t *testing.T) {
+		light := hue.Light{}
+		w := request(t, g, "PUT", "/lights/Desk/state", State{Bri: 100, XY: []float32{0.3, 0.4}}, &light)
+
+		if w.Code != http.StatusOK || !light.State.On || light.State.Bri != 100 {
+			t.Fatalf("Expected the light to be on at bri 100, got %d %+v", w.Code, light.State)
+		}
+
+		// The bridge carries out the transition, so the request doesn't wait for it
+		start := time.Now()
+		w = request(t, g, "PUT", "/lights/Desk/state", State{Bri: 200, TransitionMS: 60000}, &light)
+
+		if w.Code != http.StatusOK || light.State.Bri != 200 || time.Since(start) > 5*time.Second {
+			t.Fatalf("Expected the light to be set at bri 200 without waiting, got %d %+v", w.Code, light.State)
+		}
+
+		off := false
+		request(t, g, "PUT", "/lights/Desk/state", State{On: &off}, &light)
+
+		if on, _ := s.Bridge.Get("lights/1/state/on"); on != false {
+			t.Fatalf("Expected light 1 to be off, got %v", on)
+		}
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		w := request(t, g, "GET", "/lights/Garage", nil, nil)
+		e := expectError(t, w, http.StatusNotFound, ErrorNotFound)
+
+		if e.Message != "Light Garage not found" {
+			t.Fatalf("Unexpected message %s", e.Message)
+		}
+	})
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		s.Bridge.AddLight("Desk", huetest.DimmableLight)
+
+		w := request(t, g, "GET", "/lights/Desk", nil, nil)
+		e := expectError(t, w, http.StatusConflict, ErrorAmbiguousName)
+
+		if len(e.IDs) != 2 {
+			t.Fatalf("Expected 2 IDs, got %v", e.IDs)
+		}
+	})
+
+	t.Run("Invalid state", func(t *testing.T) {
+		w := request(t, g, "PUT", "/lights/2/state", State{}, nil)
+		expectError(t, w, http.StatusBadRequest, ErrorInvalidRequest)
+
+		w = request(t, g, "PUT", "/lights/2/state", map[string]interface{}{"brightness": 5}, nil)
+		expectError(t, w, http.StatusBadRequest, ErrorInvalidRequest)
+
+		w = request(t, g, "PUT", "/lights/2/state", State{Bri: 5, TransitionMS: 7000000}, nil)
+		expectError(t, w, http.StatusBadRequest, ErrorInvalidRequest)
+	})
+
+	t.Run("Unknown route", func(t *testing.T) {
+		w := request(t, g, "DELETE", "/lights/1", nil, nil)
+		expectError(t, w, http.StatusNotFound, ErrorNotFound)
+	})
+}
+
+func TestGroupsAndScenes(t *testing.T) {
+	g, s := createTestGateway(Config{})
+	defer s.Close()
+
+	group := hue.Group{}
+	request(t, g, "PUT", "/groups/Office/state", State{On: boolPtr(true)}, &group)
+
+	if !group.State.AllOn {
+		t.Fatalf("Expected all lights to be on, got %+v", group.State)
+	}
+
+	err := s.Connection().CreateGroupScene("Bright", 1, false, hue.SceneAppData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenes := []hue.Scene{}
+	request(t, g, "GET", "/groups/office/scenes", nil, &scenes)
+
+	if len(scenes) != 1 || scenes[0].Name != "Bright" {
+		t.Fatalf("Expected the Bright scene, got %+v", scenes)
+	}
+
+	request(t, g, "PUT", "/groups/1/state", State{On: boolPtr(false)}, nil)
+
+	w := request(t, g, "POST", "/groups/Office/scenes/bright", nil, &group)
+	if w.Code != http.StatusOK || !group.State.AnyOn {
+		t.Fatalf("Expected the scene to turn the lights on, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = request(t, g, "POST", "/groups/Office/scenes/Relax", nil, nil)
+	expectError(t, w, http.StatusNotFound, ErrorNotFound)
+
+	sensor := hue.Sensor{}
+	request(t, g, "GET", "/sensors/Motion", nil, &sensor)
+
+	if sensor.Type != huetest.PresenceSensor {
+		t.Fatalf("Expected the motion sensor, got %+v", sensor)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	g, s := createTestGateway(Config{})
+	defer s.Close()
+
+	result := batchResult{}
+	w := request(t, g, "POST", "/batch", batch{Requests: []BatchRequest{
+		{Method: "PUT", Path: "/lights/Desk/state", Body: json.RawMessage(`{"on":true}`)},
+		{Method: "GET", Path: "/lights/Garage"},
+		{Method: "get", Path: "/lights/1"},
+	}}, &result)
+
+	if w.Code != http.StatusOK || len(result.Responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %d %s", w.Code, w.Body.String())
+	}
+
+	for i, expected := range []int{http.StatusOK, http.StatusNotFound, http.StatusOK} {
+		if result.Responses[i].Status != expected {
+			t.Fatalf("Expected response %d to have status %d, got %+v", i, expected, result.Responses[i])
+		}
+	}
+
+	light := hue.Light{}
+
+	err := json.Unmarshal(result.Responses[2].Body, &light)
+	if err != nil || !light.State.On {
+		t.Fatalf("Expected light 1 to be on, got %s", result.Responses[2].Body)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	g, s := createTestGateway(Config{})
+	defer s.Close()
+
+	for _, header := range []string{"", "Bearer", "Bearer wrong", "Basic " + testToken} {
+		r := httptest.NewRequest("GET", "/lights", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, r)
+
+		expectError(t, w, http.StatusUnauthorized, ErrorUnauthorized)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	g, s := createTestGateway(Config{
+		Tokens:    map[string]string{testToken: "tablet", "other": "script"},
+		RateLimit: 1,
+		Burst:     2,
+	})
+	defer s.Close()
+
+	now := time.Now()
+	g.limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		w := request(t, g, "GET", "/groups", nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to be allowed, got %d", i, w.Code)
+		}
+	}
+
+	w := request(t, g, "GET", "/groups", nil, nil)
+	expectError(t, w, http.StatusTooManyRequests, ErrorRateLimited)
+
+	if w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("Expected Retry-After of 1, got %s", w.Header().Get("Retry-After"))
+	}
+
+	// Other clients are limited separately
+	r := httptest.NewRequest("GET", "/groups", nil)
+	r.Header.Set("Authorization", "Bearer other")
+
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, r)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the other client to be allowed, got %d", rec.Code)
+	}
+
+	now = now.Add(time.Second)
+
+	w = request(t, g, "GET", "/groups", nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the request to be allowed after waiting, got %d", w.Code)
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestUI(t *testing.T) {
+	g, s := createTestGateway(Config{UI: true})
+	defer s.Close()
+
+	for path, expected := range map[string]string{
+		"/":             "<title>Hue</title>",
+		"/ui/app.js":    "new WebSocket",
+		"/ui/style.css": ".card",
+	} {
+		// The dashboard itself doesn't need a token
+		r := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), expected) {
+			t.Fatalf("Expected %s to contain %q, got %d", path, expected, w.Code)
+		}
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		g, s := createTestGateway(Config{})
+		defer s.Close()
+
+		w := request(t, g, "GET", "/", nil, nil)
+		expectError(t, w, http.StatusNotFound, ErrorNotFound)
+	})
+}
+
+func TestSetStateRGB(t *testing.T) {
+	g, s := createTestGateway(Config{})
+	defer s.Close()
+
+	light := hue.Light{}
+	request(t, g, "PUT", "/lights/Desk/state", State{RGB: "#ff0000"}, &light)
+
+	expected := hue.RGBToXY(255, 0, 0)
+	if !light.State.On || len(light.State.XY) != 2 || light.State.XY[0] != expected[0] {
+		t.Fatalf("Expected xy %v, got %+v", expected, light.State)
+	}
+
+	w := request(t, g, "PUT", "/lights/Desk/state", State{RGB: "red"}, nil)
+	expectError(t, w, http.StatusBadRequest, ErrorInvalidRequest)
+}
+
+func TestEvents(t *testing.T) {
+	g, s := createTestGateway(Config{WatchInterval: 10 * time.Millisecond})
+	defer s.Close()
+
+	server := httptest.NewServer(g)
+	defer server.Close()
+
+	t.Run("Not a WebSocket", func(t *testing.T) {
+		w := request(t, g, "GET", "/events", nil, nil)
+		expectError(t, w, http.StatusBadRequest, ErrorInvalidRequest)
+	})
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The example key and accept value from RFC 6455
+	fmt.Fprintf(conn, "GET /events?access_token=%s HTTP/1.1\r\nHost: hue\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", testToken)
+
+	reader := bufio.NewReader(conn)
+
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected handshake %d %v", resp.StatusCode, resp.Header)
+	}
+
+	next := func() hue.Snapshot {
+		t.Helper()
+
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			t.Fatal(err)
+		}
+
+		length := int(header[1] & 0x7F)
+		switch length {
+		case 126:
+			ext := make([]byte, 2)
+			io.ReadFull(reader, ext)
+			length = int(binary.BigEndian.Uint16(ext))
+		case 127:
+			ext := make([]byte, 8)
+			io.ReadFull(reader, ext)
+			length = int(binary.BigEndian.Uint64(ext))
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			t.Fatal(err)
+		}
+
+		if header[0] != 0x80|opText {
+			t.Fatalf("Expected a text frame, got %x", header[0])
+		}
+
+		snapshot := hue.Snapshot{}
+
+		err := json.Unmarshal(payload, &snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return snapshot
+	}
+
+	snapshot := next()
+	if len(snapshot.Lights) != 2 || len(snapshot.Groups) != 1 || len(snapshot.Sensors) != 1 {
+		t.Fatalf("Unexpected snapshot %+v", snapshot)
+	}
+
+	err = s.Connection().TurnOnLight(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes to the light are pushed. The room may change in a separate
+	// snapshot if the bridge was polled part way through.
+	changed := false
+	for !changed {
+		snapshot = next()
+		for _, c := range snapshot.Changes {
+			changed = changed || c == hue.Change{Resource: hue.ResourceLights, ID: 2}
+		}
+	}
+
+	if !snapshot.Lights[1].State.On {
+		t.Fatalf("Expected light 2 to be on, got %+v", snapshot.Lights[1])
+	}
+
+	// Closing the WebSocket stops the watcher
+	conn.Write([]byte{0x80 | opClose, 0x80, 0, 0, 0, 0})
+
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		g.watchMu.Lock()
+		stopped := g.watcher == nil
+		g.watchMu.Unlock()
+
+		if stopped {
+			return
+		}
+	}
+
+	t.Fatal("Expected the watcher to be stopped")
+}
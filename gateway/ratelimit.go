@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket for a single client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// clientLimiter limits the rate of requests from each client separately
+type clientLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   int
+	clients map[string]*bucket
+	now     func() time.Time
+}
+
+func newClientLimiter(rate float64, burst int) *clientLimiter {
+	return &clientLimiter{
+		rate:    rate,
+		burst:   burst,
+		clients: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// allow returns true if the client may send a request now. Otherwise it
+// returns how long until the client may send the next request.
+func (l *clientLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	b, ok := l.clients[client]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.clients[client] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+
+	b.tokens--
+
+	return true, 0
+}
@@ -0,0 +1,125 @@
+package gateway
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/mattvella07/hue"
+)
+
+// uiFiles contains the web dashboard. It doesn't load anything from other
+// servers, so it works without internet access.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// isUIRequest returns true if the request is for the web dashboard, which
+// doesn't require a token
+func isUIRequest(r *http.Request) bool {
+	return r.Method == "GET" && (r.URL.Path == "/" || strings.HasPrefix(r.URL.Path, "/ui/"))
+}
+
+// serveUI serves the static files of the web dashboard
+func (g *Handler) serveUI(w http.ResponseWriter, r *http.Request) {
+	files, _ := fs.Sub(uiFiles, "ui")
+
+	if r.URL.Path == "/" {
+		http.ServeFileFS(w, r, files, "index.html")
+		return
+	}
+
+	http.StripPrefix("/ui/", http.FileServerFS(files)).ServeHTTP(w, r)
+}
+
+// events pushes a Snapshot of all lights, groups, and sensors over a WebSocket
+// whenever they change
+func (g *Handler) events(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, &Error{Type: ErrorInvalidRequest, Message: err.Error()})
+		return
+	}
+	defer conn.close()
+
+	watcher, release, err := g.watch()
+	if err != nil {
+		return
+	}
+	defer release()
+
+	snapshots, unsubscribe := watcher.Subscribe()
+	defer unsubscribe()
+
+	// Frames from the browser are only read to answer pings and to notice
+	// when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		for {
+			opcode, payload, err := conn.readFrame()
+			if err != nil || opcode == opClose {
+				return
+			}
+
+			if opcode == opPing {
+				conn.writeFrame(opPong, payload)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case s, ok := <-snapshots:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(s)
+			if err != nil {
+				return
+			}
+
+			err = conn.writeFrame(opText, data)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// watch returns the Watcher shared by every WebSocket client, starting it if
+// needed. The returned function must be called when the client is done, and
+// stops the Watcher after the last client.
+func (g *Handler) watch() (*hue.Watcher, func(), error) {
+	g.watchMu.Lock()
+	defer g.watchMu.Unlock()
+
+	if g.watcher == nil {
+		watcher, err := g.h.Watch(g.watchInterval)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		g.watcher = watcher
+	}
+
+	g.watchers++
+	watcher := g.watcher
+
+	return watcher, func() {
+		g.watchMu.Lock()
+		defer g.watchMu.Unlock()
+
+		g.watchers--
+		if g.watchers == 0 {
+			g.watcher.Stop()
+			g.watcher = nil
+		}
+	}, nil
+}
@@ -0,0 +1,279 @@
+// Web dashboard for the gateway. Rooms, lights, and sensors are rendered from
+// the snapshots pushed over /events, and changes are sent to the REST API.
+"use strict";
+
+const tokenKey = "hue-gateway-token";
+
+let token = localStorage.getItem(tokenKey) || "";
+let scenes = [];
+let latest = null;
+let interacting = false;
+let socket = null;
+
+const rooms = document.getElementById("rooms");
+const sensors = document.getElementById("sensors");
+const status = document.getElementById("status");
+const login = document.getElementById("login");
+
+// el creates an element with the attributes and children
+function el(tag, attrs, ...children) {
+  const e = document.createElement(tag);
+
+  for (const [name, value] of Object.entries(attrs || {})) {
+    if (name.startsWith("on")) {
+      e.addEventListener(name.slice(2), value);
+    } else if (typeof value === "boolean") {
+      e[name] = value;
+    } else {
+      e.setAttribute(name, value);
+    }
+  }
+
+  for (const child of children) {
+    if (child !== null && child !== undefined) {
+      e.append(child);
+    }
+  }
+
+  return e;
+}
+
+// api sends a request to the gateway, and shows the error if it fails
+async function api(method, path, body) {
+  const resp = await fetch(path, {
+    method,
+    headers: { Authorization: "Bearer " + token, "Content-Type": "application/json" },
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+
+  const data = await resp.json();
+
+  if (resp.status === 401) {
+    showLogin();
+  }
+
+  if (!resp.ok) {
+    status.textContent = data.error ? data.error.message : resp.statusText;
+    throw new Error(status.textContent);
+  }
+
+  return data;
+}
+
+function showLogin() {
+  login.hidden = false;
+  status.textContent = "A token is required";
+
+  if (socket) {
+    socket.onclose = null;
+    socket.close();
+    socket = null;
+  }
+}
+
+login.addEventListener("submit", (event) => {
+  event.preventDefault();
+
+  token = document.getElementById("token").value;
+  localStorage.setItem(tokenKey, token);
+  login.hidden = true;
+
+  connect();
+});
+
+// connect loads the scenes and subscribes to changes, reconnecting if the
+// connection is lost
+async function connect() {
+  if (!token) {
+    showLogin();
+    return;
+  }
+
+  try {
+    scenes = await api("GET", "scenes");
+  } catch (e) {
+    setTimeout(connect, 5000);
+    return;
+  }
+
+  const url = new URL("events", location.href);
+  url.protocol = location.protocol === "https:" ? "wss:" : "ws:";
+  url.searchParams.set("access_token", token);
+
+  socket = new WebSocket(url);
+
+  socket.onopen = () => {
+    status.textContent = "Live";
+  };
+
+  socket.onmessage = (event) => {
+    latest = JSON.parse(event.data);
+    if (!interacting) {
+      render();
+    }
+  };
+
+  socket.onclose = () => {
+    status.textContent = "Disconnected, reconnecting…";
+    setTimeout(connect, 2000);
+  };
+}
+
+// Snapshots aren't rendered while a slider is being dragged, so it doesn't
+// jump back
+document.addEventListener("pointerdown", (event) => {
+  interacting = event.target.matches("input");
+});
+
+document.addEventListener("pointerup", () => {
+  if (interacting) {
+    interacting = false;
+    render();
+  }
+});
+
+// xyToHex converts CIE xy coordinates to a hex color at full brightness
+function xyToHex(xy) {
+  if (!xy || xy.length !== 2 || xy[1] === 0) {
+    return "#ffffff";
+  }
+
+  const [x, y] = xy;
+  const X = x / y;
+  const Z = (1 - x - y) / y;
+
+  let rgb = [
+    X * 1.656492 - 0.354851 - Z * 0.255038,
+    -X * 0.707196 + 1.655397 + Z * 0.036152,
+    X * 0.051713 - 0.121364 + Z * 1.01153,
+  ];
+
+  const max = Math.max(...rgb);
+  if (max > 0) {
+    rgb = rgb.map((c) => c / max);
+  }
+
+  return "#" + rgb.map((c) => {
+    if (c <= 0) {
+      return "00";
+    }
+
+    c = c <= 0.0031308 ? 12.92 * c : 1.055 * Math.pow(c, 1 / 2.4) - 0.055;
+    return Math.round(Math.min(1, c) * 255).toString(16).padStart(2, "0");
+  }).join("");
+}
+
+function setState(resource, id, state) {
+  api("PUT", `${resource}/${id}/state`, state).catch(() => {});
+}
+
+// controls returns the toggle, brightness slider, and color picker for the
+// state of a light or group
+function controls(resource, id, state, on) {
+  const toggle = el("input", {
+    type: "checkbox",
+    checked: on,
+    title: "On",
+    onchange: (event) => setState(resource, id, { on: event.target.checked }),
+  });
+
+  const hasColor = state.xy && state.xy.length === 2 && state.colormode !== undefined && state.colormode !== "";
+
+  const color = hasColor ? el("input", {
+    type: "color",
+    value: xyToHex(state.xy),
+    title: "Color",
+    onchange: (event) => setState(resource, id, { rgb: event.target.value }),
+  }) : null;
+
+  const slider = state.bri ? el("input", {
+    type: "range",
+    min: 1,
+    max: 254,
+    value: state.bri,
+    title: "Brightness",
+    onchange: (event) => setState(resource, id, { bri: Number(event.target.value) }),
+  }) : null;
+
+  return { toggle, color, slider };
+}
+
+function roomCard(group, lights) {
+  const { toggle, color, slider } = controls("groups", group.id, group.action, group.state.any_on);
+
+  const card = el("article", { class: "card" },
+    el("div", { class: "row" }, el("h2", { class: "name" }, group.name), color, toggle),
+    slider,
+  );
+
+  for (const id of group.lights) {
+    const light = lights.get(Number(id));
+    if (!light) {
+      continue;
+    }
+
+    const c = controls("lights", light.id, light.state, light.state.on);
+    const reachable = light.state.reachable;
+
+    card.append(el("div", { class: "light" + (light.state.on ? " on" : "") },
+      el("div", { class: "row" },
+        el("span", { class: "name" + (reachable ? "" : " unreachable") }, light.name + (reachable ? "" : " (unreachable)")),
+        c.color,
+        c.toggle,
+      ),
+      c.slider,
+    ));
+  }
+
+  const groupScenes = scenes.filter((s) => s.group === String(group.id));
+  if (groupScenes.length > 0) {
+    card.append(el("div", { class: "scenes" }, ...groupScenes.map((s) => el("button", {
+      type: "button",
+      onclick: () => api("POST", `groups/${group.id}/scenes/${encodeURIComponent(s.id)}`).catch(() => {}),
+    }, s.name))));
+  }
+
+  return card;
+}
+
+// sensorReading returns the value reported by a sensor, based on its type
+function sensorReading(sensor) {
+  const state = sensor.state;
+
+  switch (sensor.type.replace(/^(ZLL|CLIP)/, "")) {
+    case "Temperature":
+      return (state.temperature / 100).toFixed(1) + " °C";
+    case "Presence":
+      return state.presence ? "Motion" : "No motion";
+    case "LightLevel":
+      return Math.round(Math.pow(10, ((state.lightlevel || 1) - 1) / 10000)) + " lux";
+    case "Humidity":
+      return (state.humidity / 100).toFixed(1) + " %";
+    case "Switch":
+      return "Button " + state.buttonevent;
+    case "Daylight":
+      return state.daylight ? "Daylight" : "Dark";
+  }
+
+  return "";
+}
+
+function render() {
+  if (!latest) {
+    return;
+  }
+
+  const lights = new Map(latest.lights.map((l) => [l.id, l]));
+
+  rooms.replaceChildren(...latest.groups
+    .filter((g) => g.type === "Room" || g.type === "Zone")
+    .map((g) => roomCard(g, lights)));
+
+  sensors.replaceChildren(...latest.sensors.map((s) => el("tr", {},
+    el("td", {}, s.name),
+    el("td", {}, sensorReading(s)),
+    el("td", { class: "muted" }, s.config.battery ? `Battery ${s.config.battery}%` : ""),
+  )));
+}
+
+connect();
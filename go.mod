@@ -1 +1,3 @@
 module github.com/mattvella07/hue
+
+go 1.22
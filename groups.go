@@ -1,14 +1,112 @@
 package hue
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// GroupType is the type of a Phillips Hue group
+type GroupType string
+
+// Group types supported by the Phillips Hue API. Luminaire and LightSource
+// groups are created by the bridge for lights with several light sources, and
+// can't be created through the API.
+const (
+	GroupTypeLightGroup    GroupType = "LightGroup"
+	GroupTypeRoom          GroupType = "Room"
+	GroupTypeZone          GroupType = "Zone"
+	GroupTypeEntertainment GroupType = "Entertainment"
+	GroupTypeLuminaire     GroupType = "Luminaire"
+	GroupTypeLightSource   GroupType = "LightSource"
+)
+
+// RoomClass is the class of a Room, Zone, or Entertainment group, which sets
+// the icon shown in apps
+type RoomClass string
+
+// Classes supported by the Phillips Hue API for Room, Zone, and Entertainment
+// groups
+const (
+	ClassLivingRoom  RoomClass = "Living room"
+	ClassKitchen     RoomClass = "Kitchen"
+	ClassDining      RoomClass = "Dining"
+	ClassBedroom     RoomClass = "Bedroom"
+	ClassKidsBedroom RoomClass = "Kids bedroom"
+	ClassBathroom    RoomClass = "Bathroom"
+	ClassNursery     RoomClass = "Nursery"
+	ClassRecreation  RoomClass = "Recreation"
+	ClassOffice      RoomClass = "Office"
+	ClassGym         RoomClass = "Gym"
+	ClassHallway     RoomClass = "Hallway"
+	ClassToilet      RoomClass = "Toilet"
+	ClassFrontDoor   RoomClass = "Front door"
+	ClassGarage      RoomClass = "Garage"
+	ClassTerrace     RoomClass = "Terrace"
+	ClassGarden      RoomClass = "Garden"
+	ClassDriveway    RoomClass = "Driveway"
+	ClassCarport     RoomClass = "Carport"
+	ClassOther       RoomClass = "Other"
+	ClassHome        RoomClass = "Home"
+	ClassDownstairs  RoomClass = "Downstairs"
+	ClassUpstairs    RoomClass = "Upstairs"
+	ClassTopFloor    RoomClass = "Top floor"
+	ClassAttic       RoomClass = "Attic"
+	ClassGuestRoom   RoomClass = "Guest room"
+	ClassStaircase   RoomClass = "Staircase"
+	ClassLounge      RoomClass = "Lounge"
+	ClassManCave     RoomClass = "Man cave"
+	ClassComputer    RoomClass = "Computer"
+	ClassStudio      RoomClass = "Studio"
+	ClassMusic       RoomClass = "Music"
+	ClassTV          RoomClass = "TV"
+	ClassReading     RoomClass = "Reading"
+	ClassCloset      RoomClass = "Closet"
+	ClassStorage     RoomClass = "Storage"
+	ClassLaundryRoom RoomClass = "Laundry room"
+	ClassBalcony     RoomClass = "Balcony"
+	ClassPorch       RoomClass = "Porch"
+	ClassBarbecue    RoomClass = "Barbecue"
+	ClassPool        RoomClass = "Pool"
+	ClassFree        RoomClass = "Free"
+)
+
+// GroupTypes contains every group type that can be created through the API
+var GroupTypes = []GroupType{GroupTypeLightGroup, GroupTypeRoom, GroupTypeZone, GroupTypeEntertainment}
+
+// RoomClasses contains every class supported for Room and Zone groups
+var RoomClasses = []RoomClass{
+	ClassLivingRoom, ClassKitchen, ClassDining, ClassBedroom, ClassKidsBedroom,
+	ClassBathroom, ClassNursery, ClassRecreation, ClassOffice, ClassGym,
+	ClassHallway, ClassToilet, ClassFrontDoor, ClassGarage, ClassTerrace,
+	ClassGarden, ClassDriveway, ClassCarport, ClassOther, ClassHome,
+	ClassDownstairs, ClassUpstairs, ClassTopFloor, ClassAttic, ClassGuestRoom,
+	ClassStaircase, ClassLounge, ClassManCave, ClassComputer, ClassStudio,
+	ClassMusic, ClassTV, ClassReading, ClassCloset, ClassStorage,
+	ClassLaundryRoom, ClassBalcony, ClassPorch, ClassBarbecue, ClassPool,
+}
+
+// EntertainmentClasses contains every class supported for Entertainment groups
+var EntertainmentClasses = []RoomClass{ClassTV, ClassFree}
+
+// RoomConflictError is returned when a light can't be added to a room because
+// it's already in another one. A light can only be in one room.
+type RoomConflictError struct {
+	Light    int
+	Room     int
+	RoomName string
+}
+
+func (e *RoomConflictError) Error() string {
+	return fmt.Sprintf("Light %d is already in room %s (%d)", e.Light, e.RoomName, e.Room)
+}
+
 type groupState struct {
 	AllOn bool `json:"all_on"`
 	AnyOn bool `json:"any_on"`
@@ -32,13 +130,62 @@ type Group struct {
 	Name    string      `json:"name"`
 	Lights  []string    `json:"lights"`
 	Sensors []string    `json:"sensors"`
-	Type    string      `json:"type"`
+	Type    GroupType   `json:"type"`
+	Class   RoomClass   `json:"class"`
 	State   groupState  `json:"state"`
 	Recycle bool        `json:"recycle"`
 	Action  groupAction `json:"action"`
 	ID      int         `json:"id"`
 }
 
+type groupCreateRequest struct {
+	Name   string    `json:"name"`
+	Type   GroupType `json:"type"`
+	Class  RoomClass `json:"class,omitempty"`
+	Lights []string  `json:"lights"`
+}
+
+type classRequest struct {
+	Class RoomClass `json:"class"`
+}
+
+// AllLightsGroup is the ID of the special group that contains every light
+// connected to the bridge. It isn't returned by GetGroups, and can't be
+// renamed, changed, or deleted.
+const AllLightsGroup = 0
+
+// Effect values supported by the Phillips Hue API
+const (
+	EffectNone      = "none"
+	EffectColorLoop = "colorloop"
+)
+
+// SceneTransition recalls a scene using the transitions stored in it for each
+// light
+const SceneTransition time.Duration = -1
+
+// GroupActionUpdate contains the changes to make to all lights in a group.
+// Fields left nil or empty aren't changed. The _inc fields change a value
+// relative to each light's current value. Scene recalls a scene, and can be
+// combined with TransitionTime.
+type GroupActionUpdate struct {
+	On             *bool     `json:"on,omitempty"`
+	Bri            *int      `json:"bri,omitempty"`
+	Hue            *int      `json:"hue,omitempty"`
+	Sat            *int      `json:"sat,omitempty"`
+	XY             []float32 `json:"xy,omitempty"`
+	CT             *int      `json:"ct,omitempty"`
+	Effect         string    `json:"effect,omitempty"`
+	Alert          string    `json:"alert,omitempty"`
+	TransitionTime *int      `json:"transitiontime,omitempty"`
+	BriInc         *int      `json:"bri_inc,omitempty"`
+	SatInc         *int      `json:"sat_inc,omitempty"`
+	HueInc         *int      `json:"hue_inc,omitempty"`
+	CTInc          *int      `json:"ct_inc,omitempty"`
+	XYInc          []float32 `json:"xy_inc,omitempty"`
+	Scene          string    `json:"scene,omitempty"`
+}
+
 // GetGroups gets all Phillips Hue light groups connected to current bridge
 func (h *Connection) GetGroups() ([]Group, error) {
 	data, err := h.get("groups")
@@ -50,31 +197,17 @@ func (h *Connection) GetGroups() ([]Group, error) {
 		return []Group{}, nil
 	}
 
-	// Create map to store JSON response
-	fullResponse := make(map[string]interface{})
+	// Decode directly into a map of IDs to groups
+	fullResponse := make(map[string]Group)
 
 	err = json.Unmarshal(data, &fullResponse)
 	if err != nil {
 		return []Group{}, err
 	}
 
-	allGroups := []Group{}
-
-	// Loop through all keys in the map and Unmarshal into
-	// Group type
-	for key, val := range fullResponse {
-		group := Group{}
-
-		g, err := json.Marshal(val)
-		if err != nil {
-			return []Group{}, err
-		}
-
-		err = json.Unmarshal(g, &group)
-		if err != nil {
-			return []Group{}, err
-		}
+	allGroups := make([]Group, 0, len(fullResponse))
 
+	for key, group := range fullResponse {
 		id, err := strconv.Atoi(key)
 		if err != nil {
 			return []Group{}, err
@@ -85,12 +218,22 @@ func (h *Connection) GetGroups() ([]Group, error) {
 		allGroups = append(allGroups, group)
 	}
 
+	// Sort by ID so the order is the same for every call
+	sort.Slice(allGroups, func(i, j int) bool {
+		return allGroups[i].ID < allGroups[j].ID
+	})
+
 	return allGroups, nil
 }
 
 // CreateGroup creates a new group with the specified name consisting of the specified
-// lights. The group is added to the bridge using the next available ID.
-func (h *Connection) CreateGroup(name, groupType, class string, lights []int) error {
+// lights. The group is added to the bridge using the next available ID. The type
+// is one of GroupTypes, and defaults to LightGroup. Room and Zone groups have a
+// class from RoomClasses, which defaults to Other, and Entertainment groups have
+// a class from EntertainmentClasses, which defaults to TV. A light can only
+// be in one Room, so a *RoomConflictError is returned if one of the lights is in
+// another room.
+func (h *Connection) CreateGroup(name string, groupType GroupType, class RoomClass, lights []int) error {
 	// Error checking
 	name = strings.Trim(name, " ")
 	if name == "" {
@@ -98,33 +241,48 @@ func (h *Connection) CreateGroup(name, groupType, class string, lights []int) er
 	}
 
 	// LightGroup is the default group
-	groupType = strings.Trim(groupType, " ")
+	groupType = GroupType(strings.Trim(string(groupType), " "))
 	if groupType == "" {
-		groupType = "LightGroup"
+		groupType = GroupTypeLightGroup
+	}
+
+	if groupType == GroupTypeLuminaire || groupType == GroupTypeLightSource {
+		return fmt.Errorf("Groups with a type of %s are created by the bridge and can't be created", groupType)
 	}
 
-	// LightGroup, Room, Luminaire, and LightSource are valid groups
-	if groupType != "LightGroup" && groupType != "Room" && groupType != "Luminaire" && groupType != "LightSource" {
-		return errors.New("Group Type must be one of the following: LightGroup, Room, Luminaire, LightSource")
+	if !isGroupTypeOneOf(groupType, GroupTypes) {
+		return fmt.Errorf("Group Type must be one of the following: %s", joinGroupTypes(GroupTypes))
 	}
 
-	// Other is the default class
-	class = strings.Trim(class, " ")
-	if class == "" {
-		class = "Other"
+	class = RoomClass(strings.Trim(string(class), " "))
+	if hasClass(groupType) {
+		if class == "" {
+			class = defaultClass(groupType)
+		}
+
+		err := validateClass(groupType, class)
+		if err != nil {
+			return err
+		}
+	} else if class != "" {
+		return fmt.Errorf("Class can't be set for groups with a type of %s", groupType)
 	}
 
-	if !h.allLightsValid(lights) {
-		return errors.New("One of the lights is invalid")
+	if groupType == GroupTypeLightGroup && len(lights) == 0 {
+		return errors.New("Lights must not be empty")
 	}
 
-	reqBody := strings.NewReader(fmt.Sprintf("{\"name\": \"%s\", \"type\": \"%s\", \"class\": \"%s\", \"lights\": %s}", name, groupType, class, h.formatSlice(lights)))
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/groups", h.baseURL), reqBody)
+	err := h.validateGroupLights(0, groupType, lights)
 	if err != nil {
 		return err
 	}
 
-	err = h.execute(req)
+	err = h.execute("POST", "groups", groupCreateRequest{
+		Name:   name,
+		Type:   groupType,
+		Class:  class,
+		Lights: formatIDs(lights),
+	})
 	if err != nil {
 		return err
 	}
@@ -157,6 +315,10 @@ func (h *Connection) GetGroup(group int) (Group, error) {
 // RenameGroup renames the specified Phillips Hue group
 func (h *Connection) RenameGroup(group int, name string) error {
 	// Error checking
+	if group == AllLightsGroup {
+		return allLightsGroupError("renamed")
+	}
+
 	if !h.doesGroupExist(group) {
 		return fmt.Errorf("Group %d not found", group)
 	}
@@ -165,9 +327,7 @@ func (h *Connection) RenameGroup(group int, name string) error {
 		return errors.New("Name must not be empty")
 	}
 
-	attributes := fmt.Sprintf("{ \"name\": \"%s\" }", name)
-
-	err := h.updateGroup(group, "attributes", attributes)
+	err := h.updateGroup(group, "attributes", nameRequest{Name: name})
 	if err != nil {
 		return err
 	}
@@ -175,20 +335,34 @@ func (h *Connection) RenameGroup(group int, name string) error {
 	return nil
 }
 
-// SetLightsInGroup sets the lights that are in the specified Phillips Hue group
+// SetLightsInGroup sets the lights that are in the specified Phillips Hue group.
+// A light can only be in one Room, so a *RoomConflictError is returned if the
+// group is a room and one of the lights is in another room.
 func (h *Connection) SetLightsInGroup(group int, lights []int) error {
 	// Error checking
-	if !h.doesGroupExist(group) {
+	if group == AllLightsGroup {
+		return allLightsGroupError("changed")
+	}
+
+	currentGroup, err := h.GetGroup(group)
+	if err != nil {
 		return fmt.Errorf("Group %d not found", group)
 	}
 
-	if !h.allLightsValid(lights) {
-		return errors.New("One of the lights is invalid")
+	if currentGroup.Type == GroupTypeLuminaire || currentGroup.Type == GroupTypeLightSource {
+		return fmt.Errorf("Unable to change lights in group %d: Can't change lights in group with a type of %s", group, currentGroup.Type)
 	}
 
-	attributes := fmt.Sprintf("{ \"lights\": %s }", h.formatSlice(lights))
+	if currentGroup.Type == GroupTypeLightGroup && len(lights) == 0 {
+		return errors.New("Lights must not be empty")
+	}
 
-	err := h.updateGroup(group, "attributes", attributes)
+	err = h.validateGroupLights(group, currentGroup.Type, lights)
+	if err != nil {
+		return err
+	}
+
+	err = h.updateGroup(group, "attributes", lightsRequest{Lights: formatIDs(lights)})
 	if err != nil {
 		return err
 	}
@@ -196,20 +370,34 @@ func (h *Connection) SetLightsInGroup(group int, lights []int) error {
 	return nil
 }
 
-// SetGroupClass sets the class for the specified Phillips Hue group
-func (h *Connection) SetGroupClass(group int, class string) error {
+// SetGroupClass sets the class for the specified Phillips Hue group, which must
+// be a Room, Zone, or Entertainment group. The class is one of RoomClasses, or
+// EntertainmentClasses for an Entertainment group.
+func (h *Connection) SetGroupClass(group int, class RoomClass) error {
 	// Error checking
-	if !h.doesGroupExist(group) {
+	if group == AllLightsGroup {
+		return allLightsGroupError("changed")
+	}
+
+	currentGroup, err := h.GetGroup(group)
+	if err != nil {
 		return fmt.Errorf("Group %d not found", group)
 	}
 
-	if strings.Trim(class, " ") == "" {
+	if strings.Trim(string(class), " ") == "" {
 		return errors.New("Class must not be empty")
 	}
 
-	attributes := fmt.Sprintf("{ \"class\": \"%s\" }", class)
+	if !hasClass(currentGroup.Type) {
+		return fmt.Errorf("Class can't be set for groups with a type of %s", currentGroup.Type)
+	}
 
-	err := h.updateGroup(group, "attributes", attributes)
+	err = validateClass(currentGroup.Type, class)
+	if err != nil {
+		return err
+	}
+
+	err = h.updateGroup(group, "attributes", classRequest{Class: class})
 	if err != nil {
 		return err
 	}
@@ -225,9 +413,7 @@ func (h *Connection) TurnOnGroup(group int) error {
 		return fmt.Errorf("Group %d not found", group)
 	}
 
-	state := "{ \"on\": true }"
-
-	err := h.updateGroup(group, "state", state)
+	err := h.updateGroup(group, "state", onRequest{On: true})
 	if err != nil {
 		return err
 	}
@@ -249,7 +435,13 @@ func (h *Connection) TurnOnGroupWithColor(group int, x, y float32, bri, hue, sat
 		return err
 	}
 
-	state := fmt.Sprintf("{\"on\": true, \"xy\": [%f, %f], \"bri\": %d, \"hue\": %d, \"sat\": %d}", x, y, bri, hue, sat)
+	state := colorStateRequest{
+		On:  true,
+		XY:  []float32{x, y},
+		Bri: bri,
+		Hue: hue,
+		Sat: sat,
+	}
 
 	err = h.updateGroup(group, "state", state)
 	if err != nil {
@@ -259,6 +451,59 @@ func (h *Connection) TurnOnGroupWithColor(group int, x, y float32, bri, hue, sat
 	return nil
 }
 
+// SetGroupAction changes the state of all lights in the specified Phillips Hue
+// group. Only the fields set in the update are changed.
+func (h *Connection) SetGroupAction(group int, update GroupActionUpdate) error {
+	// Error checking
+	err := validateGroupAction(update)
+	if err != nil {
+		return err
+	}
+
+	if !h.doesGroupExist(group) {
+		return fmt.Errorf("Group %d not found", group)
+	}
+
+	err = h.updateGroup(group, "state", update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RecallScene recalls the specified scene on the lights in the specified Phillips
+// Hue group, taking the specified transition to reach it. Use SceneTransition,
+// or any negative transition, for the transitions stored in the scene. The
+// transition is rounded to 100ms.
+func (h *Connection) RecallScene(group int, scene string, transition time.Duration) error {
+	// Error checking
+	if transition > maxTransitionTime {
+		return fmt.Errorf("Transition must be at most %s", maxTransitionTime)
+	}
+
+	if !h.doesGroupExist(group) {
+		return fmt.Errorf("Group %d not found", group)
+	}
+
+	if _, err := h.GetScene(scene); err != nil {
+		return fmt.Errorf("Scene %s not found", scene)
+	}
+
+	update := GroupActionUpdate{Scene: scene}
+	if transition >= 0 {
+		transitionTime := int(transition.Round(100*time.Millisecond) / (100 * time.Millisecond))
+		update.TransitionTime = &transitionTime
+	}
+
+	err := h.updateGroup(group, "state", update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // TurnOffGroup turns off all lights in the specified Phillips Hue group
 func (h *Connection) TurnOffGroup(group int) error {
 	// Error checking
@@ -266,9 +511,29 @@ func (h *Connection) TurnOffGroup(group int) error {
 		return fmt.Errorf("Group %d not found", group)
 	}
 
-	state := "{ \"on\": false }"
+	err := h.updateGroup(group, "state", onRequest{On: false})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IdentifyGroup sends an alert to all lights in the specified Phillips Hue group
+// so they can be identified. Use AlertSelect for a single breathe cycle,
+// AlertLSelect to breathe for 15 seconds, or AlertNone to cancel an alert.
+func (h *Connection) IdentifyGroup(group int, alert string) error {
+	// Error checking
+	if !h.doesGroupExist(group) {
+		return fmt.Errorf("Group %d not found", group)
+	}
+
+	err := validateAlert(alert)
+	if err != nil {
+		return err
+	}
 
-	err := h.updateGroup(group, "state", state)
+	err = h.updateGroup(group, "state", alertRequest{Alert: alert})
 	if err != nil {
 		return err
 	}
@@ -279,30 +544,272 @@ func (h *Connection) TurnOffGroup(group int) error {
 // DeleteGroup deletes the specified Phillips Hue light group
 func (h *Connection) DeleteGroup(group int) error {
 	// Error checking
+	if group == AllLightsGroup {
+		return allLightsGroupError("deleted")
+	}
+
 	currentGroup, err := h.GetGroup(group)
 	if err != nil {
 		return fmt.Errorf("Group %d not found", group)
 	}
 
 	// Groups with type LightSource or Luminaire can't be deleted
-	if currentGroup.Type == "LightSource" || currentGroup.Type == "Luminaire" {
+	if currentGroup.Type == GroupTypeLightSource || currentGroup.Type == GroupTypeLuminaire {
 		return fmt.Errorf("Unable to delete group %d: Can't delete group with a type of LightSource or Luminaire", group)
 	}
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/groups/%d", h.baseURL, group), nil)
+	err = h.execute("DELETE", fmt.Sprintf("groups/%d", group), nil)
 	if err != nil {
 		return err
 	}
 
-	err = h.execute(req)
+	return nil
+}
+
+// GetAllLightsGroup gets the special group that contains every light
+func (h *Connection) GetAllLightsGroup() (Group, error) {
+	group, err := h.GetGroup(AllLightsGroup)
+	if err != nil {
+		return Group{}, err
+	}
+	group.ID = AllLightsGroup
+
+	return group, nil
+}
+
+// AllLightsOn turns on every light connected to the bridge, also making the
+// changes in state. On is always set to true.
+func (h *Connection) AllLightsOn(state GroupActionUpdate) error {
+	on := true
+	state.On = &on
+
+	return h.SetGroupAction(AllLightsGroup, state)
+}
+
+// AllLightsOff turns off every light connected to the bridge
+func (h *Connection) AllLightsOff() error {
+	return h.TurnOffGroup(AllLightsGroup)
+}
+
+// allLightsGroupError is returned when trying to modify the group that
+// contains every light
+func allLightsGroupError(action string) error {
+	return fmt.Errorf("Group %d contains every light and can't be %s", AllLightsGroup, action)
+}
+
+// hasClass returns true if groups of the specified type have a class
+func hasClass(groupType GroupType) bool {
+	return groupType == GroupTypeRoom || groupType == GroupTypeZone || groupType == GroupTypeEntertainment
+}
+
+// classes returns the classes supported for a group type
+func classes(groupType GroupType) []RoomClass {
+	if groupType == GroupTypeEntertainment {
+		return EntertainmentClasses
+	}
+
+	return RoomClasses
+}
+
+// defaultClass returns the class used when a group is created without one
+func defaultClass(groupType GroupType) RoomClass {
+	if groupType == GroupTypeEntertainment {
+		return ClassTV
+	}
+
+	return ClassOther
+}
+
+func validateClass(groupType GroupType, class RoomClass) error {
+	if !isClassOneOf(class, classes(groupType)) {
+		return fmt.Errorf("Class %s is invalid: must be one of the following: %s", class, joinClasses(classes(groupType)))
+	}
+
+	return nil
+}
+
+// validateGroupLights checks that the lights exist and can be in a group of the
+// specified type. group is the ID of the group being changed, or 0 for a new
+// group.
+func (h *Connection) validateGroupLights(group int, groupType GroupType, lights []int) error {
+	for _, light := range lights {
+		l, err := h.GetLight(light)
+		if err != nil {
+			return errors.New("One of the lights is invalid")
+		}
+
+		if groupType == GroupTypeEntertainment && !l.Capabilities.Streaming.Renderer {
+			return fmt.Errorf("Light %d doesn't support entertainment streaming", light)
+		}
+	}
+
+	if groupType != GroupTypeRoom || len(lights) == 0 {
+		return nil
+	}
+
+	// A light can only be in one room
+	groups, err := h.GetGroups()
 	if err != nil {
 		return err
 	}
 
+	for _, light := range lights {
+		id := strconv.Itoa(light)
+
+		for _, g := range groups {
+			if g.Type != GroupTypeRoom || g.ID == group {
+				continue
+			}
+
+			for _, l := range g.Lights {
+				if l == id {
+					return &RoomConflictError{Light: light, Room: g.ID, RoomName: g.Name}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func isGroupTypeOneOf(value GroupType, options []GroupType) bool {
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isClassOneOf(value RoomClass, options []RoomClass) bool {
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+func joinGroupTypes(groupTypes []GroupType) string {
+	names := make([]string, len(groupTypes))
+	for i, t := range groupTypes {
+		names[i] = string(t)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func joinClasses(classes []RoomClass) string {
+	names := make([]string, len(classes))
+	for i, c := range classes {
+		names[i] = string(c)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func validateGroupAction(update GroupActionUpdate) error {
+	if reflect.DeepEqual(update, GroupActionUpdate{}) {
+		return errors.New("Update must change at least one attribute")
+	}
+
+	if update.Bri != nil && (*update.Bri < 1 || *update.Bri > 254) {
+		return errors.New("Invalid brightness value: bri must be between 1 and 254")
+	}
+
+	if update.Hue != nil && (*update.Hue < 0 || *update.Hue > 65535) {
+		return errors.New("Invalid hue value: hue must be between 0 and 65,535")
+	}
+
+	if update.Sat != nil && (*update.Sat < 0 || *update.Sat > 254) {
+		return errors.New("Invalid saturation value: sat must be between 0 and 254")
+	}
+
+	if update.XY != nil {
+		if len(update.XY) != 2 {
+			return errors.New("Invalid color value: xy must contain exactly 2 values")
+		}
+
+		if update.XY[0] < 0 || update.XY[0] > 1 || update.XY[1] < 0 || update.XY[1] > 1 {
+			return errors.New("Invalid color value: x and y must be between 0 and 1")
+		}
+	}
+
+	if update.CT != nil && (*update.CT < 153 || *update.CT > 500) {
+		return errors.New("Invalid color temperature value: ct must be between 153 and 500")
+	}
+
+	if update.Effect != "" && update.Effect != EffectNone && update.Effect != EffectColorLoop {
+		return fmt.Errorf("Effect must be one of the following: %s, %s", EffectNone, EffectColorLoop)
+	}
+
+	if update.Alert != "" {
+		err := validateAlert(update.Alert)
+		if err != nil {
+			return err
+		}
+	}
+
+	if update.TransitionTime != nil && (*update.TransitionTime < 0 || *update.TransitionTime > 65535) {
+		return errors.New("Invalid transition time: transitiontime must be between 0 and 65,535")
+	}
+
+	if update.BriInc != nil && (*update.BriInc < -254 || *update.BriInc > 254) {
+		return errors.New("Invalid brightness increment: bri_inc must be between -254 and 254")
+	}
+
+	if update.SatInc != nil && (*update.SatInc < -254 || *update.SatInc > 254) {
+		return errors.New("Invalid saturation increment: sat_inc must be between -254 and 254")
+	}
+
+	if update.HueInc != nil && (*update.HueInc < -65534 || *update.HueInc > 65534) {
+		return errors.New("Invalid hue increment: hue_inc must be between -65,534 and 65,534")
+	}
+
+	if update.CTInc != nil && (*update.CTInc < -65534 || *update.CTInc > 65534) {
+		return errors.New("Invalid color temperature increment: ct_inc must be between -65,534 and 65,534")
+	}
+
+	if update.XYInc != nil {
+		if len(update.XYInc) != 2 {
+			return errors.New("Invalid color increment: xy_inc must contain exactly 2 values")
+		}
+
+		if update.XYInc[0] < -0.5 || update.XYInc[0] > 0.5 || update.XYInc[1] < -0.5 || update.XYInc[1] > 0.5 {
+			return errors.New("Invalid color increment: xy_inc values must be between -0.5 and 0.5")
+		}
+	}
+
+	// A value and an increment of the same attribute can't both be set
+	increments := []struct {
+		name, inc string
+		value     bool
+		increment bool
+	}{
+		{"bri", "bri_inc", update.Bri != nil, update.BriInc != nil},
+		{"sat", "sat_inc", update.Sat != nil, update.SatInc != nil},
+		{"hue", "hue_inc", update.Hue != nil, update.HueInc != nil},
+		{"ct", "ct_inc", update.CT != nil, update.CTInc != nil},
+		{"xy", "xy_inc", update.XY != nil, update.XYInc != nil},
+	}
+
+	for _, i := range increments {
+		if i.value && i.increment {
+			return fmt.Errorf("Only one of %s and %s can be set", i.name, i.inc)
+		}
+	}
+
 	return nil
 }
 
 func (h *Connection) doesGroupExist(group int) bool {
+	// The group that contains every light always exists
+	if group == AllLightsGroup {
+		return true
+	}
+
 	// If GetGroup returns an error, then the group doesn't exist
 	_, err := h.GetGroup(group)
 	if err != nil {
@@ -312,24 +819,27 @@ func (h *Connection) doesGroupExist(group int) bool {
 	return true
 }
 
-func (h *Connection) updateGroup(group int, toUpdate, value string) error {
-	url := ""
+func (h *Connection) updateGroup(group int, toUpdate string, value interface{}) error {
+	return h.updateGroupContext(context.Background(), group, toUpdate, value)
+}
+
+func (h *Connection) updateGroupContext(ctx context.Context, group int, toUpdate string, value interface{}) error {
+	path := ""
 	switch toUpdate {
 	case "attributes":
-		url = fmt.Sprintf("%s/groups/%d", h.baseURL, group)
+		path = fmt.Sprintf("groups/%d", group)
 	case "state":
-		url = fmt.Sprintf("%s/groups/%d/action", h.baseURL, group)
+		path = fmt.Sprintf("groups/%d/action", group)
+
+		err := h.throttle(ctx, "groups", groupCommandInterval)
+		if err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("Error while updating group %d", group)
 	}
 
-	reqBody := strings.NewReader(value)
-	req, err := http.NewRequest("PUT", url, reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.executeContext(ctx, "PUT", path, value)
 	if err != nil {
 		return err
 	}
@@ -1,7 +1,9 @@
 package hue
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetGroups(t *testing.T) {
@@ -36,7 +38,7 @@ func TestGetGroups(t *testing.T) {
 		}
 
 		{
-			expected := "LightGroup"
+			expected := GroupTypeLightGroup
 			if groups[0].Type != expected {
 				t.Fatalf("Expected Type to equal %s, got %s", expected, groups[0].Type)
 			}
@@ -86,20 +88,48 @@ func TestCreateGroup(t *testing.T) {
 		}
 	})
 
-	t.Run("Successful group creation - Luminaire", func(t *testing.T) {
-		err := h.CreateGroup("New Group", "Luminaire", "", []int{1})
+	t.Run("Successful group creation - Zone", func(t *testing.T) {
+		err := h.CreateGroup("New Group", GroupTypeZone, ClassHallway, []int{1})
 		if err != nil {
 			t.Fatal(err)
 		}
 	})
 
-	t.Run("Successful group creation - LightSource", func(t *testing.T) {
-		err := h.CreateGroup("New Group", "LightSource", "", []int{1})
+	t.Run("Successful group creation - Entertainment", func(t *testing.T) {
+		err := h.CreateGroup("New Group", GroupTypeEntertainment, ClassTV, []int{1})
 		if err != nil {
 			t.Fatal(err)
 		}
 	})
 
+	t.Run("Luminaire can't be created", func(t *testing.T) {
+		err := h.CreateGroup("New Group", "Luminaire", "", []int{1})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Groups with a type of Luminaire are created by the bridge and can't be created"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("LightSource can't be created", func(t *testing.T) {
+		err := h.CreateGroup("New Group", "LightSource", "", []int{1})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Groups with a type of LightSource are created by the bridge and can't be created"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
 	t.Run("Successful group creation - Empty group name", func(t *testing.T) {
 		err := h.CreateGroup("New Group", "", "", []int{1})
 		if err != nil {
@@ -128,7 +158,92 @@ func TestCreateGroup(t *testing.T) {
 		}
 
 		{
-			expected := "Group Type must be one of the following: LightGroup, Room, Luminaire, LightSource"
+			expected := "Group Type must be one of the following: LightGroup, Room, Zone, Entertainment"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid class", func(t *testing.T) {
+		err := h.CreateGroup("New Group", "Room", "Cellar", []int{1})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		if !strings.HasPrefix(err.Error(), "Class Cellar is invalid: must be one of the following: Living room, Kitchen") {
+			t.Fatalf("Unexpected error message %s", err.Error())
+		}
+	})
+
+	t.Run("Successful group creation - Entertainment with Free class", func(t *testing.T) {
+		err := h.CreateGroup("New Group", GroupTypeEntertainment, ClassFree, []int{1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Successful group creation - Entertainment without class", func(t *testing.T) {
+		err := h.CreateGroup("New Group", GroupTypeEntertainment, "", []int{1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Room class for Entertainment", func(t *testing.T) {
+		err := h.CreateGroup("New Group", GroupTypeEntertainment, ClassKitchen, []int{1})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Class Kitchen is invalid: must be one of the following: TV, Free"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Free class for Room", func(t *testing.T) {
+		err := h.CreateGroup("New Group", GroupTypeRoom, ClassFree, []int{1})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		if !strings.HasPrefix(err.Error(), "Class Free is invalid: must be one of the following: Living room, Kitchen") {
+			t.Fatalf("Unexpected error message %s", err.Error())
+		}
+	})
+
+	t.Run("Free class for Zone", func(t *testing.T) {
+		err := h.CreateGroup("New Group", GroupTypeZone, ClassFree, []int{1})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+
+	t.Run("Class for LightGroup", func(t *testing.T) {
+		err := h.CreateGroup("New Group", "LightGroup", "Kitchen", []int{1})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Class can't be set for groups with a type of LightGroup"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Empty LightGroup", func(t *testing.T) {
+		err := h.CreateGroup("New Group", "LightGroup", "", []int{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Lights must not be empty"
 			if err.Error() != expected {
 				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
 			}
@@ -168,7 +283,7 @@ func TestGetGroup(t *testing.T) {
 		}
 
 		{
-			expected := "LightGroup"
+			expected := GroupTypeLightGroup
 			if group.Type != expected {
 				t.Fatalf("Expected Type to equal %s, got %s", expected, group.Type)
 			}
@@ -278,12 +393,40 @@ func TestSetGroupClass(t *testing.T) {
 	defer server.Close()
 
 	t.Run("Successful", func(t *testing.T) {
-		err := h.SetGroupClass(1, "Other")
+		err := h.SetGroupClass(4, "Other")
 		if err != nil {
 			t.Fatal(err)
 		}
 	})
 
+	t.Run("LightGroup", func(t *testing.T) {
+		err := h.SetGroupClass(1, "Other")
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Class can't be set for groups with a type of LightGroup"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid class", func(t *testing.T) {
+		err := h.SetGroupClass(4, "Cellar")
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+
+	t.Run("Entertainment class for Room", func(t *testing.T) {
+		err := h.SetGroupClass(4, ClassFree)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+
 	t.Run("Group doesn't exist", func(t *testing.T) {
 		err := h.SetGroupClass(3, "Other")
 		if err == nil {
@@ -486,3 +629,298 @@ func TestTurnOffGroup(t *testing.T) {
 		}
 	})
 }
+
+func TestIdentifyGroup(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Group exists", func(t *testing.T) {
+		err := h.IdentifyGroup(1, AlertLSelect)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Cancel alert", func(t *testing.T) {
+		err := h.IdentifyGroup(1, AlertNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Group doesn't exist", func(t *testing.T) {
+		err := h.IdentifyGroup(3, AlertSelect)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Group 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid alert", func(t *testing.T) {
+		err := h.IdentifyGroup(1, "blink")
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Alert must be one of the following: none, select, lselect"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+}
+
+func TestSetGroupAction(t *testing.T) {
+	h, server, lastBody := createRecordingConnection()
+	defer server.Close()
+
+	on := true
+	bri := 200
+	ct := 300
+	transition := 10
+	briInc := -50
+
+	t.Run("Successful", func(t *testing.T) {
+		err := h.SetGroupAction(1, GroupActionUpdate{On: &on, CT: &ct, Effect: EffectNone, TransitionTime: &transition})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := `{"on":true,"ct":300,"effect":"none","transitiontime":10}`
+			if string(lastBody()) != expected {
+				t.Fatalf("Expected body to equal %s, got %s", expected, lastBody())
+			}
+		}
+	})
+
+	t.Run("Increment", func(t *testing.T) {
+		err := h.SetGroupAction(1, GroupActionUpdate{BriInc: &briInc, XYInc: []float32{0.1, -0.1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := `{"bri_inc":-50,"xy_inc":[0.1,-0.1]}`
+			if string(lastBody()) != expected {
+				t.Fatalf("Expected body to equal %s, got %s", expected, lastBody())
+			}
+		}
+	})
+
+	t.Run("Group doesn't exist", func(t *testing.T) {
+		err := h.SetGroupAction(3, GroupActionUpdate{On: &on})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Group 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	invalidBri := 0
+	invalidCT := 600
+	invalidTransition := -1
+
+	tests := []struct {
+		name     string
+		update   GroupActionUpdate
+		expected string
+	}{
+		{"Empty", GroupActionUpdate{}, "Update must change at least one attribute"},
+		{"Invalid bri", GroupActionUpdate{Bri: &invalidBri}, "Invalid brightness value: bri must be between 1 and 254"},
+		{"Invalid ct", GroupActionUpdate{CT: &invalidCT}, "Invalid color temperature value: ct must be between 153 and 500"},
+		{"Invalid xy", GroupActionUpdate{XY: []float32{0.5}}, "Invalid color value: xy must contain exactly 2 values"},
+		{"Invalid effect", GroupActionUpdate{Effect: "sparkle"}, "Effect must be one of the following: none, colorloop"},
+		{"Invalid alert", GroupActionUpdate{Alert: "blink"}, "Alert must be one of the following: none, select, lselect"},
+		{"Invalid transition", GroupActionUpdate{TransitionTime: &invalidTransition}, "Invalid transition time: transitiontime must be between 0 and 65,535"},
+		{"Invalid xy_inc", GroupActionUpdate{XYInc: []float32{0.6, 0}}, "Invalid color increment: xy_inc values must be between -0.5 and 0.5"},
+		{"Value and increment", GroupActionUpdate{Bri: &bri, BriInc: &briInc}, "Only one of bri and bri_inc can be set"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := h.SetGroupAction(1, test.update)
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+
+			if err.Error() != test.expected {
+				t.Fatalf("Expected error message to equal %s, got %s", test.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestRecallScene(t *testing.T) {
+	h, server, lastBody := createRecordingConnection()
+	defer server.Close()
+
+	t.Run("Successful", func(t *testing.T) {
+		err := h.RecallScene(1, "1", 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := `{"transitiontime":20,"scene":"1"}`
+			if string(lastBody()) != expected {
+				t.Fatalf("Expected body to equal %s, got %s", expected, lastBody())
+			}
+		}
+	})
+
+	t.Run("Instant", func(t *testing.T) {
+		err := h.RecallScene(1, "1", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := `{"transitiontime":0,"scene":"1"}`
+			if string(lastBody()) != expected {
+				t.Fatalf("Expected body to equal %s, got %s", expected, lastBody())
+			}
+		}
+	})
+
+	t.Run("Scene transitions", func(t *testing.T) {
+		err := h.RecallScene(1, "1", SceneTransition)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := `{"scene":"1"}`
+			if string(lastBody()) != expected {
+				t.Fatalf("Expected body to equal %s, got %s", expected, lastBody())
+			}
+		}
+	})
+
+	t.Run("Group doesn't exist", func(t *testing.T) {
+		err := h.RecallScene(3, "1", SceneTransition)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Group 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Scene doesn't exist", func(t *testing.T) {
+		err := h.RecallScene(1, "missing", SceneTransition)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Scene missing not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid transition", func(t *testing.T) {
+		err := h.RecallScene(1, "1", 2*time.Hour)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+}
+
+func TestAllLightsGroup(t *testing.T) {
+	h, server, lastBody := createRecordingConnection()
+	defer server.Close()
+
+	t.Run("Get", func(t *testing.T) {
+		group, err := h.GetAllLightsGroup()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if group.ID != AllLightsGroup || group.Name != "Group 0" || len(group.Lights) != 2 {
+			t.Fatalf("Unexpected group %+v", group)
+		}
+	})
+
+	t.Run("On", func(t *testing.T) {
+		bri := 150
+		err := h.AllLightsOn(GroupActionUpdate{Bri: &bri})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := `{"on":true,"bri":150}`
+			if string(lastBody()) != expected {
+				t.Fatalf("Expected body to equal %s, got %s", expected, lastBody())
+			}
+		}
+	})
+
+	t.Run("Off", func(t *testing.T) {
+		err := h.AllLightsOff()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := `{"on":false}`
+			if string(lastBody()) != expected {
+				t.Fatalf("Expected body to equal %s, got %s", expected, lastBody())
+			}
+		}
+	})
+
+	t.Run("Resolve", func(t *testing.T) {
+		group, err := h.ResolveGroup("0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if group != AllLightsGroup {
+			t.Fatalf("Expected group %d, got %d", AllLightsGroup, group)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		fn       func() error
+		expected string
+	}{
+		{"Rename", func() error { return h.RenameGroup(0, "Everything") }, "Group 0 contains every light and can't be renamed"},
+		{"Set lights", func() error { return h.SetLightsInGroup(0, []int{1}) }, "Group 0 contains every light and can't be changed"},
+		{"Set class", func() error { return h.SetGroupClass(0, "Kitchen") }, "Group 0 contains every light and can't be changed"},
+		{"Delete", func() error { return h.DeleteGroup(0) }, "Group 0 contains every light and can't be deleted"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.fn()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+
+			if err.Error() != test.expected {
+				t.Fatalf("Expected error message to equal %s, got %s", test.expected, err.Error())
+			}
+		})
+	}
+}
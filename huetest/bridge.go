@@ -0,0 +1,458 @@
+// Package huetest provides an in-memory Phillips Hue bridge for testing code
+// that uses the hue package.
+//
+// A Bridge implements the v1 API for lights, groups, scenes, schedules, rules,
+// sensors, resourcelinks, and the bridge configuration. Creates allocate IDs,
+// writes persist, and errors are returned with the same type codes a real
+// bridge uses.
+//
+//	server := huetest.NewServer()
+//	defer server.Close()
+//
+//	light := server.Bridge.AddLight("Desk", huetest.ExtendedColorLight)
+//	h := server.Connection()
+package huetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resource types served by the bridge
+const (
+	resourceLights        = "lights"
+	resourceGroups        = "groups"
+	resourceScenes        = "scenes"
+	resourceSchedules     = "schedules"
+	resourceRules         = "rules"
+	resourceSensors       = "sensors"
+	resourceResourceLinks = "resourcelinks"
+)
+
+var resourceTypes = []string{
+	resourceLights,
+	resourceGroups,
+	resourceScenes,
+	resourceSchedules,
+	resourceRules,
+	resourceSensors,
+	resourceResourceLinks,
+}
+
+// timeFormat is the format of all times returned by the bridge
+const timeFormat = "2006-01-02T15:04:05"
+
+// linkButtonDuration is how long the link button stays pressed
+const linkButtonDuration = 30 * time.Second
+
+// DefaultBridgeID is the ID reported by a new Bridge
+const DefaultBridgeID = "001788FFFE4E7A11"
+
+// object is a single resource, decoded from JSON
+type object = map[string]interface{}
+
+// Bridge is a stateful in-memory Phillips Hue bridge. It is an http.Handler
+// serving the v1 API under /api and is safe for concurrent use.
+type Bridge struct {
+	mu              sync.Mutex
+	resources       map[string]map[string]object
+	config          object
+	whitelist       map[string]object
+	linkButtonUntil time.Time
+	queued          map[string][]object
+	found           map[string][]string
+	lastScan        map[string]string
+	zeroAction      object
+	nextScene       int
+	nextUser        int
+
+	// Now returns the current time. It can be replaced to control the times
+	// reported by the bridge.
+	Now func() time.Time
+}
+
+// NewBridge creates a Bridge without any resources or users
+func NewBridge() *Bridge {
+	b := &Bridge{
+		resources: make(map[string]map[string]object),
+		whitelist: make(map[string]object),
+		queued:    make(map[string][]object),
+		found:     make(map[string][]string),
+		lastScan:  map[string]string{resourceLights: "none", resourceSensors: "none"},
+		Now:       time.Now,
+	}
+
+	for _, resource := range resourceTypes {
+		b.resources[resource] = make(map[string]object)
+	}
+
+	b.config = object{
+		"name":             "Philips hue",
+		"bridgeid":         DefaultBridgeID,
+		"modelid":          "BSB002",
+		"datastoreversion": "98",
+		"zigbeechannel":    float64(15),
+		"mac":              "00:17:88:4e:7a:11",
+		"dhcp":             true,
+		"ipaddress":        "192.168.1.2",
+		"netmask":          "255.255.255.0",
+		"gateway":          "192.168.1.1",
+		"proxyaddress":     "none",
+		"proxyport":        float64(0),
+		"timezone":         "UTC",
+		"swversion":        "1953188020",
+		"apiversion":       "1.53.0",
+		"swupdate": object{
+			"updatestate":    float64(0),
+			"checkforupdate": false,
+			"devicetypes": object{
+				"bridge":  false,
+				"lights":  []interface{}{},
+				"sensors": []interface{}{},
+			},
+			"url":    "",
+			"text":   "",
+			"notify": false,
+		},
+		"portalservices":   true,
+		"portalconnection": "connected",
+		"portalstate": object{
+			"signedon":      true,
+			"incoming":      false,
+			"outgoing":      true,
+			"communication": "disconnected",
+		},
+		"factorynew": false,
+	}
+
+	return b
+}
+
+// AddUser adds a user to the whitelist and returns its username
+func (b *Bridge) AddUser(deviceType string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.addUser(deviceType)
+}
+
+func (b *Bridge) addUser(deviceType string) string {
+	b.nextUser++
+	username := fmt.Sprintf("huetest%033d", b.nextUser)
+
+	now := b.Now().UTC().Format(timeFormat)
+	b.whitelist[username] = object{
+		"name":          deviceType,
+		"create date":   now,
+		"last use date": now,
+	}
+
+	return username
+}
+
+// PressLinkButton presses the link button, allowing new users to be created
+// for the next 30 seconds
+func (b *Bridge) PressLinkButton() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.linkButtonUntil = b.Now().Add(linkButtonDuration)
+}
+
+// Get returns a copy of the value at the specified path, such as
+// lights/1/state/on or config/name, and whether it exists
+func (b *Bridge) Get(path string) (interface{}, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var value interface{} = b.fullState()
+	for _, part := range splitPath(path) {
+		obj, ok := value.(object)
+		if !ok {
+			return nil, false
+		}
+
+		value, ok = obj[part]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return copyValue(value), true
+}
+
+// ServeHTTP implements http.Handler
+func (b *Bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := splitPath(r.URL.Path)
+	if len(parts) == 0 || parts[0] != "api" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var body object
+	data, _ := ioutil.ReadAll(r.Body)
+	if len(strings.TrimSpace(string(data))) > 0 {
+		err := json.Unmarshal(data, &body)
+		if err != nil {
+			writeJSON(w, errorResponse(2, address(parts[min(len(parts), 2):]), "body contains invalid json"))
+			return
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Creating a user is the only request that doesn't need a username
+	if len(parts) == 1 {
+		if r.Method != "POST" {
+			writeJSON(w, errorResponse(4, "/", fmt.Sprintf("method, %s, not available for resource, /", r.Method)))
+			return
+		}
+
+		writeJSON(w, b.createUser(body))
+		return
+	}
+
+	username := parts[1]
+	parts = parts[2:]
+
+	if _, ok := b.whitelist[username]; !ok {
+		// Unauthorized users can still read the basic configuration
+		if r.Method == "GET" && len(parts) == 1 && parts[0] == "config" {
+			writeJSON(w, b.basicConfig())
+			return
+		}
+
+		writeJSON(w, errorResponse(1, address(parts), "unauthorized user"))
+		return
+	}
+
+	b.whitelist[username]["last use date"] = b.Now().UTC().Format(timeFormat)
+
+	writeJSON(w, b.handle(r.Method, username, parts, body))
+}
+
+// handle routes an authorized request and returns the response
+func (b *Bridge) handle(method, username string, parts []string, body object) interface{} {
+	if len(parts) == 0 {
+		if method == "GET" {
+			return b.fullState()
+		}
+
+		return methodNotAvailable(method, parts)
+	}
+
+	if parts[0] == "config" {
+		return b.handleConfig(method, parts, body)
+	}
+
+	if _, ok := b.resources[parts[0]]; !ok {
+		return resourceNotAvailable(parts)
+	}
+
+	resource := parts[0]
+
+	switch len(parts) {
+	case 1:
+		switch method {
+		case "GET":
+			return b.list(resource)
+		case "POST":
+			return b.create(resource, username, body)
+		}
+	case 2:
+		if parts[1] == "new" && (resource == resourceLights || resource == resourceSensors) {
+			if method == "GET" {
+				return b.newDevices(resource)
+			}
+
+			return methodNotAvailable(method, parts)
+		}
+
+		switch method {
+		case "GET":
+			return b.get(resource, parts[1])
+		case "PUT":
+			return b.update(resource, parts[1], body)
+		case "DELETE":
+			return b.delete(resource, parts[1])
+		}
+	case 3:
+		if method == "PUT" {
+			return b.updateChild(resource, parts[1], parts[2], body)
+		}
+	case 4:
+		if method == "PUT" && resource == resourceScenes && parts[2] == "lightstates" {
+			return b.updateSceneLightState(parts[1], parts[3], body)
+		}
+	}
+
+	if len(parts) > 4 {
+		return resourceNotAvailable(parts)
+	}
+
+	return methodNotAvailable(method, parts)
+}
+
+// fullState returns the entire state of the bridge
+func (b *Bridge) fullState() object {
+	state := object{"config": b.fullConfig()}
+	for _, resource := range resourceTypes {
+		state[resource] = b.list(resource)
+	}
+
+	return state
+}
+
+// list returns all resources of the specified type keyed by ID
+func (b *Bridge) list(resource string) object {
+	all := object{}
+	for id := range b.resources[resource] {
+		all[id] = b.view(resource, id, true)
+	}
+
+	return all
+}
+
+// get returns a single resource
+func (b *Bridge) get(resource, id string) interface{} {
+	if resource == resourceGroups && id == "0" {
+		return b.groupZero()
+	}
+
+	if _, ok := b.resources[resource][id]; !ok {
+		return resourceNotAvailable([]string{resource, id})
+	}
+
+	return b.view(resource, id, false)
+}
+
+// view returns a copy of a resource as the API returns it, including values
+// derived from other resources
+func (b *Bridge) view(resource, id string, inList bool) object {
+	obj := copyValue(b.resources[resource][id]).(object)
+
+	switch resource {
+	case resourceGroups:
+		obj["state"] = b.groupState(stringList(obj["lights"]))
+	case resourceScenes:
+		// Light states are only returned when getting a single scene
+		if inList {
+			delete(obj, "lightstates")
+		}
+	}
+
+	return obj
+}
+
+// nextID returns the lowest unused numeric ID for the resource type
+func (b *Bridge) nextID(resource string) string {
+	for i := 1; ; i++ {
+		id := strconv.Itoa(i)
+		if _, ok := b.resources[resource][id]; !ok {
+			return id
+		}
+	}
+}
+
+// sortedIDs returns the IDs of all resources of the specified type, sorted
+// numerically
+func (b *Bridge) sortedIDs(resource string) []string {
+	ids := make([]string, 0, len(b.resources[resource]))
+	for id := range b.resources[resource] {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		c, errC := strconv.Atoi(ids[j])
+		if errA != nil || errC != nil {
+			return ids[i] < ids[j]
+		}
+
+		return a < c
+	})
+
+	return ids
+}
+
+func (b *Bridge) now() string {
+	return b.Now().UTC().Format(timeFormat)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func splitPath(path string) []string {
+	parts := []string{}
+	for _, part := range strings.Split(path, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
+
+// address formats path parts as the address used in responses
+func address(parts []string) string {
+	return "/" + strings.Join(parts, "/")
+}
+
+// copyValue returns a deep copy of a value decoded from JSON
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case object:
+		c := make(object, len(v))
+		for key, item := range v {
+			c[key] = copyValue(item)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = copyValue(item)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// stringList converts a list decoded from JSON to strings
+func stringList(value interface{}) []string {
+	list, _ := value.([]interface{})
+
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+
+	return strs
+}
+
+func interfaceList(strs []string) []interface{} {
+	list := make([]interface{}, len(strs))
+	for i, s := range strs {
+		list[i] = s
+	}
+
+	return list
+}
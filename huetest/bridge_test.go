@@ -0,0 +1,489 @@
+package huetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mattvella07/hue"
+)
+
+// request sends a raw request to the server and decodes the response
+func request(t *testing.T, s *Server, method, path string, body interface{}) interface{} {
+	t.Helper()
+
+	data, _ := json.Marshal(body)
+	if body == nil {
+		data = nil
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var decoded interface{}
+	err = json.NewDecoder(resp.Body).Decode(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return decoded
+}
+
+// errorType returns the type of the first error in a response, or 0 if the
+// first result isn't an error
+func errorType(response interface{}) int {
+	results, ok := response.([]interface{})
+	if !ok || len(results) == 0 {
+		return 0
+	}
+
+	e, ok := results[0].(map[string]interface{})["error"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	return int(e["type"].(float64))
+}
+
+func expectBridgeError(t *testing.T, err error, errorType int) {
+	t.Helper()
+
+	bridgeErrs := hue.BridgeErrors{}
+	if !errors.As(err, &bridgeErrs) || !bridgeErrs.HasType(errorType) {
+		t.Fatalf("Expected bridge error type %d, got %v", errorType, err)
+	}
+}
+
+func TestLights(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	s.Bridge.AddLight("Desk", ExtendedColorLight)
+	s.Bridge.AddLight("Hall", DimmableLight)
+	h := s.Connection()
+
+	lights, err := h.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	if len(lights) != 2 || lights[0].Name != "Desk" || lights[1].ID != 2 {
+		t.Fatalf("Unexpected lights %+v", lights)
+	}
+
+	t.Run("State changes persist", func(t *testing.T) {
+		err := h.TurnOnLightWithColor(1, 0.3, 0.3, 100, 200, 150)
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		light, _ := h.GetLight(1)
+		if !light.State.On || light.State.Bri != 100 || light.State.ColorMode != "xy" {
+			t.Fatalf("Unexpected state %+v", light.State)
+		}
+	})
+
+	t.Run("Light off", func(t *testing.T) {
+		err := h.TurnOffLight(1)
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		resp := request(t, s, "PUT", "/api/"+s.Username+"/lights/1/state", map[string]interface{}{"bri": 50})
+		if errorType(resp) != 201 {
+			t.Fatalf("Expected error type 201, got %v", resp)
+		}
+	})
+
+	t.Run("Unsupported parameter", func(t *testing.T) {
+		resp := request(t, s, "PUT", "/api/"+s.Username+"/lights/2/state", map[string]interface{}{"on": true, "xy": []float64{0.3, 0.3}})
+		if errorType(resp) != 6 {
+			t.Fatalf("Expected error type 6, got %v", resp)
+		}
+
+		if on, _ := s.Bridge.Get("lights/2/state/on"); on != true {
+			t.Fatal("Expected the valid parameter to be applied")
+		}
+	})
+
+	t.Run("Invalid value", func(t *testing.T) {
+		resp := request(t, s, "PUT", "/api/"+s.Username+"/lights/2/state", map[string]interface{}{"bri": 300})
+		if errorType(resp) != 7 {
+			t.Fatalf("Expected error type 7, got %v", resp)
+		}
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := h.GetLight(9)
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+
+		resp := request(t, s, "GET", "/api/"+s.Username+"/lights/9", nil)
+		if errorType(resp) != 3 {
+			t.Fatalf("Expected error type 3, got %v", resp)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		s.Bridge.QueueLight("Porch", ColorLight)
+
+		err := h.FindNewLights()
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		newLights, err := h.GetNewLights()
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		if len(newLights.NewLights) != 1 || newLights.NewLights[0].Name != "Porch" || newLights.NewLights[0].ID != 3 {
+			t.Fatalf("Unexpected new lights %+v", newLights)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		err := h.DeleteLight(3)
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		if _, ok := s.Bridge.Get("lights/3"); ok {
+			t.Fatal("Expected light to be deleted")
+		}
+	})
+}
+
+func TestEntertainmentGroups(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	s.Bridge.AddLight("Strip", ExtendedColorLight)
+	s.Bridge.AddLight("Bulb", DimmableLight)
+	h := s.Connection()
+
+	err := h.CreateGroup("TV", hue.GroupTypeEntertainment, hue.ClassTV, []int{1})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	err = h.CreateGroup("Movie", hue.GroupTypeEntertainment, hue.ClassTV, []int{1, 2})
+	if err == nil || err.Error() != "Light 2 doesn't support entertainment streaming" {
+		t.Fatalf("Expected light 2 to be rejected, got %v", err)
+	}
+
+	err = h.SetGroupClass(1, hue.ClassFree)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	// Entertainment groups only accept TV and Free
+	resp := request(t, s, "PUT", "/api/"+s.Username+"/groups/1", map[string]interface{}{"class": "Kitchen"})
+	if errorType(resp) != 7 {
+		t.Fatalf("Expected the class to be rejected, got %v", resp)
+	}
+
+	group, err := h.GetGroup(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if group.Class != hue.ClassFree {
+		t.Fatalf("Expected class to equal %s, got %s", hue.ClassFree, group.Class)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		s.Bridge.AddLight(fmt.Sprintf("Light %d", i+1), ExtendedColorLight)
+	}
+	h := s.Connection()
+
+	err := h.CreateGroup("Kitchen", "Room", "Kitchen", []int{1, 2})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	// The library refuses to move a light out of a room, so the bridge's own
+	// behavior is tested with a request
+	resp := request(t, s, "POST", "/api/"+s.Username+"/groups", map[string]interface{}{"name": "Dining", "type": "Room", "class": "Dining", "lights": []string{"2", "3"}})
+	if errorType(resp) != 0 {
+		t.Fatalf("Expected no errors, got %v", resp)
+	}
+
+	t.Run("IDs are allocated", func(t *testing.T) {
+		groups, _ := h.GetGroups()
+		if len(groups) != 2 || groups[0].ID != 1 || groups[1].ID != 2 {
+			t.Fatalf("Unexpected groups %+v", groups)
+		}
+	})
+
+	t.Run("A light is only in one room", func(t *testing.T) {
+		kitchen, _ := h.GetGroup(1)
+		if len(kitchen.Lights) != 1 || kitchen.Lights[0] != "1" {
+			t.Fatalf("Expected light 2 to move out of the kitchen, got %v", kitchen.Lights)
+		}
+	})
+
+	t.Run("Room conflict", func(t *testing.T) {
+		err := h.CreateGroup("Pantry", "Room", "Storage", []int{1})
+
+		var conflict *hue.RoomConflictError
+		if !errors.As(err, &conflict) || conflict.Light != 1 || conflict.Room != 1 || conflict.RoomName != "Kitchen" {
+			t.Fatalf("Expected a room conflict with the kitchen, got %v", err)
+		}
+
+		err = h.SetLightsInGroup(2, []int{1, 2})
+		if !errors.As(err, &conflict) || conflict.Light != 1 {
+			t.Fatalf("Expected a room conflict with the kitchen, got %v", err)
+		}
+
+		// Lights can be in several zones and a room
+		err = h.CreateGroup("Downstairs", "Zone", "Downstairs", []int{1, 2})
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+	})
+
+	t.Run("Actions apply to lights", func(t *testing.T) {
+		err := h.TurnOnGroup(2)
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		group, _ := h.GetGroup(2)
+		if !group.State.AllOn || !group.State.AnyOn {
+			t.Fatalf("Expected all lights to be on, got %+v", group.State)
+		}
+
+		light, _ := h.GetLight(1)
+		if light.State.On {
+			t.Fatal("Expected light 1 to stay off")
+		}
+	})
+
+	t.Run("Group 0", func(t *testing.T) {
+		group, err := h.GetGroup(0)
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		if len(group.Lights) != 3 || group.State.AllOn || !group.State.AnyOn {
+			t.Fatalf("Unexpected group 0 %+v", group)
+		}
+
+		resp := request(t, s, "PUT", "/api/"+s.Username+"/groups/0/action", map[string]interface{}{"on": false})
+		if errorType(resp) != 0 {
+			t.Fatalf("Expected no errors, got %v", resp)
+		}
+
+		if on, _ := s.Bridge.Get("lights/2/state/on"); on != false {
+			t.Fatal("Expected all lights to be off")
+		}
+	})
+
+	t.Run("Invalid type", func(t *testing.T) {
+		resp := request(t, s, "POST", "/api/"+s.Username+"/groups", map[string]interface{}{"name": "Lamp", "type": "Luminaire", "lights": []string{"1"}})
+		if errorType(resp) != 7 {
+			t.Fatalf("Expected error type 7, got %v", resp)
+		}
+	})
+}
+
+func TestScenes(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	s.Bridge.AddLight("Desk", ExtendedColorLight)
+	h := s.Connection()
+
+	err := h.TurnOnLightWithColor(1, 0.2, 0.3, 50, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	err = h.CreateLightScene("Reading", []int{1}, false, hue.SceneAppData{})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	scenes, _ := h.GetScenes()
+	if len(scenes) != 1 || scenes[0].Name != "Reading" || scenes[0].Owner != s.Username {
+		t.Fatalf("Unexpected scenes %+v", scenes)
+	}
+
+	err = h.TurnOnLightWithColor(1, 0.5, 0.4, 200, 100, 100)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	resp := request(t, s, "PUT", "/api/"+s.Username+"/groups/0/action", map[string]interface{}{"scene": scenes[0].ID})
+	if errorType(resp) != 0 {
+		t.Fatalf("Expected no errors, got %v", resp)
+	}
+
+	light, _ := h.GetLight(1)
+	if light.State.Bri != 50 {
+		t.Fatalf("Expected scene brightness to be recalled, got %d", light.State.Bri)
+	}
+
+	err = h.DeleteScene(scenes[0].ID)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+}
+
+func TestSchedulesRulesAndResourceLinks(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	h := s.Connection()
+
+	command := hue.ScheduleCommand{Address: "/api/" + s.Username + "/groups/0/action", Method: "PUT", Body: hue.ScheduleCommandBody{Scene: "abc"}}
+	err := h.CreateSchedule("Wake up", "Morning", command, "W127/T07:00:00", "enabled", false, false)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	err = h.SetScheduleStatus(1, "disabled")
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	schedule, _ := h.GetSchedule(1)
+	if schedule.Name != "Wake up" || schedule.Status != "disabled" || schedule.Time != "W127/T07:00:00" {
+		t.Fatalf("Unexpected schedule %+v", schedule)
+	}
+
+	conditions := []hue.RuleConditions{{Address: "/sensors/1/state/presence", Operator: "eq", Value: "true"}}
+	actions := []hue.RuleActions{{Address: "/groups/0/action", Method: "PUT", Body: hue.RuleActionsBody{Scene: "abc"}}}
+	err = h.CreateRule("Motion", conditions, actions)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	rule, _ := h.GetRule(1)
+	if rule.Name != "Motion" || rule.Owner != s.Username || rule.Status != "enabled" {
+		t.Fatalf("Unexpected rule %+v", rule)
+	}
+
+	err = h.CreateResourceLink("Morning", "Wake up routine", false, []string{"/schedules/1", "/rules/1"})
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	err = h.RenameResourceLink(1, "Mornings")
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	link, _ := h.GetResourceLink(1)
+	if link.Name != "Mornings" || len(link.Links) != 2 {
+		t.Fatalf("Unexpected resourcelink %+v", link)
+	}
+
+	resp := request(t, s, "POST", "/api/"+s.Username+"/rules", map[string]interface{}{"name": "Empty"})
+	if errorType(resp) != 5 {
+		t.Fatalf("Expected error type 5, got %v", resp)
+	}
+}
+
+func TestSensors(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	s.Bridge.AddSensor("Hall motion", PresenceSensor)
+	h := s.Connection()
+
+	err := h.CreateSensor("Flag", "Flag", "1.0", "CLIPGenericFlag", "flag-1", "huetest", hue.SensorState{}, hue.SensorConfig{On: true}, false)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	err = h.TurnOffSensor(2)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	sensor, _ := h.GetSensor(2)
+	if sensor.Type != "CLIPGenericFlag" || sensor.Config.On {
+		t.Fatalf("Unexpected sensor %+v", sensor)
+	}
+
+	s.Bridge.SetSensorState("1", map[string]interface{}{"presence": true})
+	if presence, _ := s.Bridge.Get("sensors/1/state/presence"); presence != true {
+		t.Fatal("Expected presence to be detected")
+	}
+
+	sensor, _ = h.GetSensor(1)
+	if !sensor.State.Presence || sensor.Config.Battery != 100 || !sensor.Config.Reachable {
+		t.Fatalf("Expected presence and battery to be decoded, got %+v", sensor)
+	}
+
+	resp := request(t, s, "PUT", "/api/"+s.Username+"/sensors/1/state", map[string]interface{}{"presence": false})
+	if errorType(resp) != 8 {
+		t.Fatalf("Expected error type 8, got %v", resp)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	t.Run("Unauthorized user", func(t *testing.T) {
+		h := &hue.Connection{UserID: "unknown", Address: s.Address()}
+
+		_, err := h.GetLights()
+		expectBridgeError(t, err, hue.ErrorUnauthorizedUser)
+
+		config, err := h.GetConfiguration()
+		if err != nil || config.BridgeID != DefaultBridgeID {
+			t.Fatalf("Expected basic configuration, got %+v, %v", config, err)
+		}
+	})
+
+	t.Run("Link button", func(t *testing.T) {
+		resp := request(t, s, "POST", "/api", map[string]interface{}{"devicetype": "app#test"})
+		if errorType(resp) != 101 {
+			t.Fatalf("Expected error type 101, got %v", resp)
+		}
+
+		s.Bridge.PressLinkButton()
+
+		resp = request(t, s, "POST", "/api", map[string]interface{}{"devicetype": "app#test"})
+		username := resp.([]interface{})[0].(map[string]interface{})["success"].(map[string]interface{})["username"].(string)
+
+		h := &hue.Connection{UserID: username, Address: s.Address()}
+		config, err := h.GetConfiguration()
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		if len(config.Whitelist) != 2 {
+			t.Fatalf("Expected 2 whitelisted users, got %d", len(config.Whitelist))
+		}
+
+		err = h.DeleteUser(username)
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		_, err = h.GetLights()
+		expectBridgeError(t, err, hue.ErrorUnauthorizedUser)
+	})
+}
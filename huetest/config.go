@@ -0,0 +1,137 @@
+package huetest
+
+import "fmt"
+
+// writableConfig are the configuration attributes that can be changed with a
+// PUT to config, and a function that validates their value
+var writableConfig = map[string]func(value interface{}) bool{
+	"name":           func(value interface{}) bool { return validName(value) && len(value.(string)) >= 4 },
+	"linkbutton":     isBool,
+	"dhcp":           isBool,
+	"ipaddress":      isString,
+	"netmask":        isString,
+	"gateway":        isString,
+	"proxyaddress":   isString,
+	"proxyport":      func(value interface{}) bool { return isIntInRange(value, 0, 65535) },
+	"timezone":       isString,
+	"zigbeechannel":  func(value interface{}) bool { return isOneOf(fmt.Sprint(value), "11", "15", "20", "25") },
+	"portalservices": isBool,
+}
+
+// handleConfig handles requests to the bridge configuration and whitelist
+func (b *Bridge) handleConfig(method string, parts []string, body object) interface{} {
+	switch {
+	case len(parts) == 1 && method == "GET":
+		return b.fullConfig()
+	case len(parts) == 1 && method == "PUT":
+		return b.updateConfig(body)
+	case len(parts) == 3 && parts[1] == "whitelist" && method == "DELETE":
+		if _, ok := b.whitelist[parts[2]]; !ok {
+			return resourceNotAvailable(parts)
+		}
+
+		delete(b.whitelist, parts[2])
+		return []interface{}{success(fmt.Sprintf("%s deleted", address(parts)))}
+	case len(parts) > 1 && parts[1] != "whitelist":
+		return resourceNotAvailable(parts)
+	}
+
+	return methodNotAvailable(method, parts)
+}
+
+// fullConfig returns the configuration as returned to an authorized user
+func (b *Bridge) fullConfig() object {
+	config := copyValue(b.config).(object)
+
+	whitelist := object{}
+	for username, user := range b.whitelist {
+		whitelist[username] = copyValue(user)
+	}
+
+	now := b.Now()
+	config["whitelist"] = whitelist
+	config["linkbutton"] = now.Before(b.linkButtonUntil)
+	config["UTC"] = now.UTC().Format(timeFormat)
+	config["localtime"] = now.Format(timeFormat)
+
+	return config
+}
+
+// basicConfig returns the configuration as returned to an unauthorized user
+func (b *Bridge) basicConfig() object {
+	basic := object{}
+	for _, param := range []string{"name", "datastoreversion", "swversion", "apiversion", "mac", "bridgeid", "factorynew", "modelid"} {
+		basic[param] = b.config[param]
+	}
+
+	basic["replacesbridgeid"] = nil
+	basic["starterkitid"] = ""
+
+	return basic
+}
+
+// updateConfig changes the bridge configuration
+func (b *Bridge) updateConfig(body object) []interface{} {
+	parts := []string{"config"}
+
+	if len(body) == 0 {
+		return missingParameters(parts)
+	}
+
+	results := []interface{}{}
+
+	for _, param := range sortedKeys(body) {
+		value := body[param]
+
+		valid, ok := writableConfig[param]
+		switch {
+		case !ok:
+			if _, exists := b.fullConfig()[param]; exists {
+				results = append(results, notModifiable(parts, param))
+			} else {
+				results = append(results, parameterNotAvailable(parts, param))
+			}
+			continue
+		case !valid(value):
+			results = append(results, invalidValue(parts, param, value))
+			continue
+		}
+
+		if param == "linkbutton" {
+			if value == true {
+				b.linkButtonUntil = b.Now().Add(linkButtonDuration)
+			} else {
+				b.linkButtonUntil = b.Now()
+			}
+		} else {
+			b.config[param] = value
+		}
+
+		results = append(results, success(object{paramAddress(parts, param): value}))
+	}
+
+	return results
+}
+
+// createUser adds a user to the whitelist if the link button has been pressed
+func (b *Bridge) createUser(body object) []interface{} {
+	deviceType, ok := body["devicetype"].(string)
+	if !ok {
+		return missingParameters(nil)
+	}
+
+	if deviceType == "" || len(deviceType) > 40 {
+		return []interface{}{invalidValue(nil, "devicetype", deviceType)}
+	}
+
+	if !b.Now().Before(b.linkButtonUntil) {
+		return errorResponse(101, "", "link button not pressed")
+	}
+
+	result := object{"username": b.addUser(deviceType)}
+	if body["generateclientkey"] == true {
+		result["clientkey"] = fmt.Sprintf("%032X", b.nextUser)
+	}
+
+	return []interface{}{success(result)}
+}
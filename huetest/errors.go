@@ -0,0 +1,74 @@
+package huetest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// errorResponse creates a response containing a single error
+func errorResponse(errorType int, address, description string) []interface{} {
+	return []interface{}{bridgeError(errorType, address, description)}
+}
+
+func bridgeError(errorType int, address, description string) object {
+	return object{
+		"error": object{
+			"type":        errorType,
+			"address":     address,
+			"description": description,
+		},
+	}
+}
+
+func success(value interface{}) object {
+	return object{"success": value}
+}
+
+func resourceNotAvailable(parts []string) []interface{} {
+	return errorResponse(3, address(parts), fmt.Sprintf("resource, %s, not available", address(parts)))
+}
+
+func methodNotAvailable(method string, parts []string) []interface{} {
+	return errorResponse(4, address(parts), fmt.Sprintf("method, %s, not available for resource, %s", method, address(parts)))
+}
+
+func missingParameters(parts []string) []interface{} {
+	return errorResponse(5, address(parts), "invalid/missing parameters in body")
+}
+
+func parameterNotAvailable(parts []string, param string) object {
+	return bridgeError(6, paramAddress(parts, param), fmt.Sprintf("parameter, %s, not available", param))
+}
+
+func invalidValue(parts []string, param string, value interface{}) object {
+	return bridgeError(7, paramAddress(parts, param), fmt.Sprintf("invalid value, %s, for parameter, %s", formatValue(value), param))
+}
+
+func notModifiable(parts []string, param string) object {
+	return bridgeError(8, paramAddress(parts, param), fmt.Sprintf("parameter, %s, is not modifiable", param))
+}
+
+func deviceIsOff(parts []string, param string) object {
+	return bridgeError(201, paramAddress(parts, param), fmt.Sprintf("parameter, %s, is not modifiable. Device is set to off.", param))
+}
+
+// formatValue formats a value from a request body for an error description
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		strs := make([]string, len(v))
+		for i, item := range v {
+			strs[i] = formatValue(item)
+		}
+		return "[" + strings.Join(strs, ", ") + "]"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// paramAddress formats the address of a parameter of the resource at parts
+func paramAddress(parts []string, param string) string {
+	return strings.TrimSuffix(address(parts), "/") + "/" + param
+}
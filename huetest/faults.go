@@ -0,0 +1,350 @@
+package huetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault describes how a request is disrupted. Latency is added before any
+// other fault. A zero Fault lets the request through unchanged.
+type Fault struct {
+	// Latency delays the request
+	Latency time.Duration
+
+	// StatusCode responds with the HTTP status, such as 503, without sending
+	// the request to the bridge
+	StatusCode int
+
+	// Drop fails the request as if the connection was reset after it was sent
+	Drop bool
+
+	// Refuse fails the request as if the bridge couldn't be connected to
+	Refuse bool
+
+	// Truncate sends the request to the bridge and cuts its response body in
+	// half, leaving invalid JSON
+	Truncate bool
+
+	// ErrorType responds with a bridge error of the type, such as 201 or 901,
+	// without sending the request to the bridge
+	ErrorType int
+
+	// ErrorDescription is the description of the bridge error. If empty, the
+	// description the bridge uses for the error type is used.
+	ErrorDescription string
+}
+
+// Delay returns a Fault that delays requests
+func Delay(latency time.Duration) Fault {
+	return Fault{Latency: latency}
+}
+
+// Status returns a Fault that responds with the HTTP status
+func Status(code int) Fault {
+	return Fault{StatusCode: code}
+}
+
+// Drop returns a Fault that resets the connection after the request is sent
+func Drop() Fault {
+	return Fault{Drop: true}
+}
+
+// Refuse returns a Fault that fails to connect to the bridge
+func Refuse() Fault {
+	return Fault{Refuse: true}
+}
+
+// Truncate returns a Fault that cuts the bridge's response in half
+func Truncate() Fault {
+	return Fault{Truncate: true}
+}
+
+// ErrorPayload returns a Fault that responds with a bridge error of the type
+func ErrorPayload(errorType int) Fault {
+	return Fault{ErrorType: errorType}
+}
+
+// errorDescriptions are the descriptions the bridge uses for error types that
+// don't depend on the request
+var errorDescriptions = map[int]string{
+	1:   "unauthorized user",
+	2:   "body contains invalid json",
+	4:   "method, %s, not available for resource, %s",
+	5:   "invalid/missing parameters in body",
+	101: "link button not pressed",
+	201: "parameter, on, is not modifiable. Device is set to off.",
+	301: "group could not be created. Group table is full.",
+	901: "Internal error, 404",
+}
+
+// Match selects the requests a Fault applies to
+type Match struct {
+	// Method is the HTTP method. If empty, all methods match.
+	Method string
+
+	// Path is matched against the path after the username, such as
+	// lights/1/state. It may contain wildcards as used by path.Match, and
+	// also matches all paths below it, so lights matches every light. If
+	// empty, all paths match.
+	Path string
+}
+
+func (m Match) matches(method, apiPath string) bool {
+	if m.Method != "" && !strings.EqualFold(m.Method, method) {
+		return false
+	}
+
+	if m.Path == "" {
+		return true
+	}
+
+	pattern := strings.Trim(m.Path, "/")
+	parts := strings.Split(apiPath, "/")
+
+	// Compare the pattern with the path and each of its parents
+	for i := len(parts); i > 0; i-- {
+		if ok, _ := path.Match(pattern, strings.Join(parts[:i], "/")); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+type faultRule struct {
+	match       Match
+	probability float64
+	fault       Fault
+	script      []Fault
+}
+
+// FaultTransport is an http.RoundTripper that injects faults into requests
+// sent to a bridge. Faults can be applied with a probability, or scripted so
+// that consecutive matching requests receive a fixed sequence of faults. Set
+// it as the Transport of a hue.Connection. It is safe for concurrent use.
+type FaultTransport struct {
+	// Base sends requests to the bridge. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	mu       sync.Mutex
+	rules    []*faultRule
+	rnd      *rand.Rand
+	injected int
+}
+
+// NewFaultTransport creates a FaultTransport. Probabilistic faults are
+// chosen using the seed, so the same seed produces the same faults for the
+// same sequence of requests.
+func NewFaultTransport(base http.RoundTripper, seed int64) *FaultTransport {
+	return &FaultTransport{
+		Base: base,
+		rnd:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Inject applies the fault to matching requests with the probability, from 0
+// to 1
+func (t *FaultTransport) Inject(match Match, fault Fault, probability float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rules = append(t.rules, &faultRule{match: match, fault: fault, probability: probability})
+}
+
+// Script applies the faults in order to consecutive matching requests. Once all
+// faults have been used, matching requests are no longer disrupted. Use a zero
+// Fault to let a request through.
+func (t *FaultTransport) Script(match Match, faults ...Fault) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rules = append(t.rules, &faultRule{match: match, script: faults})
+}
+
+// Reset removes all faults
+func (t *FaultTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rules = nil
+}
+
+// Injected returns the number of requests that have been disrupted
+func (t *FaultTransport) Injected() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.injected
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fault, ok := t.next(req.Method, apiPath(req.URL.Path))
+	if !ok {
+		return t.base().RoundTrip(req)
+	}
+
+	if fault.Latency > 0 {
+		err := sleep(req.Context(), fault.Latency)
+		if err != nil {
+			closeBody(req)
+			return nil, err
+		}
+	}
+
+	switch {
+	case fault.Refuse:
+		closeBody(req)
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	case fault.StatusCode != 0:
+		closeBody(req)
+		return newResponse(req, fault.StatusCode, []byte(http.StatusText(fault.StatusCode))), nil
+	case fault.ErrorType != 0:
+		closeBody(req)
+		return newResponse(req, http.StatusOK, errorPayload(req, fault)), nil
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case fault.Drop:
+		resp.Body.Close()
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+	case fault.Truncate:
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		body = body[:len(body)/2]
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Del("Content-Length")
+	}
+
+	return resp, nil
+}
+
+// next returns the fault for a request, if any
+func (t *FaultTransport) next(method, apiPath string) (Fault, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, rule := range t.rules {
+		if !rule.match.matches(method, apiPath) {
+			continue
+		}
+
+		if rule.script != nil {
+			if len(rule.script) == 0 {
+				continue
+			}
+
+			fault := rule.script[0]
+			rule.script = rule.script[1:]
+
+			if fault == (Fault{}) {
+				return Fault{}, false
+			}
+
+			t.injected++
+			return fault, true
+		}
+
+		if t.rnd.Float64() < rule.probability {
+			t.injected++
+			return rule.fault, true
+		}
+	}
+
+	return Fault{}, false
+}
+
+func (t *FaultTransport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+
+	return t.Base
+}
+
+// apiPath returns the part of a request path after the username
+func apiPath(urlPath string) string {
+	parts := splitPath(urlPath)
+	if len(parts) < 2 || parts[0] != "api" {
+		return strings.Join(parts, "/")
+	}
+
+	return strings.Join(parts[2:], "/")
+}
+
+// errorPayload creates the response body for a bridge error fault
+// closeBody closes the body of a request that isn't sent, as a RoundTripper
+// must close it even on errors
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+func errorPayload(req *http.Request, fault Fault) []byte {
+	addr := "/" + apiPath(req.URL.Path)
+
+	description := fault.ErrorDescription
+	if description == "" {
+		description = errorDescriptions[fault.ErrorType]
+		if fault.ErrorType == 4 {
+			description = fmt.Sprintf(description, req.Method, addr)
+		}
+	}
+
+	if description == "" {
+		description = fmt.Sprintf("error type %d", fault.ErrorType)
+	}
+
+	data, _ := json.Marshal(errorResponse(fault.ErrorType, addr, description))
+
+	return data
+}
+
+func newResponse(req *http.Request, statusCode int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// sleep pauses for the duration, or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,207 @@
+package huetest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattvella07/hue"
+)
+
+// createFaultConnection creates a server with one light and a Connection to
+// it that sends requests through a FaultTransport
+func createFaultConnection(seed int64) (*Server, *hue.Connection, *FaultTransport) {
+	s := NewServer()
+	s.Bridge.AddLight("Desk", ExtendedColorLight)
+
+	transport := NewFaultTransport(nil, seed)
+	h := s.Connection()
+	h.Transport = transport
+
+	return s, h, transport
+}
+
+func TestScriptedFaults(t *testing.T) {
+	s, h, transport := createFaultConnection(1)
+	defer s.Close()
+
+	transport.Script(Match{Method: "PUT", Path: "lights/*/state"}, ErrorPayload(hue.ErrorDeviceIsOff), Fault{}, ErrorPayload(hue.ErrorInternal))
+
+	err := h.TurnOnLight(1)
+	expectBridgeError(t, err, hue.ErrorDeviceIsOff)
+
+	err = h.TurnOnLight(1)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	err = h.TurnOnLight(1)
+	expectBridgeError(t, err, hue.ErrorInternal)
+
+	// The script is finished
+	err = h.TurnOnLight(1)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	if transport.Injected() != 2 {
+		t.Fatalf("Expected 2 faults, got %d", transport.Injected())
+	}
+}
+
+func TestTransportFaults(t *testing.T) {
+	faults := map[string]Fault{
+		"Status":   Status(http.StatusServiceUnavailable),
+		"Drop":     Drop(),
+		"Refuse":   Refuse(),
+		"Truncate": Truncate(),
+	}
+
+	for name, fault := range faults {
+		t.Run(name, func(t *testing.T) {
+			s, h, transport := createFaultConnection(1)
+			defer s.Close()
+
+			transport.Script(Match{Path: "lights"}, fault)
+
+			_, err := h.GetLights()
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+
+			_, err = h.GetLights()
+			if err != nil {
+				t.Fatalf("Expected no errors after the fault, got %s", err)
+			}
+		})
+	}
+}
+
+// trackedBody is a request body that records whether it was closed
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestFaultsCloseBody(t *testing.T) {
+	faults := map[string]Fault{
+		"Status":       Status(http.StatusServiceUnavailable),
+		"Refuse":       Refuse(),
+		"ErrorPayload": ErrorPayload(hue.ErrorInternal),
+	}
+
+	for name, fault := range faults {
+		t.Run(name, func(t *testing.T) {
+			transport := NewFaultTransport(nil, 1)
+			transport.Script(Match{}, fault)
+
+			body := &trackedBody{Reader: strings.NewReader(`{"on":true}`)}
+			req, err := http.NewRequest("PUT", "http://bridge/api/user/lights/1/state", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, _ := transport.RoundTrip(req)
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			if !body.closed {
+				t.Fatal("Expected the request body to be closed")
+			}
+		})
+	}
+}
+
+func TestLatencyFault(t *testing.T) {
+	s, h, transport := createFaultConnection(1)
+	defer s.Close()
+
+	transport.Inject(Match{}, Delay(50*time.Millisecond), 1)
+
+	start := time.Now()
+
+	_, err := h.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("Expected request to be delayed, took %s", elapsed)
+	}
+}
+
+func TestProbabilisticFaults(t *testing.T) {
+	failures := func(seed int64) []bool {
+		s, h, transport := createFaultConnection(seed)
+		defer s.Close()
+
+		transport.Inject(Match{Method: "GET", Path: "groups"}, Status(http.StatusServiceUnavailable), 0.5)
+
+		results := make([]bool, 100)
+		for i := range results {
+			_, err := h.GetGroups()
+			results[i] = err != nil
+
+			// Requests that don't match are never disrupted
+			_, err = h.GetLights()
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err)
+			}
+		}
+
+		return results
+	}
+
+	first := failures(42)
+	second := failures(42)
+
+	count := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatal("Expected the same seed to produce the same faults")
+		}
+
+		if first[i] {
+			count++
+		}
+	}
+
+	if count < 30 || count > 70 {
+		t.Fatalf("Expected about half of the requests to fail, got %d", count)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	matches := []struct {
+		match    Match
+		method   string
+		path     string
+		expected bool
+	}{
+		{Match{}, "GET", "lights", true},
+		{Match{Method: "PUT"}, "GET", "lights", false},
+		{Match{Path: "lights"}, "PUT", "lights/1/state", true},
+		{Match{Path: "lights/*/state"}, "PUT", "lights/12/state", true},
+		{Match{Path: "lights/*/state"}, "PUT", "groups/1/action", false},
+		{Match{Path: "/groups/0"}, "GET", "groups/0", true},
+		{Match{Path: "groups/0"}, "GET", "groups/01", false},
+	}
+
+	for _, m := range matches {
+		if got := m.match.matches(m.method, m.path); got != m.expected {
+			t.Fatalf("Expected %v for %+v and %s %s, got %v", m.expected, m.match, m.method, m.path, got)
+		}
+	}
+
+	if got := apiPath("/api/user/lights/1/state"); !strings.EqualFold(got, "lights/1/state") {
+		t.Fatalf("Unexpected API path %s", got)
+	}
+}
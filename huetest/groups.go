@@ -0,0 +1,341 @@
+package huetest
+
+import "fmt"
+
+// Group types that can be created through the API. Luminaire and LightSource
+// groups are created by the bridge for multisource lights.
+var creatableGroupTypes = []string{"LightGroup", "Room", "Zone", "Entertainment"}
+
+// RoomClasses are the classes accepted for Room and Zone groups
+var RoomClasses = []string{
+	"Living room", "Kitchen", "Dining", "Bedroom", "Kids bedroom", "Bathroom",
+	"Nursery", "Recreation", "Office", "Gym", "Hallway", "Toilet", "Front door",
+	"Garage", "Terrace", "Garden", "Driveway", "Carport", "Other", "Home",
+	"Downstairs", "Upstairs", "Top floor", "Attic", "Guest room", "Staircase",
+	"Lounge", "Man cave", "Computer", "Studio", "Music", "TV", "Reading",
+	"Closet", "Storage", "Laundry room", "Balcony", "Porch", "Barbecue", "Pool",
+}
+
+// EntertainmentClasses are the classes accepted for Entertainment groups
+var EntertainmentClasses = []string{"TV", "Free"}
+
+// AddGroup adds a group of the specified type containing the lights, and
+// returns its ID. Unlike creating a group through the API, any type can be
+// added, including Luminaire and LightSource.
+func (b *Bridge) AddGroup(name, groupType string, lights []string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID(resourceGroups)
+	b.resources[resourceGroups][id] = newGroup(name, groupType, defaultClass(groupType), lights)
+
+	if groupType == "Room" {
+		b.moveToRoom(id, lights)
+	}
+
+	return id
+}
+
+func newGroup(name, groupType, class string, lights []string) object {
+	group := object{
+		"name":    name,
+		"lights":  interfaceList(lights),
+		"sensors": []interface{}{},
+		"type":    groupType,
+		"recycle": false,
+		"action": object{
+			"on":        false,
+			"bri":       float64(254),
+			"hue":       float64(8417),
+			"sat":       float64(140),
+			"effect":    "none",
+			"xy":        []interface{}{0.4573, 0.41},
+			"ct":        float64(366),
+			"alert":     "none",
+			"colormode": "xy",
+		},
+	}
+
+	if groupType != "LightGroup" {
+		group["class"] = class
+	}
+
+	return group
+}
+
+// createGroup creates a group from a POST request
+func (b *Bridge) createGroup(body object) []interface{} {
+	parts := []string{resourceGroups}
+
+	if _, ok := body["lights"]; !ok {
+		return missingParameters(parts)
+	}
+
+	groupType := "LightGroup"
+	if value, ok := body["type"]; ok {
+		if !isOneOf(value, creatableGroupTypes...) {
+			return []interface{}{invalidValue(parts, "type", value)}
+		}
+		groupType = value.(string)
+	}
+
+	lights, ok := b.validLights(body["lights"])
+	if !ok || (len(lights) == 0 && groupType == "LightGroup") {
+		return []interface{}{invalidValue(parts, "lights", body["lights"])}
+	}
+
+	id := b.nextID(resourceGroups)
+
+	name := fmt.Sprintf("Group %s", id)
+	if value, ok := body["name"]; ok {
+		if !validName(value) {
+			return []interface{}{invalidValue(parts, "name", value)}
+		}
+		name = value.(string)
+	}
+
+	class := defaultClass(groupType)
+	if value, ok := body["class"]; ok && groupType != "LightGroup" {
+		if !isOneOf(value, classes(groupType)...) {
+			return []interface{}{invalidValue(parts, "class", value)}
+		}
+		class = value.(string)
+	}
+
+	b.resources[resourceGroups][id] = newGroup(name, groupType, class, lights)
+
+	// A light can only be in one room, so it's removed from any other room
+	if groupType == "Room" {
+		b.moveToRoom(id, lights)
+	}
+
+	return []interface{}{success(object{"id": id})}
+}
+
+// updateGroup updates a group's attributes
+func (b *Bridge) updateGroup(id string, body object) []interface{} {
+	parts := []string{resourceGroups, id}
+	group := b.resources[resourceGroups][id]
+	groupType, _ := group["type"].(string)
+
+	results := []interface{}{}
+
+	for _, param := range sortedKeys(body) {
+		value := body[param]
+
+		switch param {
+		case "name":
+			if !validName(value) {
+				results = append(results, invalidValue(parts, param, value))
+				continue
+			}
+		case "lights":
+			lights, ok := b.validLights(value)
+			if !ok || groupType == "Luminaire" || groupType == "LightSource" {
+				results = append(results, invalidValue(parts, param, value))
+				continue
+			}
+
+			if groupType == "Room" {
+				b.moveToRoom(id, lights)
+			}
+		case "class":
+			if groupType == "LightGroup" || !isOneOf(value, classes(groupType)...) {
+				results = append(results, invalidValue(parts, param, value))
+				continue
+			}
+		default:
+			if _, ok := group[param]; ok {
+				results = append(results, notModifiable(parts, param))
+			} else {
+				results = append(results, parameterNotAvailable(parts, param))
+			}
+			continue
+		}
+
+		group[param] = copyValue(value)
+		results = append(results, success(object{paramAddress(parts, param): value}))
+	}
+
+	return results
+}
+
+// setGroupAction applies an action to all lights in a group
+func (b *Bridge) setGroupAction(id string, body object) []interface{} {
+	parts := []string{resourceGroups, id, "action"}
+
+	var lights []string
+	var action object
+
+	if id == "0" {
+		lights = b.sortedIDs(resourceLights)
+		action = b.groupZeroAction()
+	} else {
+		group := b.resources[resourceGroups][id]
+		lights = stringList(group["lights"])
+		action = group["action"].(object)
+	}
+
+	results := []interface{}{}
+	valid := object{}
+
+	for _, param := range sortedKeys(body) {
+		value := body[param]
+
+		if param == "scene" {
+			scene, ok := b.resources[resourceScenes][fmt.Sprint(value)]
+			if !ok {
+				results = append(results, invalidValue(parts, param, value))
+				continue
+			}
+
+			b.recallScene(scene, lights)
+			results = append(results, success(object{paramAddress(parts, param): value}))
+			continue
+		}
+
+		if _, ok := stateParams[param]; !ok {
+			results = append(results, parameterNotAvailable(parts, param))
+			continue
+		}
+
+		if !validStateValue(param, value) {
+			results = append(results, invalidValue(parts, param, value))
+			continue
+		}
+
+		valid[param] = value
+		results = append(results, success(object{paramAddress(parts, param): value}))
+	}
+
+	if len(valid) == 0 {
+		return results
+	}
+
+	// Each light applies the parameters it supports. Errors for individual
+	// lights aren't reported for groups.
+	for _, lightID := range lights {
+		if light, ok := b.resources[resourceLights][lightID]; ok {
+			supported := object{}
+			for param, value := range valid {
+				if stateParams[param](light["type"].(string)) {
+					supported[param] = value
+				}
+			}
+
+			b.setLightState(light, []string{resourceLights, lightID, "state"}, supported)
+		}
+	}
+
+	for param, value := range valid {
+		applyStateParam(action, param, value)
+	}
+
+	return results
+}
+
+// groupZero returns the special group containing all lights
+func (b *Bridge) groupZero() object {
+	lights := b.sortedIDs(resourceLights)
+
+	return object{
+		"name":    "Group 0",
+		"lights":  interfaceList(lights),
+		"sensors": []interface{}{},
+		"type":    "LightGroup",
+		"state":   b.groupState(lights),
+		"recycle": false,
+		"action":  copyValue(b.groupZeroAction()),
+	}
+}
+
+// groupZeroAction returns the last action sent to group 0
+func (b *Bridge) groupZeroAction() object {
+	if b.zeroAction == nil {
+		b.zeroAction = newGroup("", "LightGroup", "", nil)["action"].(object)
+	}
+
+	return b.zeroAction
+}
+
+// groupState returns whether all or any of the lights are on
+func (b *Bridge) groupState(lights []string) object {
+	anyOn, allOn := false, len(lights) > 0
+
+	for _, id := range lights {
+		light, ok := b.resources[resourceLights][id]
+		if !ok {
+			continue
+		}
+
+		if on, _ := light["state"].(object)["on"].(bool); on {
+			anyOn = true
+		} else {
+			allOn = false
+		}
+	}
+
+	return object{"all_on": allOn, "any_on": anyOn}
+}
+
+// moveToRoom removes the lights from every room other than the specified one
+func (b *Bridge) moveToRoom(room string, lights []string) {
+	moved := make(map[string]bool, len(lights))
+	for _, light := range lights {
+		moved[light] = true
+	}
+
+	for id, group := range b.resources[resourceGroups] {
+		if id == room || group["type"] != "Room" {
+			continue
+		}
+
+		remaining := []string{}
+		for _, light := range stringList(group["lights"]) {
+			if !moved[light] {
+				remaining = append(remaining, light)
+			}
+		}
+
+		group["lights"] = interfaceList(remaining)
+	}
+}
+
+// validLights returns the light IDs in a list, and whether all of them exist
+func (b *Bridge) validLights(value interface{}) ([]string, bool) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	lights := stringList(value)
+	if len(lights) != len(list) {
+		return nil, false
+	}
+
+	for _, id := range lights {
+		if _, ok := b.resources[resourceLights][id]; !ok {
+			return nil, false
+		}
+	}
+
+	return lights, true
+}
+
+// classes returns the classes accepted for a group type
+func classes(groupType string) []string {
+	if groupType == "Entertainment" {
+		return EntertainmentClasses
+	}
+
+	return RoomClasses
+}
+
+// defaultClass returns the class of a group created without one
+func defaultClass(groupType string) string {
+	if groupType == "Entertainment" {
+		return "TV"
+	}
+
+	return "Other"
+}
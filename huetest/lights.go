@@ -0,0 +1,367 @@
+package huetest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Light types supported by AddLight
+const (
+	ExtendedColorLight    = "Extended color light"
+	ColorLight            = "Color light"
+	ColorTemperatureLight = "Color temperature light"
+	DimmableLight         = "Dimmable light"
+	OnOffPlug             = "On/Off plug-in unit"
+)
+
+// AddLight adds a light of the specified type, as if it had been paired with the
+// bridge, and returns its ID
+func (b *Bridge) AddLight(name, lightType string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID(resourceLights)
+	b.resources[resourceLights][id] = newLight(name, lightType, id)
+
+	return id
+}
+
+// QueueLight adds a light of the specified type that will be found by the next
+// search for new lights
+func (b *Bridge) QueueLight(name, lightType string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.queued[resourceLights] = append(b.queued[resourceLights], object{"name": name, "type": lightType})
+}
+
+// SetLightReachable sets whether the bridge can reach the specified light
+func (b *Bridge) SetLightReachable(id string, reachable bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if light, ok := b.resources[resourceLights][id]; ok {
+		light["state"].(object)["reachable"] = reachable
+	}
+}
+
+func newLight(name, lightType, id string) object {
+	state := object{
+		"on":        false,
+		"alert":     "none",
+		"mode":      "homeautomation",
+		"reachable": true,
+	}
+
+	modelID, productName, archetype := "LWB010", "Hue white lamp", "classicbulb"
+
+	if supportsBri(lightType) {
+		state["bri"] = float64(254)
+	}
+
+	if supportsCT(lightType) {
+		state["ct"] = float64(366)
+		state["colormode"] = "ct"
+		modelID, productName, archetype = "LTW001", "Hue white ambiance lamp", "classicbulb"
+	}
+
+	if supportsColor(lightType) {
+		state["hue"] = float64(8417)
+		state["sat"] = float64(140)
+		state["effect"] = "none"
+		state["xy"] = []interface{}{0.4573, 0.41}
+		state["colormode"] = "xy"
+		modelID, productName, archetype = "LCT016", "Hue color lamp", "sultanbulb"
+	}
+
+	if lightType == OnOffPlug {
+		modelID, productName, archetype = "LOM001", "Hue Smart plug", "plug"
+	}
+
+	return object{
+		"state": state,
+		"swupdate": object{
+			"state":       "noupdates",
+			"lastinstall": "2020-01-01T00:00:00",
+		},
+		"type":             lightType,
+		"name":             name,
+		"modelid":          modelID,
+		"manufacturername": "Signify Netherlands B.V.",
+		"productname":      productName,
+		"capabilities": object{
+			"certified": true,
+			"control": object{
+				"mindimlevel": float64(1000),
+				"maxlumen":    float64(800),
+				"ct":          object{"min": float64(153), "max": float64(500)},
+			},
+			"streaming": object{"renderer": supportsColor(lightType), "proxy": supportsColor(lightType)},
+		},
+		"config": object{
+			"archetype": archetype,
+			"function":  "mixed",
+			"direction": "omnidirectional",
+			"startup":   object{"mode": "safety", "configured": true},
+		},
+		"uniqueid":   uniqueID(id, "0b"),
+		"swversion":  "1.50.2_r30933",
+		"swconfigid": "772B0E5E",
+		"productid":  "Philips-" + modelID,
+	}
+}
+
+// uniqueID creates a unique ID for a device from its ID and endpoint
+func uniqueID(id, endpoint string) string {
+	n, _ := strconv.Atoi(id)
+	return fmt.Sprintf("00:17:88:01:00:%02x:%02x:%02x-%s", n>>16&0xff, n>>8&0xff, n&0xff, endpoint)
+}
+
+func supportsBri(lightType string) bool {
+	return lightType != OnOffPlug
+}
+
+func supportsCT(lightType string) bool {
+	return lightType == ExtendedColorLight || lightType == ColorTemperatureLight
+}
+
+func supportsColor(lightType string) bool {
+	return lightType == ExtendedColorLight || lightType == ColorLight
+}
+
+// stateParams are the parameters that can be set on a light's state, and the
+// light types supporting them
+var stateParams = map[string]func(lightType string) bool{
+	"on":             func(string) bool { return true },
+	"alert":          func(string) bool { return true },
+	"transitiontime": func(string) bool { return true },
+	"bri":            supportsBri,
+	"bri_inc":        supportsBri,
+	"ct":             supportsCT,
+	"ct_inc":         supportsCT,
+	"hue":            supportsColor,
+	"hue_inc":        supportsColor,
+	"sat":            supportsColor,
+	"sat_inc":        supportsColor,
+	"xy":             supportsColor,
+	"xy_inc":         supportsColor,
+	"effect":         supportsColor,
+}
+
+// validStateValue returns true if the value is valid for the state parameter
+func validStateValue(param string, value interface{}) bool {
+	switch param {
+	case "on":
+		_, ok := value.(bool)
+		return ok
+	case "bri":
+		return isIntInRange(value, 1, 254)
+	case "hue":
+		return isIntInRange(value, 0, 65535)
+	case "sat":
+		return isIntInRange(value, 0, 254)
+	case "ct":
+		return isIntInRange(value, 153, 500)
+	case "transitiontime":
+		return isIntInRange(value, 0, 65535)
+	case "bri_inc", "sat_inc":
+		return isIntInRange(value, -254, 254)
+	case "hue_inc", "ct_inc":
+		return isIntInRange(value, -65534, 65534)
+	case "xy":
+		return isPair(value, 0, 1)
+	case "xy_inc":
+		return isPair(value, -0.5, 0.5)
+	case "alert":
+		return isOneOf(value, "none", "select", "lselect")
+	case "effect":
+		return isOneOf(value, "none", "colorloop")
+	}
+
+	return false
+}
+
+// setLightState applies a state change to a light and returns the result for
+// each parameter. Parameters other than on, alert, and transitiontime can't be
+// changed while the light is off.
+func (b *Bridge) setLightState(light object, parts []string, body object) []interface{} {
+	lightType, _ := light["type"].(string)
+	state := light["state"].(object)
+
+	results := []interface{}{}
+	valid := object{}
+
+	for _, param := range sortedKeys(body) {
+		value := body[param]
+
+		supported, ok := stateParams[param]
+		if !ok || !supported(lightType) {
+			results = append(results, parameterNotAvailable(parts, param))
+			continue
+		}
+
+		if !validStateValue(param, value) {
+			results = append(results, invalidValue(parts, param, value))
+			continue
+		}
+
+		valid[param] = value
+	}
+
+	on, _ := state["on"].(bool)
+	if newOn, ok := valid["on"].(bool); ok {
+		on = newOn
+	}
+
+	for _, param := range sortedKeys(valid) {
+		value := valid[param]
+
+		if !on && param != "on" && param != "alert" && param != "transitiontime" {
+			results = append(results, deviceIsOff(parts, param))
+			continue
+		}
+
+		applyStateParam(state, param, value)
+		results = append(results, success(object{paramAddress(parts, param): value}))
+	}
+
+	return results
+}
+
+// applyStateParam sets a single validated parameter on a light's state
+func applyStateParam(state object, param string, value interface{}) {
+	switch param {
+	case "transitiontime":
+		// Transitions happen instantly
+	case "alert":
+		// Alerts finish instantly
+		state["alert"] = "none"
+	case "bri_inc":
+		state["bri"] = clamp(number(state["bri"])+number(value), 1, 254)
+	case "sat_inc":
+		state["sat"] = clamp(number(state["sat"])+number(value), 0, 254)
+		state["colormode"] = "hs"
+	case "hue_inc":
+		state["hue"] = math.Mod(number(state["hue"])+number(value)+65536, 65536)
+		state["colormode"] = "hs"
+	case "ct_inc":
+		state["ct"] = clamp(number(state["ct"])+number(value), 153, 500)
+		state["colormode"] = "ct"
+	case "xy_inc":
+		xy, _ := state["xy"].([]interface{})
+		inc := value.([]interface{})
+		if len(xy) == 2 {
+			state["xy"] = []interface{}{
+				clamp(number(xy[0])+number(inc[0]), 0, 1),
+				clamp(number(xy[1])+number(inc[1]), 0, 1),
+			}
+		}
+		state["colormode"] = "xy"
+	default:
+		state[param] = copyValue(value)
+
+		switch param {
+		case "xy":
+			state["colormode"] = "xy"
+		case "ct":
+			state["colormode"] = "ct"
+		case "hue", "sat":
+			state["colormode"] = "hs"
+		}
+	}
+}
+
+// search finds all queued devices of the specified type
+func (b *Bridge) search(resource string) []interface{} {
+	found := []string{}
+
+	for _, device := range b.queued[resource] {
+		id := b.nextID(resource)
+		name, _ := device["name"].(string)
+		deviceType, _ := device["type"].(string)
+
+		if resource == resourceLights {
+			b.resources[resource][id] = newLight(name, deviceType, id)
+		} else {
+			b.resources[resource][id] = newSensor(name, deviceType, id, b.now())
+		}
+
+		found = append(found, id)
+	}
+
+	b.queued[resource] = nil
+	b.found[resource] = found
+	b.lastScan[resource] = b.now()
+
+	return []interface{}{success(object{"/" + resource: "Searching for new devices"})}
+}
+
+// newDevices returns the devices found by the last search
+func (b *Bridge) newDevices(resource string) object {
+	devices := object{"lastscan": b.lastScan[resource]}
+
+	for _, id := range b.found[resource] {
+		if device, ok := b.resources[resource][id]; ok {
+			devices[id] = object{"name": device["name"]}
+		}
+	}
+
+	return devices
+}
+
+func isIntInRange(value interface{}, min, max float64) bool {
+	n, ok := value.(float64)
+	return ok && n == math.Trunc(n) && n >= min && n <= max
+}
+
+func isPair(value interface{}, min, max float64) bool {
+	list, ok := value.([]interface{})
+	if !ok || len(list) != 2 {
+		return false
+	}
+
+	for _, item := range list {
+		n, ok := item.(float64)
+		if !ok || n < min || n > max {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isOneOf(value interface{}, options ...string) bool {
+	s, ok := value.(string)
+	if !ok {
+		return false
+	}
+
+	for _, option := range options {
+		if s == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+func number(value interface{}) float64 {
+	n, _ := value.(float64)
+	return n
+}
+
+func clamp(value, min, max float64) float64 {
+	return math.Max(min, math.Min(max, value))
+}
+
+func sortedKeys(obj object) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
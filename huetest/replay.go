@@ -0,0 +1,455 @@
+package huetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces the username of the recording Connection in fixtures.
+// Other usernames, such as those in the whitelist, are replaced with
+// Placeholder followed by a number.
+const Placeholder = "USERNAME"
+
+// Fixture contains recorded requests and responses
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request with usernames replaced by placeholders
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response with usernames replaced by placeholders.
+// Bodies that aren't JSON are stored as a JSON string with Text set.
+type RecordedResponse struct {
+	StatusCode int             `json:"status"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	Text       bool            `json:"text,omitempty"`
+}
+
+// LoadFixture reads a fixture file
+func LoadFixture(file string) (Fixture, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return Fixture{}, err
+	}
+
+	fixture := Fixture{}
+
+	err = json.Unmarshal(data, &fixture)
+	if err != nil {
+		return Fixture{}, err
+	}
+
+	return fixture, nil
+}
+
+// Save writes the fixture to a file
+func (f Fixture) Save(file string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// RecordingTransport is an http.RoundTripper that records every request and
+// response it sends to a bridge. Usernames are scrubbed from the recording so
+// it can be shared. Set it as the Transport of a hue.Connection and call Save
+// when finished. It is safe for concurrent use.
+type RecordingTransport struct {
+	// Base sends requests to the bridge. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	usernames    map[string]string
+}
+
+// NewRecordingTransport creates a RecordingTransport
+func NewRecordingTransport(base http.RoundTripper) *RecordingTransport {
+	return &RecordingTransport{Base: base, usernames: make(map[string]string)}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	t.record(req.Method, req.URL.Path, reqBody, resp.StatusCode, respBody)
+
+	return resp, nil
+}
+
+func (t *RecordingTransport) record(method, urlPath string, reqBody []byte, statusCode int, respBody []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parts := splitPath(urlPath)
+	if len(parts) >= 2 && parts[0] == "api" {
+		t.addUsername(parts[1])
+	}
+
+	t.addUsernames(respBody)
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: method,
+			Path:   t.scrub(urlPath),
+		},
+		Response: RecordedResponse{
+			StatusCode: statusCode,
+		},
+	}
+
+	if len(reqBody) > 0 {
+		interaction.Request.Body, _ = rawJSON([]byte(t.scrub(string(reqBody))))
+	}
+
+	if len(respBody) > 0 {
+		interaction.Response.Body, interaction.Response.Text = rawJSON([]byte(t.scrub(string(respBody))))
+	}
+
+	t.interactions = append(t.interactions, interaction)
+}
+
+// addUsername assigns a placeholder to a username
+func (t *RecordingTransport) addUsername(username string) {
+	if _, ok := t.usernames[username]; ok || username == "" {
+		return
+	}
+
+	if len(t.usernames) == 0 {
+		t.usernames[username] = Placeholder
+		return
+	}
+
+	t.usernames[username] = fmt.Sprintf("%s%d", Placeholder, len(t.usernames)+1)
+}
+
+// addUsernames finds usernames in a response body. Usernames appear as keys of
+// the whitelist and in the response to creating a user.
+func (t *RecordingTransport) addUsernames(body []byte) {
+	var decoded interface{}
+	if json.Unmarshal(body, &decoded) != nil {
+		return
+	}
+
+	found := []string{}
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if whitelist, ok := v["whitelist"].(map[string]interface{}); ok {
+				for username := range whitelist {
+					found = append(found, username)
+				}
+			}
+
+			if username, ok := v["username"].(string); ok {
+				found = append(found, username)
+			}
+
+			for _, item := range v {
+				walk(item)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(decoded)
+
+	// Sort so placeholders are assigned in the same order for every recording
+	sort.Strings(found)
+	for _, username := range found {
+		t.addUsername(username)
+	}
+}
+
+// scrub replaces all known usernames with their placeholders
+func (t *RecordingTransport) scrub(s string) string {
+	// Replace longer usernames first in case one contains another
+	usernames := make([]string, 0, len(t.usernames))
+	for username := range t.usernames {
+		usernames = append(usernames, username)
+	}
+	sort.Slice(usernames, func(i, j int) bool {
+		return len(usernames[i]) > len(usernames[j])
+	})
+
+	for _, username := range usernames {
+		s = strings.Replace(s, username, t.usernames[username], -1)
+	}
+
+	return s
+}
+
+// Fixture returns everything recorded so far
+func (t *RecordingTransport) Fixture() Fixture {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	interactions := make([]Interaction, len(t.interactions))
+	copy(interactions, t.interactions)
+
+	return Fixture{Interactions: interactions}
+}
+
+// Save writes everything recorded so far to a fixture file
+func (t *RecordingTransport) Save(file string) error {
+	return t.Fixture().Save(file)
+}
+
+// ReplayMode controls how a ReplayTransport matches requests to the fixture
+type ReplayMode int
+
+// Matching modes for a ReplayTransport
+const (
+	// Strict requires requests to be sent in the recorded order, with the
+	// same method, path, and body
+	Strict ReplayMode = iota
+
+	// Lenient matches each request to the first unused interaction with the
+	// same method and path, ignoring the order and body. Once all matching
+	// interactions are used, the last one is repeated.
+	Lenient
+)
+
+// ReplayTransport is an http.RoundTripper that responds to requests with the
+// responses in a fixture, without contacting a bridge. Requests with any
+// username match the recording, and the username is restored in responses.
+// It is safe for concurrent use.
+type ReplayTransport struct {
+	fixture Fixture
+	mode    ReplayMode
+
+	mu   sync.Mutex
+	used []bool
+	next int
+}
+
+// NewReplayTransport creates a ReplayTransport for the fixture using the
+// matching mode, either Strict or Lenient
+func NewReplayTransport(fixture Fixture, mode ReplayMode) *ReplayTransport {
+	return &ReplayTransport{
+		fixture: fixture,
+		mode:    mode,
+		used:    make([]bool, len(fixture.Interactions)),
+	}
+}
+
+// LoadReplayTransport creates a ReplayTransport for a fixture file
+func LoadReplayTransport(file string, mode ReplayMode) (*ReplayTransport, error) {
+	fixture, err := LoadFixture(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReplayTransport(fixture, mode), nil
+}
+
+// Remaining returns the number of interactions that haven't been replayed
+func (t *ReplayTransport) Remaining() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	remaining := 0
+	for _, used := range t.used {
+		if !used {
+			remaining++
+		}
+	}
+
+	return remaining
+}
+
+// RoundTrip implements http.RoundTripper. Requests that don't match the
+// fixture fail with an error.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	username := ""
+	urlPath := req.URL.Path
+
+	parts := splitPath(urlPath)
+	if len(parts) >= 2 && parts[0] == "api" {
+		username = parts[1]
+		parts[1] = Placeholder
+		urlPath = "/" + strings.Join(parts, "/")
+	}
+
+	if username != "" {
+		reqBody = []byte(strings.Replace(string(reqBody), username, Placeholder, -1))
+	}
+
+	interaction, err := t.match(req.Method, urlPath, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	body := []byte(interaction.Response.Body)
+	if interaction.Response.Text {
+		text := ""
+		json.Unmarshal(body, &text)
+		body = []byte(text)
+	}
+
+	if username != "" {
+		body = restoreUsername(body, username)
+	}
+
+	return newResponse(req, interaction.Response.StatusCode, body), nil
+}
+
+// match finds the interaction for a request
+func (t *ReplayTransport) match(method, urlPath string, body []byte) (Interaction, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.mode == Strict {
+		if t.next >= len(t.fixture.Interactions) {
+			return Interaction{}, fmt.Errorf("huetest: unexpected request %s %s: all %d interactions have been replayed", method, urlPath, len(t.fixture.Interactions))
+		}
+
+		interaction := t.fixture.Interactions[t.next]
+		recorded := interaction.Request
+
+		if recorded.Method != method || recorded.Path != urlPath || !equalBodies(recorded.Body, body) {
+			return Interaction{}, fmt.Errorf("huetest: request %d is %s %s %s, expected %s %s %s", t.next+1, method, urlPath, body, recorded.Method, recorded.Path, recorded.Body)
+		}
+
+		t.used[t.next] = true
+		t.next++
+
+		return interaction, nil
+	}
+
+	last := -1
+	for i, interaction := range t.fixture.Interactions {
+		if interaction.Request.Method != method || interaction.Request.Path != urlPath {
+			continue
+		}
+
+		if !t.used[i] {
+			t.used[i] = true
+			return interaction, nil
+		}
+
+		last = i
+	}
+
+	if last == -1 {
+		return Interaction{}, fmt.Errorf("huetest: no recorded interaction for %s %s", method, urlPath)
+	}
+
+	return t.fixture.Interactions[last], nil
+}
+
+// rawJSON returns the data if it's valid JSON, otherwise the data encoded as a
+// JSON string and true
+func rawJSON(data []byte) (json.RawMessage, bool) {
+	if json.Valid(data) {
+		return json.RawMessage(data), false
+	}
+
+	encoded, _ := json.Marshal(string(data))
+
+	return json.RawMessage(encoded), true
+}
+
+// equalBodies returns true if the bodies are the same, ignoring formatting
+func equalBodies(recorded json.RawMessage, body []byte) bool {
+	if len(recorded) == 0 || len(body) == 0 {
+		return len(recorded) == len(body)
+	}
+
+	var a, b interface{}
+	if json.Unmarshal(recorded, &a) != nil || json.Unmarshal(body, &b) != nil {
+		return bytes.Equal(recorded, body)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// restoreUsername replaces the placeholder for the recording Connection's
+// username, but not the numbered placeholders for other users
+func restoreUsername(body []byte, username string) []byte {
+	s := string(body)
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, Placeholder)
+		if i == -1 {
+			b.WriteString(s)
+			break
+		}
+
+		b.WriteString(s[:i])
+		rest := s[i+len(Placeholder):]
+
+		if len(rest) > 0 && rest[0] >= '0' && rest[0] <= '9' {
+			b.WriteString(Placeholder)
+		} else {
+			b.WriteString(username)
+		}
+
+		s = rest
+	}
+
+	return []byte(b.String())
+}
@@ -0,0 +1,190 @@
+package huetest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mattvella07/hue"
+)
+
+// recordFixture records a session against a server and saves it to a file
+func recordFixture(t *testing.T) (string, []hue.Light, string) {
+	s := NewServer()
+	defer s.Close()
+
+	s.Bridge.AddLight("Desk", ExtendedColorLight)
+	s.Bridge.AddLight("Hall", DimmableLight)
+	other := s.Bridge.AddUser("huetest#other")
+
+	recorder := NewRecordingTransport(nil)
+	h := s.Connection()
+	h.Transport = recorder
+
+	_, err := h.GetConfiguration()
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	err = h.TurnOnLight(1)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	lights, err := h.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "fixture.json")
+
+	err = recorder.Save(file)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	for _, username := range []string{s.Username, other} {
+		if strings.Contains(string(data), username) {
+			t.Fatalf("Expected username %s to be scrubbed from the fixture", username)
+		}
+	}
+
+	return file, lights, s.Address()
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	file, lights, address := recordFixture(t)
+
+	fixture, err := LoadFixture(file)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	// Turning on the light checks that it exists first
+	if len(fixture.Interactions) != 4 {
+		t.Fatalf("Expected 4 interactions, got %d", len(fixture.Interactions))
+	}
+
+	if path := fixture.Interactions[2].Request.Path; path != "/api/USERNAME/lights/1/state" {
+		t.Fatalf("Unexpected path %s", path)
+	}
+
+	replay := NewReplayTransport(fixture, Strict)
+	h := &hue.Connection{UserID: "replayuser", Address: address, Transport: replay}
+
+	config, err := h.GetConfiguration()
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	ids := []string{}
+	for _, user := range config.Whitelist {
+		ids = append(ids, user.ID)
+	}
+
+	// The username is restored, and other users keep their placeholder
+	if !reflect.DeepEqual(ids, []string{"USERNAME2", "replayuser"}) {
+		t.Fatalf("Unexpected whitelist %v", ids)
+	}
+
+	err = h.TurnOnLight(1)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	replayed, err := h.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	if !reflect.DeepEqual(lights, replayed) {
+		t.Fatalf("Expected %+v, got %+v", lights, replayed)
+	}
+
+	if replay.Remaining() != 0 {
+		t.Fatalf("Expected all interactions to be replayed, %d remaining", replay.Remaining())
+	}
+
+	// Strict replay fails once the fixture is used up
+	_, err = h.GetLights()
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestStrictReplay(t *testing.T) {
+	file, _, address := recordFixture(t)
+
+	replay, err := LoadReplayTransport(file, Strict)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	h := &hue.Connection{UserID: "replayuser", Address: address, Transport: replay}
+
+	// Requests must be sent in the recorded order
+	_, err = h.GetLights()
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	replay, _ = LoadReplayTransport(file, Strict)
+	h.Transport = replay
+
+	_, err = h.GetConfiguration()
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	// Requests must have the recorded body
+	err = h.TurnOffLight(1)
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestLenientReplay(t *testing.T) {
+	file, lights, address := recordFixture(t)
+
+	replay, err := LoadReplayTransport(file, Lenient)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	h := &hue.Connection{UserID: "replayuser", Address: address, Transport: replay}
+
+	// Requests may be sent in any order, and are repeated
+	for i := 0; i < 2; i++ {
+		replayed, err := h.GetLights()
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		if !reflect.DeepEqual(lights, replayed) {
+			t.Fatalf("Expected %+v, got %+v", lights, replayed)
+		}
+	}
+
+	// The body is ignored
+	err = h.TurnOffLight(1)
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	// Requests that weren't recorded fail
+	_, err = h.GetGroups()
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	if replay.Remaining() != 1 {
+		t.Fatalf("Expected 1 interaction remaining, got %d", replay.Remaining())
+	}
+}
@@ -0,0 +1,408 @@
+package huetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// maxNameLength is the longest name the bridge accepts for any resource
+const maxNameLength = 32
+
+// create handles a POST to a resource type
+func (b *Bridge) create(resource, username string, body object) interface{} {
+	switch resource {
+	case resourceLights:
+		return b.search(resourceLights)
+	case resourceSensors:
+		if len(body) == 0 {
+			return b.search(resourceSensors)
+		}
+		return b.createSensor(body)
+	case resourceGroups:
+		return b.createGroup(body)
+	case resourceScenes:
+		return b.createScene(body, username)
+	case resourceSchedules:
+		return b.createSchedule(body)
+	case resourceRules:
+		return b.createRule(body, username)
+	case resourceResourceLinks:
+		return b.createResourceLink(body, username)
+	}
+
+	return methodNotAvailable("POST", []string{resource})
+}
+
+// update handles a PUT to a single resource
+func (b *Bridge) update(resource, id string, body object) interface{} {
+	parts := []string{resource, id}
+
+	if resource == resourceGroups && id == "0" {
+		results := []interface{}{}
+		for _, param := range sortedKeys(body) {
+			results = append(results, notModifiable(parts, param))
+		}
+		return results
+	}
+
+	if _, ok := b.resources[resource][id]; !ok {
+		return resourceNotAvailable(parts)
+	}
+
+	if len(body) == 0 {
+		return missingParameters(parts)
+	}
+
+	switch resource {
+	case resourceGroups:
+		return b.updateGroup(id, body)
+	case resourceScenes:
+		return b.updateScene(id, body)
+	}
+
+	return b.updateAttributes(resource, id, body)
+}
+
+// writableAttributes are the attributes that can be changed with a PUT to a
+// resource, and a function that validates their value. Groups and scenes are
+// handled separately.
+var writableAttributes = map[string]map[string]func(value interface{}) bool{
+	resourceLights: {
+		"name": validName,
+	},
+	resourceSchedules: {
+		"name":        validName,
+		"description": isString,
+		"command":     validCommand,
+		"localtime":   isString,
+		"time":        isString,
+		"status":      func(value interface{}) bool { return isOneOf(value, "enabled", "disabled") },
+		"autodelete":  isBool,
+		"recycle":     isBool,
+	},
+	resourceRules: {
+		"name":       validName,
+		"status":     func(value interface{}) bool { return isOneOf(value, "enabled", "disabled") },
+		"conditions": isNonEmptyList,
+		"actions":    isNonEmptyList,
+	},
+	resourceSensors: {
+		"name": validName,
+	},
+	resourceResourceLinks: {
+		"name":        validName,
+		"description": isString,
+		"links":       isList,
+		"recycle":     isBool,
+	},
+}
+
+// updateAttributes sets the writable attributes of a resource
+func (b *Bridge) updateAttributes(resource, id string, body object) []interface{} {
+	parts := []string{resource, id}
+	obj := b.resources[resource][id]
+
+	results := []interface{}{}
+
+	for _, param := range sortedKeys(body) {
+		value := body[param]
+
+		valid, ok := writableAttributes[resource][param]
+		switch {
+		case !ok:
+			if _, exists := obj[param]; exists {
+				results = append(results, notModifiable(parts, param))
+			} else {
+				results = append(results, parameterNotAvailable(parts, param))
+			}
+		case !valid(value):
+			results = append(results, invalidValue(parts, param, value))
+		default:
+			obj[param] = copyValue(value)
+			results = append(results, success(object{paramAddress(parts, param): value}))
+		}
+	}
+
+	return results
+}
+
+// updateChild handles a PUT to the state, action, or config of a resource
+func (b *Bridge) updateChild(resource, id, child string, body object) interface{} {
+	parts := []string{resource, id, child}
+
+	if resource == resourceGroups && id == "0" && child == "action" {
+		return b.setGroupAction(id, body)
+	}
+
+	obj, ok := b.resources[resource][id]
+	if !ok {
+		return resourceNotAvailable(parts[:2])
+	}
+
+	if len(body) == 0 {
+		return missingParameters(parts)
+	}
+
+	switch {
+	case resource == resourceLights && child == "state":
+		return b.setLightState(obj, parts, body)
+	case resource == resourceLights && child == "config":
+		return b.updateLightConfig(obj, parts, body)
+	case resource == resourceGroups && child == "action":
+		return b.setGroupAction(id, body)
+	case resource == resourceSensors && (child == "state" || child == "config"):
+		return b.updateSensorChild(obj, parts, body)
+	}
+
+	return resourceNotAvailable(parts)
+}
+
+// updateLightConfig sets the startup behavior of a light
+func (b *Bridge) updateLightConfig(light object, parts []string, body object) []interface{} {
+	config := light["config"].(object)
+	results := []interface{}{}
+
+	for _, param := range sortedKeys(body) {
+		value := body[param]
+
+		if param != "startup" {
+			if _, ok := config[param]; ok {
+				results = append(results, notModifiable(parts, param))
+			} else {
+				results = append(results, parameterNotAvailable(parts, param))
+			}
+			continue
+		}
+
+		startup, ok := value.(object)
+		if !ok || !isOneOf(startup["mode"], "safety", "powerfail", "custom", "lastonstate") {
+			results = append(results, invalidValue(parts, param, value))
+			continue
+		}
+
+		stored := object{"mode": startup["mode"], "configured": true}
+		if custom, ok := startup["customsettings"]; ok {
+			stored["customsettings"] = copyValue(custom)
+		}
+
+		config[param] = stored
+		results = append(results, success(object{paramAddress(parts, param): value}))
+	}
+
+	return results
+}
+
+// delete handles a DELETE of a single resource. Deleted lights are removed
+// from all groups and scenes.
+func (b *Bridge) delete(resource, id string) interface{} {
+	parts := []string{resource, id}
+
+	obj, ok := b.resources[resource][id]
+	if !ok {
+		return resourceNotAvailable(parts)
+	}
+
+	if resource == resourceGroups && (obj["type"] == "Luminaire" || obj["type"] == "LightSource") {
+		return methodNotAvailable("DELETE", parts)
+	}
+
+	delete(b.resources[resource], id)
+
+	if resource == resourceLights {
+		for _, group := range b.resources[resourceGroups] {
+			group["lights"] = interfaceList(without(stringList(group["lights"]), id))
+		}
+
+		for _, scene := range b.resources[resourceScenes] {
+			scene["lights"] = interfaceList(without(stringList(scene["lights"]), id))
+			delete(scene["lightstates"].(object), id)
+		}
+	}
+
+	return []interface{}{success(fmt.Sprintf("%s deleted", address(parts)))}
+}
+
+// createSchedule creates a schedule from a POST request
+func (b *Bridge) createSchedule(body object) []interface{} {
+	parts := []string{resourceSchedules}
+
+	_, hasTime := body["time"]
+	_, hasLocalTime := body["localtime"]
+	if _, ok := body["command"]; !ok || (!hasTime && !hasLocalTime) {
+		return missingParameters(parts)
+	}
+
+	schedule := object{
+		"name":        "schedule",
+		"description": "",
+		"status":      "enabled",
+		"autodelete":  true,
+		"created":     b.now(),
+	}
+
+	for _, param := range sortedKeys(body) {
+		valid, ok := writableAttributes[resourceSchedules][param]
+		if !ok {
+			return []interface{}{parameterNotAvailable(parts, param)}
+		}
+
+		if !valid(body[param]) {
+			return []interface{}{invalidValue(parts, param, body[param])}
+		}
+
+		schedule[param] = copyValue(body[param])
+	}
+
+	// The bridge reports both forms of the time
+	if hasLocalTime && !hasTime {
+		schedule["time"] = schedule["localtime"]
+	}
+	if hasTime && !hasLocalTime {
+		schedule["localtime"] = schedule["time"]
+	}
+
+	id := b.nextID(resourceSchedules)
+	b.resources[resourceSchedules][id] = schedule
+
+	return []interface{}{success(object{"id": id})}
+}
+
+// createRule creates a rule from a POST request
+func (b *Bridge) createRule(body object, username string) []interface{} {
+	parts := []string{resourceRules}
+
+	if !isNonEmptyList(body["conditions"]) || !isNonEmptyList(body["actions"]) {
+		return missingParameters(parts)
+	}
+
+	rule := object{
+		"name":           "rule",
+		"owner":          username,
+		"created":        b.now(),
+		"lasttriggered":  "none",
+		"timestriggered": float64(0),
+		"status":         "enabled",
+		"recycle":        false,
+	}
+
+	for _, param := range sortedKeys(body) {
+		if param == "recycle" {
+			rule[param] = body[param]
+			continue
+		}
+
+		valid, ok := writableAttributes[resourceRules][param]
+		if !ok {
+			return []interface{}{parameterNotAvailable(parts, param)}
+		}
+
+		if !valid(body[param]) {
+			return []interface{}{invalidValue(parts, param, body[param])}
+		}
+
+		rule[param] = copyValue(body[param])
+	}
+
+	id := b.nextID(resourceRules)
+	b.resources[resourceRules][id] = rule
+
+	return []interface{}{success(object{"id": id})}
+}
+
+// createResourceLink creates a resourcelink from a POST request
+func (b *Bridge) createResourceLink(body object, username string) []interface{} {
+	parts := []string{resourceResourceLinks}
+
+	if !validName(body["name"]) {
+		return missingParameters(parts)
+	}
+
+	if !isList(body["links"]) {
+		return missingParameters(parts)
+	}
+
+	link := object{
+		"name":        body["name"],
+		"description": "",
+		"type":        "Link",
+		"classid":     float64(1),
+		"owner":       username,
+		"recycle":     false,
+		"links":       copyValue(body["links"]),
+	}
+
+	for _, param := range []string{"description", "classid", "recycle"} {
+		if value, ok := body[param]; ok {
+			link[param] = value
+		}
+	}
+
+	id := b.nextID(resourceResourceLinks)
+	b.resources[resourceResourceLinks][id] = link
+
+	return []interface{}{success(object{"id": id})}
+}
+
+func validName(value interface{}) bool {
+	name, ok := value.(string)
+	return ok && name != "" && utf8.RuneCountInString(name) <= maxNameLength
+}
+
+func validCommand(value interface{}) bool {
+	command, ok := value.(object)
+	if !ok {
+		return false
+	}
+
+	_, hasAddress := command["address"].(string)
+	_, hasMethod := command["method"].(string)
+
+	return hasAddress && hasMethod
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+func isBool(value interface{}) bool {
+	_, ok := value.(bool)
+	return ok
+}
+
+func isList(value interface{}) bool {
+	_, ok := value.([]interface{})
+	return ok
+}
+
+func isNonEmptyList(value interface{}) bool {
+	list, ok := value.([]interface{})
+	return ok && len(list) > 0
+}
+
+func without(ids []string, id string) []string {
+	remaining := []string{}
+	for _, item := range ids {
+		if item != id {
+			remaining = append(remaining, item)
+		}
+	}
+
+	return remaining
+}
+
+// normalizeValue converts a Go value to the form it has when decoded from JSON
+func normalizeValue(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	if json.Unmarshal(data, &normalized) != nil {
+		return value
+	}
+
+	return normalized
+}
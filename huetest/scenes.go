@@ -0,0 +1,243 @@
+package huetest
+
+import "fmt"
+
+// sceneStateParams are the parameters that can be stored for a light in a scene
+var sceneStateParams = []string{"on", "bri", "hue", "sat", "xy", "ct", "effect", "transitiontime"}
+
+// createScene creates a scene from a POST request. The current state of each
+// light is stored in the scene.
+func (b *Bridge) createScene(body object, username string) []interface{} {
+	parts := []string{resourceScenes}
+
+	if _, ok := body["name"]; !ok {
+		return missingParameters(parts)
+	}
+
+	if !validName(body["name"]) {
+		return []interface{}{invalidValue(parts, "name", body["name"])}
+	}
+
+	sceneType := "LightScene"
+	if value, ok := body["type"]; ok {
+		if !isOneOf(value, "LightScene", "GroupScene") {
+			return []interface{}{invalidValue(parts, "type", value)}
+		}
+		sceneType = value.(string)
+	}
+
+	scene := object{
+		"name":        body["name"],
+		"type":        sceneType,
+		"owner":       username,
+		"recycle":     false,
+		"locked":      false,
+		"appdata":     object{},
+		"picture":     "",
+		"lastupdated": b.now(),
+		"version":     float64(2),
+	}
+
+	var lights []string
+
+	if sceneType == "GroupScene" {
+		groupID, ok := body["group"].(string)
+		if !ok {
+			return missingParameters(parts)
+		}
+
+		group, ok := b.resources[resourceGroups][groupID]
+		if !ok {
+			return []interface{}{invalidValue(parts, "group", body["group"])}
+		}
+
+		scene["group"] = groupID
+		lights = stringList(group["lights"])
+	} else {
+		if _, ok := body["lights"]; !ok {
+			return missingParameters(parts)
+		}
+
+		var ok bool
+		lights, ok = b.validLights(body["lights"])
+		if !ok || len(lights) == 0 {
+			return []interface{}{invalidValue(parts, "lights", body["lights"])}
+		}
+	}
+
+	for _, param := range []string{"recycle", "appdata", "picture"} {
+		if value, ok := body[param]; ok {
+			scene[param] = copyValue(value)
+		}
+	}
+
+	scene["lights"] = interfaceList(lights)
+	scene["lightstates"] = b.captureLightStates(lights)
+
+	b.nextScene++
+	id := fmt.Sprintf("huetest%08d", b.nextScene)
+	b.resources[resourceScenes][id] = scene
+
+	return []interface{}{success(object{"id": id})}
+}
+
+// updateScene updates a scene's attributes
+func (b *Bridge) updateScene(id string, body object) []interface{} {
+	parts := []string{resourceScenes, id}
+	scene := b.resources[resourceScenes][id]
+
+	results := []interface{}{}
+
+	for _, param := range sortedKeys(body) {
+		value := body[param]
+
+		switch param {
+		case "name":
+			if !validName(value) {
+				results = append(results, invalidValue(parts, param, value))
+				continue
+			}
+			scene[param] = value
+		case "lights":
+			lights, ok := b.validLights(value)
+			if !ok || len(lights) == 0 || scene["type"] == "GroupScene" {
+				results = append(results, invalidValue(parts, param, value))
+				continue
+			}
+
+			// Keep the stored states of lights that remain in the scene
+			states := b.captureLightStates(lights)
+			for light, state := range scene["lightstates"].(object) {
+				if _, ok := states[light]; ok {
+					states[light] = state
+				}
+			}
+
+			scene["lights"] = interfaceList(lights)
+			scene["lightstates"] = states
+		case "storelightstate":
+			if _, ok := value.(bool); !ok {
+				results = append(results, invalidValue(parts, param, value))
+				continue
+			}
+
+			if value == true {
+				scene["lightstates"] = b.captureLightStates(stringList(scene["lights"]))
+			}
+		case "recycle", "appdata", "picture":
+			scene[param] = copyValue(value)
+		default:
+			if _, ok := scene[param]; ok {
+				results = append(results, notModifiable(parts, param))
+			} else {
+				results = append(results, parameterNotAvailable(parts, param))
+			}
+			continue
+		}
+
+		scene["lastupdated"] = b.now()
+		results = append(results, success(object{paramAddress(parts, param): value}))
+	}
+
+	return results
+}
+
+// updateSceneLightState changes the state stored for a light in a scene
+func (b *Bridge) updateSceneLightState(id, light string, body object) []interface{} {
+	parts := []string{resourceScenes, id, "lightstates", light}
+
+	scene, ok := b.resources[resourceScenes][id]
+	if !ok {
+		return resourceNotAvailable(parts[:2])
+	}
+
+	state, ok := scene["lightstates"].(object)[light].(object)
+	if !ok {
+		return resourceNotAvailable(parts)
+	}
+
+	results := []interface{}{}
+
+	for _, param := range sortedKeys(body) {
+		value := body[param]
+
+		if !isOneOf(param, sceneStateParams...) {
+			results = append(results, parameterNotAvailable(parts, param))
+			continue
+		}
+
+		if !validStateValue(param, value) {
+			results = append(results, invalidValue(parts, param, value))
+			continue
+		}
+
+		// A light state uses a single color mode
+		switch param {
+		case "xy":
+			delete(state, "ct")
+		case "ct":
+			delete(state, "xy")
+		}
+
+		state[param] = copyValue(value)
+		results = append(results, success(object{paramAddress(parts, param): value}))
+	}
+
+	scene["lastupdated"] = b.now()
+
+	return results
+}
+
+// captureLightStates returns the current state of each light as stored in a
+// scene
+func (b *Bridge) captureLightStates(lights []string) object {
+	states := object{}
+
+	for _, id := range lights {
+		light, ok := b.resources[resourceLights][id]
+		if !ok {
+			continue
+		}
+
+		current := light["state"].(object)
+		state := object{"on": current["on"]}
+
+		if bri, ok := current["bri"]; ok {
+			state["bri"] = bri
+		}
+
+		switch current["colormode"] {
+		case "xy", "hs":
+			state["xy"] = copyValue(current["xy"])
+		case "ct":
+			state["ct"] = current["ct"]
+		}
+
+		states[id] = state
+	}
+
+	return states
+}
+
+// recallScene applies the scene's stored light states to the lights in the
+// scene that are also in the specified lights
+func (b *Bridge) recallScene(scene object, lights []string) {
+	inGroup := make(map[string]bool, len(lights))
+	for _, id := range lights {
+		inGroup[id] = true
+	}
+
+	for id, state := range scene["lightstates"].(object) {
+		light, ok := b.resources[resourceLights][id]
+		if !ok || !inGroup[id] {
+			continue
+		}
+
+		lightState := light["state"].(object)
+		for param, value := range state.(object) {
+			if param != "transitiontime" && stateParams[param](light["type"].(string)) {
+				applyStateParam(lightState, param, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package huetest
+
+import "strings"
+
+// Sensor types supported by AddSensor, in addition to any CLIP sensor type
+const (
+	PresenceSensor    = "ZLLPresence"
+	TemperatureSensor = "ZLLTemperature"
+	LightLevelSensor  = "ZLLLightLevel"
+	SwitchSensor      = "ZLLSwitch"
+	DaylightSensor    = "Daylight"
+)
+
+// AddSensor adds a sensor of the specified type, as if it had been paired with
+// the bridge, and returns its ID
+func (b *Bridge) AddSensor(name, sensorType string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID(resourceSensors)
+	b.resources[resourceSensors][id] = newSensor(name, sensorType, id, b.now())
+
+	return id
+}
+
+// QueueSensor adds a sensor of the specified type that will be found by the
+// next search for new sensors
+func (b *Bridge) QueueSensor(name, sensorType string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.queued[resourceSensors] = append(b.queued[resourceSensors], object{"name": name, "type": sensorType})
+}
+
+// SetSensorState changes the state of the specified sensor, as if the sensor
+// had detected a change. Temperatures are in hundredths of a degree Celsius.
+func (b *Bridge) SetSensorState(id string, state map[string]interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sensor, ok := b.resources[resourceSensors][id]
+	if !ok {
+		return
+	}
+
+	current := sensor["state"].(object)
+	for key, value := range state {
+		current[key] = normalizeValue(value)
+	}
+	current["lastupdated"] = b.now()
+}
+
+func newSensor(name, sensorType, id, now string) object {
+	state := object{"lastupdated": now}
+	config := object{"on": true, "reachable": true}
+
+	manufacturer, modelID := "Signify Netherlands B.V.", "SML001"
+
+	switch sensorType {
+	case PresenceSensor, "CLIPPresence":
+		state["presence"] = false
+		config["sensitivity"] = float64(2)
+		config["sensitivitymax"] = float64(2)
+	case TemperatureSensor, "CLIPTemperature":
+		state["temperature"] = float64(2100)
+	case LightLevelSensor, "CLIPLightLevel":
+		state["lightlevel"] = float64(12000)
+		state["dark"] = false
+		state["daylight"] = true
+		config["tholddark"] = float64(16000)
+		config["tholdoffset"] = float64(7000)
+	case SwitchSensor, "CLIPSwitch":
+		state["buttonevent"] = float64(1002)
+		modelID = "RWL021"
+	case DaylightSensor:
+		state["daylight"] = false
+		delete(config, "reachable")
+		config["configured"] = false
+		config["sunriseoffset"] = float64(30)
+		config["sunsetoffset"] = float64(-30)
+		modelID = "PHDL00"
+	case "CLIPGenericStatus":
+		state["status"] = float64(0)
+	case "CLIPGenericFlag":
+		state["flag"] = false
+	case "CLIPOpenClose":
+		state["open"] = false
+	case "CLIPHumidity":
+		state["humidity"] = float64(0)
+	}
+
+	if strings.HasPrefix(sensorType, "ZLL") {
+		config["battery"] = float64(100)
+	}
+
+	sensor := object{
+		"state":            state,
+		"config":           config,
+		"name":             name,
+		"type":             sensorType,
+		"modelid":          modelID,
+		"manufacturername": manufacturer,
+		"swversion":        "6.1.1.27575",
+		"recycle":          false,
+	}
+
+	if sensorType != DaylightSensor {
+		sensor["uniqueid"] = uniqueID(id, "02-0406")
+	}
+
+	return sensor
+}
+
+// createSensor creates a CLIP sensor
+func (b *Bridge) createSensor(body object) []interface{} {
+	parts := []string{resourceSensors}
+
+	for _, param := range []string{"name", "type", "modelid", "swversion", "uniqueid", "manufacturername"} {
+		if _, ok := body[param].(string); !ok {
+			return missingParameters(parts)
+		}
+	}
+
+	sensorType := body["type"].(string)
+	if !strings.HasPrefix(sensorType, "CLIP") {
+		return []interface{}{invalidValue(parts, "type", sensorType)}
+	}
+
+	if !validName(body["name"]) {
+		return []interface{}{invalidValue(parts, "name", body["name"])}
+	}
+
+	id := b.nextID(resourceSensors)
+	sensor := newSensor(body["name"].(string), sensorType, id, b.now())
+
+	for _, param := range []string{"modelid", "swversion", "uniqueid", "manufacturername", "recycle"} {
+		if value, ok := body[param]; ok {
+			sensor[param] = value
+		}
+	}
+
+	for _, child := range []string{"state", "config"} {
+		values, _ := body[child].(object)
+		for key, value := range values {
+			sensor[child].(object)[key] = value
+		}
+	}
+
+	b.resources[resourceSensors][id] = sensor
+
+	return []interface{}{success(object{"id": id})}
+}
+
+// updateSensorChild updates the state or config of a sensor. Only CLIP sensors
+// can have their state changed through the API.
+func (b *Bridge) updateSensorChild(sensor object, parts []string, body object) []interface{} {
+	child := parts[2]
+	current := sensor[child].(object)
+	sensorType, _ := sensor["type"].(string)
+
+	results := []interface{}{}
+	changed := false
+
+	for _, param := range sortedKeys(body) {
+		value := body[param]
+
+		existing, ok := current[param]
+		switch {
+		case !ok || param == "lastupdated":
+			results = append(results, parameterNotAvailable(parts, param))
+		case (child == "state" && !strings.HasPrefix(sensorType, "CLIP")) || param == "reachable":
+			results = append(results, notModifiable(parts, param))
+		case !sameKind(existing, value):
+			results = append(results, invalidValue(parts, param, value))
+		default:
+			current[param] = value
+			changed = true
+			results = append(results, success(object{paramAddress(parts, param): value}))
+		}
+	}
+
+	if child == "state" && changed {
+		current["lastupdated"] = b.now()
+	}
+
+	return results
+}
+
+// sameKind returns true if both values decode from the same kind of JSON value
+func sameKind(a, b interface{}) bool {
+	switch a.(type) {
+	case bool:
+		_, ok := b.(bool)
+		return ok
+	case float64:
+		_, ok := b.(float64)
+		return ok
+	case string:
+		_, ok := b.(string)
+		return ok
+	}
+
+	return true
+}
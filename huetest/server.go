@@ -0,0 +1,54 @@
+package huetest
+
+import (
+	"net/http/httptest"
+	"strings"
+
+	"github.com/mattvella07/hue"
+)
+
+// Server is a Bridge served over HTTP on a local address, with a whitelisted
+// user
+type Server struct {
+	Bridge *Bridge
+
+	// URL is the base URL of the server, of the form http://ipaddr:port
+	URL string
+
+	// Username is a user in the bridge's whitelist
+	Username string
+
+	server *httptest.Server
+}
+
+// NewServer starts a Server with a new, empty Bridge. The caller should call
+// Close when finished, to shut it down.
+func NewServer() *Server {
+	bridge := NewBridge()
+	server := httptest.NewServer(bridge)
+
+	return &Server{
+		Bridge:   bridge,
+		URL:      server.URL,
+		Username: bridge.AddUser("huetest#server"),
+		server:   server,
+	}
+}
+
+// Address returns the host and port of the server
+func (s *Server) Address() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// Connection creates a Connection to the server using its whitelisted user
+func (s *Server) Connection() *hue.Connection {
+	return &hue.Connection{
+		UserID:  s.Username,
+		Address: s.Address(),
+	}
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
@@ -6,15 +6,41 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
 )
 
-// Connection contains important connection info
+// Connection contains important connection info. A Connection is safe for
+// concurrent use by multiple goroutines and must not be copied after first use.
+//
+// The bridge is discovered the first time the Connection is used. If the bridge
+// later can't be reached, for example because DHCP gave it a new address, it's
+// discovered again. A new address is only used after its /config reports the
+// same bridge ID, so commands are never sent to the wrong bridge.
 type Connection struct {
-	discoveryResponse []hueDiscoveryResponse
+	UserID string
+
+	// BridgeID is the ID of the bridge to connect to. If empty, the first bridge
+	// found is used and its ID is remembered for later discoveries.
+	BridgeID string
+
+	// Address is the host and optional port of the bridge. If set, discovery is
+	// skipped and the bridge is never discovered again.
+	Address string
+
+	// Transport sends all HTTP requests, including discovery. If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	mu                sync.RWMutex
 	internalIPAddress string
-	UserID            string
 	baseURL           string
+	bridgeID          string
+	discoveryURL      string
 	isInitialized     bool
+	limiter           rateLimiter
+	interceptors      []Interceptor
+	metrics           Metrics
 }
 
 type hueDiscoveryResponse struct {
@@ -22,49 +48,202 @@ type hueDiscoveryResponse struct {
 	InternalIPAddress string `json:"internalipaddress"`
 }
 
+// discoveredBridge is a bridge found by discoverBridge
+type discoveredBridge struct {
+	address string
+	baseURL string
+	id      string
+}
+
+type bridgeIDResponse struct {
+	BridgeID string `json:"bridgeid"`
+}
+
 const hueDiscoveryURL = "https://discovery.meethue.com/"
 
-func (h *Connection) initializeHue() error {
+// initializeHue discovers the bridge if it hasn't been already and returns the
+// base URL for all requests
+func (h *Connection) initializeHue() (string, error) {
+	h.mu.RLock()
 	if h.isInitialized {
-		return nil
+		baseURL := h.baseURL
+		h.mu.RUnlock()
+		return baseURL, nil
 	}
+	h.mu.RUnlock()
 
-	err := h.getBridgeIPAddress()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Another goroutine may have finished discovery while waiting for the lock
+	if h.isInitialized {
+		return h.baseURL, nil
+	}
+
+	if h.Address != "" {
+		h.internalIPAddress = h.Address
+		h.baseURL = fmt.Sprintf("http://%s/api/%s", h.Address, h.UserID)
+		h.isInitialized = true
+
+		return h.baseURL, nil
+	}
+
+	bridge, err := h.discoverBridge(h.discoveryURL, h.expectedBridgeID())
 	if err != nil {
-		return fmt.Errorf("GetBridgeIPAddress Error: %s", err)
+		return "", fmt.Errorf("%w: %w", ErrDiscovery, err)
 	}
+	h.useBridge(bridge)
 
-	h.getBaseURL()
+	return h.baseURL, nil
+}
 
-	h.isInitialized = true
+// GetBridgeIPAddress gets the address of the bridge, discovering it if needed
+func (h *Connection) GetBridgeIPAddress() (string, error) {
+	_, err := h.initializeHue()
+	if err != nil {
+		return "", err
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 
-	return nil
+	return h.internalIPAddress, nil
 }
 
-func (h *Connection) getBridgeIPAddress() error {
-	resp, err := http.Get(hueDiscoveryURL)
+// rediscover discovers the bridge again after failedURL couldn't be reached and
+// returns the new base URL
+func (h *Connection) rediscover(failedURL string) (string, error) {
+	h.mu.RLock()
+	baseURL := h.baseURL
+	discoveryURL := h.discoveryURL
+	expectedID := h.expectedBridgeID()
+	h.mu.RUnlock()
+
+	// Another goroutine may have already found the bridge's new address
+	if baseURL != failedURL {
+		return baseURL, nil
+	}
+
+	// Discover without the lock so other requests aren't held up by it
+	bridge, err := h.discoverBridge(discoveryURL, expectedID)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("%w: %w", ErrDiscovery, err)
 	}
 
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Keep the address another goroutine found while this one was discovering
+	if h.baseURL != failedURL {
+		return h.baseURL, nil
+	}
+	h.useBridge(bridge)
+
+	return h.baseURL, nil
+}
+
+// expectedBridgeID returns the ID the discovered bridge must have, if any. The
+// caller must hold the lock.
+func (h *Connection) expectedBridgeID() string {
+	if h.BridgeID != "" {
+		return h.BridgeID
+	}
+
+	return h.bridgeID
+}
+
+// useBridge sends all further requests to the bridge. The caller must hold the
+// write lock.
+func (h *Connection) useBridge(bridge discoveredBridge) {
+	h.internalIPAddress = bridge.address
+	h.baseURL = bridge.baseURL
+	h.bridgeID = bridge.id
+	h.isInitialized = true
+}
+
+// discoverBridge finds the bridge with the expected ID, or the first bridge if
+// expectedID is empty, without changing the Connection
+func (h *Connection) discoverBridge(discoveryURL, expectedID string) (discoveredBridge, error) {
+	if discoveryURL == "" {
+		discoveryURL = hueDiscoveryURL
+	}
+
+	resp, err := h.client().Get(discoveryURL)
+	if err != nil {
+		return discoveredBridge{}, err
+	}
+	defer resp.Body.Close()
+
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return discoveredBridge{}, err
+	}
+
+	discoveryResponse := []hueDiscoveryResponse{}
+
+	err = json.Unmarshal(body, &discoveryResponse)
+	if err != nil {
+		return discoveredBridge{}, err
+	}
+
+	if len(discoveryResponse) == 0 {
+		return discoveredBridge{}, errors.New("Unable to determine Hue bridge internal IP address")
+	}
+
+	for _, bridge := range discoveryResponse {
+		baseURL := fmt.Sprintf("http://%s/api/%s", bridge.InternalIPAddress, h.UserID)
+
+		if expectedID != "" {
+			if !strings.EqualFold(bridge.ID, expectedID) {
+				continue
+			}
+
+			// Make sure the bridge at this address really is the expected one
+			// before sending it any commands
+			actualID, err := h.getBridgeID(baseURL)
+			if err != nil || !strings.EqualFold(actualID, expectedID) {
+				continue
+			}
+		}
+
+		return discoveredBridge{
+			address: bridge.InternalIPAddress,
+			baseURL: baseURL,
+			id:      bridge.ID,
+		}, nil
+	}
+
+	return discoveredBridge{}, fmt.Errorf("Unable to find Hue bridge %s", expectedID)
+}
+
+// getBridgeID gets the ID the bridge at the base URL reports in its configuration
+func (h *Connection) getBridgeID(baseURL string) (string, error) {
+	resp, err := h.client().Get(fmt.Sprintf("%s/config", baseURL))
+	if err != nil {
+		return "", err
 	}
+	defer resp.Body.Close()
 
-	err = json.Unmarshal(body, &h.discoveryResponse)
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	if len(h.discoveryResponse) == 0 {
-		return errors.New("Unable to determine Hue bridge internal IP address")
+	config := bridgeIDResponse{}
+
+	err = json.Unmarshal(body, &config)
+	if err != nil {
+		return "", err
 	}
 
-	h.internalIPAddress = h.discoveryResponse[0].InternalIPAddress
-	return nil
+	return config.BridgeID, nil
 }
 
-func (h *Connection) getBaseURL() {
-	h.baseURL = fmt.Sprintf("http://%s/api/%s", h.internalIPAddress, h.UserID)
+// client returns the HTTP client used for all requests
+func (h *Connection) client() *http.Client {
+	if h.Transport == nil {
+		return http.DefaultClient
+	}
+
+	return &http.Client{Transport: h.Transport}
 }
@@ -0,0 +1,274 @@
+package hue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createBridgeServer creates a test bridge that reports the specified bridge ID
+func createBridgeServer(bridgeID string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/config") {
+			w.Write([]byte(fmt.Sprintf("{\"bridgeid\": %q}", bridgeID)))
+			return
+		}
+
+		data := generateTestData(strings.TrimPrefix(r.URL.Path, "/api/TEST"))
+		if data == nil {
+			return
+		}
+
+		returnData, _ := json.Marshal(data)
+		w.Write(returnData)
+	}))
+}
+
+// createDiscoveryServer creates a test discovery server. The bridges it returns
+// can be changed while the server is running.
+func createDiscoveryServer() (*httptest.Server, func(bridges ...hueDiscoveryResponse)) {
+	var mu sync.Mutex
+	current := []hueDiscoveryResponse{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		returnData, _ := json.Marshal(current)
+		w.Write(returnData)
+	}))
+
+	return server, func(bridges ...hueDiscoveryResponse) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		current = bridges
+	}
+}
+
+func bridgeAddress(server *httptest.Server) string {
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestConcurrentInitialization(t *testing.T) {
+	bridge := createBridgeServer("001788FFFE000001")
+	defer bridge.Close()
+
+	discovery, setBridges := createDiscoveryServer()
+	defer discovery.Close()
+	setBridges(hueDiscoveryResponse{ID: "001788fffe000001", InternalIPAddress: bridgeAddress(bridge)})
+
+	h := &Connection{UserID: "TEST", discoveryURL: discovery.URL}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := h.GetLights()
+			if err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	if h.bridgeID != "001788fffe000001" {
+		t.Fatalf("Expected bridge ID to be remembered, got %q", h.bridgeID)
+	}
+}
+
+func TestRediscovery(t *testing.T) {
+	oldBridge := createBridgeServer("001788FFFE000001")
+
+	discovery, setBridges := createDiscoveryServer()
+	defer discovery.Close()
+	setBridges(hueDiscoveryResponse{ID: "001788fffe000001", InternalIPAddress: bridgeAddress(oldBridge)})
+
+	h := &Connection{UserID: "TEST", discoveryURL: discovery.URL}
+
+	_, err := h.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	// The bridge gets a new address
+	oldBridge.Close()
+
+	newBridge := createBridgeServer("001788FFFE000001")
+	defer newBridge.Close()
+	setBridges(hueDiscoveryResponse{ID: "001788fffe000001", InternalIPAddress: bridgeAddress(newBridge)})
+
+	_, err = h.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no errors after rediscovery, got %s", err)
+	}
+
+	if h.internalIPAddress != bridgeAddress(newBridge) {
+		t.Fatalf("Expected new address %s, got %s", bridgeAddress(newBridge), h.internalIPAddress)
+	}
+}
+
+func TestRediscoveryWrongBridge(t *testing.T) {
+	oldBridge := createBridgeServer("001788FFFE000001")
+
+	discovery, setBridges := createDiscoveryServer()
+	defer discovery.Close()
+	setBridges(hueDiscoveryResponse{ID: "001788fffe000001", InternalIPAddress: bridgeAddress(oldBridge)})
+
+	h := &Connection{UserID: "TEST", discoveryURL: discovery.URL}
+
+	_, err := h.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	oldBridge.Close()
+
+	// A different bridge claims to be the original one
+	otherBridge := createBridgeServer("001788FFFE000002")
+	defer otherBridge.Close()
+	setBridges(hueDiscoveryResponse{ID: "001788fffe000001", InternalIPAddress: bridgeAddress(otherBridge)})
+
+	_, err = h.GetLights()
+	if err == nil {
+		t.Fatal("Expected error when the bridge at the new address has a different ID")
+	}
+}
+
+func TestExpectedBridgeID(t *testing.T) {
+	first := createBridgeServer("001788FFFE000001")
+	defer first.Close()
+
+	second := createBridgeServer("001788FFFE000002")
+	defer second.Close()
+
+	discovery, setBridges := createDiscoveryServer()
+	defer discovery.Close()
+	setBridges(
+		hueDiscoveryResponse{ID: "001788fffe000001", InternalIPAddress: bridgeAddress(first)},
+		hueDiscoveryResponse{ID: "001788fffe000002", InternalIPAddress: bridgeAddress(second)},
+	)
+
+	h := &Connection{UserID: "TEST", BridgeID: "001788FFFE000002", discoveryURL: discovery.URL}
+
+	_, err := h.GetLights()
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	if h.internalIPAddress != bridgeAddress(second) {
+		t.Fatalf("Expected address %s, got %s", bridgeAddress(second), h.internalIPAddress)
+	}
+
+	h = &Connection{UserID: "TEST", BridgeID: "001788FFFE000003", discoveryURL: discovery.URL}
+
+	_, err = h.GetLights()
+	if err == nil {
+		t.Fatal("Expected error when the bridge isn't found")
+	}
+
+	if !errors.Is(err, ErrDiscovery) {
+		t.Fatalf("Expected a discovery error, got %s", err)
+	}
+}
+
+func TestRediscoveryDoesNotBlock(t *testing.T) {
+	bridge := createBridgeServer("001788FFFE000001")
+	defer bridge.Close()
+
+	release := make(chan struct{})
+	discovery := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+
+		returnData, _ := json.Marshal([]hueDiscoveryResponse{{ID: "001788fffe000001", InternalIPAddress: bridgeAddress(bridge)}})
+		w.Write(returnData)
+	}))
+	defer discovery.Close()
+
+	failedURL := "http://127.0.0.1:1/api/TEST"
+	h := &Connection{UserID: "TEST", bridgeID: "001788FFFE000001", discoveryURL: discovery.URL}
+	h.baseURL = failedURL
+	h.isInitialized = true
+
+	rediscovered := make(chan error, 1)
+	go func() {
+		_, err := h.rediscover(failedURL)
+		rediscovered <- err
+	}()
+
+	// Other requests get the current address while discovery is still running
+	initialized := make(chan struct{})
+	go func() {
+		h.initializeHue()
+		close(initialized)
+	}()
+
+	select {
+	case <-initialized:
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatalf("Expected requests not to wait for rediscovery")
+	}
+
+	close(release)
+
+	err := <-rediscovered
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	if h.internalIPAddress != bridgeAddress(bridge) {
+		t.Fatalf("Expected new address %s, got %s", bridgeAddress(bridge), h.internalIPAddress)
+	}
+}
+
+func TestGetBridgeIPAddress(t *testing.T) {
+	bridge := createBridgeServer("001788FFFE000001")
+	defer bridge.Close()
+
+	discovery, setBridges := createDiscoveryServer()
+	defer discovery.Close()
+	setBridges(hueDiscoveryResponse{ID: "001788fffe000001", InternalIPAddress: bridgeAddress(bridge)})
+
+	t.Run("Discovered", func(t *testing.T) {
+		h := &Connection{UserID: "TEST", discoveryURL: discovery.URL}
+
+		address, err := h.GetBridgeIPAddress()
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		if address != bridgeAddress(bridge) {
+			t.Fatalf("Expected %s, got %s", bridgeAddress(bridge), address)
+		}
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		h := &Connection{UserID: "TEST", Address: "192.168.1.2:8080", discoveryURL: discovery.URL}
+
+		address, err := h.GetBridgeIPAddress()
+		if err != nil {
+			t.Fatalf("Expected no errors, got %s", err)
+		}
+
+		if address != "192.168.1.2:8080" {
+			t.Fatalf("Expected 192.168.1.2:8080, got %s", address)
+		}
+	})
+}
@@ -1,14 +1,35 @@
 package hue
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+// Alert values supported by the Phillips Hue API
+const (
+	AlertNone    = "none"
+	AlertSelect  = "select"
+	AlertLSelect = "lselect"
+)
+
+// Startup modes supported by the Phillips Hue API. These control the state a
+// light powers on to, for example after a power outage.
+const (
+	StartupSafety      = "safety"
+	StartupPowerfail   = "powerfail"
+	StartupCustom      = "custom"
+	StartupLastOnState = "lastonstate"
+)
+
+// StartupModes contains every startup mode the Phillips Hue API accepts. The
+// bridge doesn't report which of them an individual light supports.
+var StartupModes = []string{StartupSafety, StartupPowerfail, StartupCustom, StartupLastOnState}
+
 type lightState struct {
 	On        bool      `json:"on"`
 	Bri       int       `json:"bri"`
@@ -52,10 +73,34 @@ type lightCapabilities struct {
 	Streaming lightCapabilitiesStreaming `json:"streaming"`
 }
 
+// LightStartupSettings contains the state a Phillips Hue light powers on
+// to when its startup mode is custom
+type LightStartupSettings struct {
+	Bri int       `json:"bri,omitempty"`
+	XY  []float32 `json:"xy,omitempty"`
+	CT  int       `json:"ct,omitempty"`
+}
+
+type lightStartup struct {
+	Mode           string               `json:"mode"`
+	Configured     bool                 `json:"configured"`
+	CustomSettings LightStartupSettings `json:"customsettings"`
+}
+
 type lightConfig struct {
-	ArcheType string `json:"archetype"`
-	Function  string `json:"function"`
-	Direction string `json:"direction"`
+	ArcheType string       `json:"archetype"`
+	Function  string       `json:"function"`
+	Direction string       `json:"direction"`
+	Startup   lightStartup `json:"startup"`
+}
+
+type lightStartupRequest struct {
+	Mode           string                `json:"mode"`
+	CustomSettings *LightStartupSettings `json:"customsettings,omitempty"`
+}
+
+type lightConfigRequest struct {
+	Startup lightStartupRequest `json:"startup"`
 }
 
 // Light contains all data returned from the Phillips Hue API
@@ -90,6 +135,36 @@ type NewLightResponse struct {
 	LastScan  string     `json:"lastScan"`
 }
 
+type lightSearchRequest struct {
+	DeviceID []string `json:"deviceid"`
+}
+
+type colorStateRequest struct {
+	On  bool      `json:"on"`
+	XY  []float32 `json:"xy"`
+	Bri int       `json:"bri"`
+	Hue int       `json:"hue"`
+	Sat int       `json:"sat"`
+}
+
+// LightStateUpdate contains the changes to make to the state of a light. Fields
+// left nil or empty aren't changed.
+type LightStateUpdate struct {
+	On             *bool     `json:"on,omitempty"`
+	Bri            *int      `json:"bri,omitempty"`
+	Hue            *int      `json:"hue,omitempty"`
+	Sat            *int      `json:"sat,omitempty"`
+	XY             []float32 `json:"xy,omitempty"`
+	CT             *int      `json:"ct,omitempty"`
+	Effect         string    `json:"effect,omitempty"`
+	Alert          string    `json:"alert,omitempty"`
+	TransitionTime *int      `json:"transitiontime,omitempty"`
+}
+
+type alertRequest struct {
+	Alert string `json:"alert"`
+}
+
 // GetLights gets all Phillips Hue lights connected to current bridge
 func (h *Connection) GetLights() ([]Light, error) {
 	data, err := h.get("lights")
@@ -101,31 +176,17 @@ func (h *Connection) GetLights() ([]Light, error) {
 		return []Light{}, nil
 	}
 
-	// Create map to store JSON response
-	fullResponse := make(map[string]interface{})
+	// Decode directly into a map of IDs to lights
+	fullResponse := make(map[string]Light)
 
 	err = json.Unmarshal(data, &fullResponse)
 	if err != nil {
 		return []Light{}, err
 	}
 
-	allLights := []Light{}
-
-	// Loop through all keys in the map and Unmarshal into
-	// Group type
-	for key, val := range fullResponse {
-		light := Light{}
-
-		l, err := json.Marshal(val)
-		if err != nil {
-			return []Light{}, err
-		}
-
-		err = json.Unmarshal(l, &light)
-		if err != nil {
-			return []Light{}, err
-		}
+	allLights := make([]Light, 0, len(fullResponse))
 
+	for key, light := range fullResponse {
 		id, err := strconv.Atoi(key)
 		if err != nil {
 			return []Light{}, err
@@ -136,6 +197,11 @@ func (h *Connection) GetLights() ([]Light, error) {
 		allLights = append(allLights, light)
 	}
 
+	// Sort by ID so the order is the same for every call
+	sort.Slice(allLights, func(i, j int) bool {
+		return allLights[i].ID < allLights[j].ID
+	})
+
 	return allLights, nil
 }
 
@@ -151,8 +217,9 @@ func (h *Connection) GetNewLights() (NewLightResponse, error) {
 		return NewLightResponse{}, nil
 	}
 
-	// Create map to store JSON response
-	fullResponse := make(map[string]interface{})
+	// Decode each entry only once. Every key is the ID of a new light except
+	// for lastscan.
+	fullResponse := make(map[string]json.RawMessage)
 
 	err = json.Unmarshal(data, &fullResponse)
 	if err != nil {
@@ -161,20 +228,16 @@ func (h *Connection) GetNewLights() (NewLightResponse, error) {
 
 	newLightRes := NewLightResponse{}
 
-	// Loop through all keys in the map and Unmarshal into
-	// Group type
 	for key, val := range fullResponse {
 		if key == "lastscan" {
-			newLightRes.LastScan = val.(string)
-		} else {
-			newLight := NewLight{}
-
-			l, err := json.Marshal(val)
+			err = json.Unmarshal(val, &newLightRes.LastScan)
 			if err != nil {
 				return NewLightResponse{}, err
 			}
+		} else {
+			newLight := NewLight{}
 
-			err = json.Unmarshal(l, &newLight)
+			err = json.Unmarshal(val, &newLight)
 			if err != nil {
 				return NewLightResponse{}, err
 			}
@@ -190,18 +253,49 @@ func (h *Connection) GetNewLights() (NewLightResponse, error) {
 		}
 	}
 
+	// Sort by ID so the order is the same for every call
+	sort.Slice(newLightRes.NewLights, func(i, j int) bool {
+		return newLightRes.NewLights[i].ID < newLightRes.NewLights[j].ID
+	})
+
 	return newLightRes, nil
 }
 
 // FindNewLights finds new Phillips Hue lights that have been added since
 // the last time performing this call
 func (h *Connection) FindNewLights() error {
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/lights", h.baseURL), nil)
+	err := h.execute("POST", "lights", nil)
 	if err != nil {
 		return err
 	}
 
-	err = h.execute(req)
+	return nil
+}
+
+// SearchLightsBySerial searches for the Phillips Hue lights with the specified
+// serial numbers, for example lights that were reset or paired with another
+// bridge. Up to 10 serial numbers can be searched for at once.
+func (h *Connection) SearchLightsBySerial(serials []string) error {
+	// Error checking
+	if len(serials) == 0 {
+		return errors.New("Serials must not be empty")
+	}
+
+	if len(serials) > maxSearchSerials {
+		return fmt.Errorf("Serials must contain at most %d serial numbers", maxSearchSerials)
+	}
+
+	deviceIDs := make([]string, len(serials))
+	for i, serial := range serials {
+		serial = strings.ToUpper(strings.Trim(serial, " "))
+		if !isSerial(serial) {
+			return fmt.Errorf("Invalid serial number: %s", serials[i])
+		}
+
+		deviceIDs[i] = serial
+	}
+
+	err := h.execute("POST", "lights", lightSearchRequest{DeviceID: deviceIDs})
 	if err != nil {
 		return err
 	}
@@ -209,6 +303,26 @@ func (h *Connection) FindNewLights() error {
 	return nil
 }
 
+// WaitForLightSearch blocks until the search started by FindNewLights or
+// SearchLightsBySerial completes, then returns the lights that were found
+func (h *Connection) WaitForLightSearch(ctx context.Context) (NewLightResponse, error) {
+	for {
+		newLights, err := h.GetNewLights()
+		if err != nil {
+			return NewLightResponse{}, err
+		}
+
+		if newLights.LastScan != scanActive {
+			return newLights, nil
+		}
+
+		err = sleepContext(ctx, searchPollInterval)
+		if err != nil {
+			return NewLightResponse{}, err
+		}
+	}
+}
+
 // GetLight gets the specified Phillips Hue light
 func (h *Connection) GetLight(light int) (Light, error) {
 	data, err := h.get(fmt.Sprintf("lights/%d", light))
@@ -242,13 +356,7 @@ func (h *Connection) RenameLight(light int, name string) error {
 		return errors.New("Name must not be empty")
 	}
 
-	reqBody := strings.NewReader(fmt.Sprintf("{ \"name\": \"%s\" }", name))
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/lights/%d", h.baseURL, light), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("PUT", fmt.Sprintf("lights/%d", light), nameRequest{Name: name})
 	if err != nil {
 		return err
 	}
@@ -264,9 +372,7 @@ func (h *Connection) TurnOnLight(light int) error {
 	}
 
 	// Set state
-	state := "{\"on\": true}"
-
-	err := h.changeLightState(light, state)
+	err := h.changeLightState(light, onRequest{On: true})
 	if err != nil {
 		return err
 	}
@@ -288,7 +394,13 @@ func (h *Connection) TurnOnLightWithColor(light int, x, y float32, bri, hue, sat
 	}
 
 	// Set state
-	state := fmt.Sprintf("{\"on\": true, \"xy\": [%f, %f], \"bri\": %d, \"hue\": %d, \"sat\": %d}", x, y, bri, hue, sat)
+	state := colorStateRequest{
+		On:  true,
+		XY:  []float32{x, y},
+		Bri: bri,
+		Hue: hue,
+		Sat: sat,
+	}
 
 	err = h.changeLightState(light, state)
 	if err != nil {
@@ -306,9 +418,38 @@ func (h *Connection) TurnOffLight(light int) error {
 	}
 
 	// Set state
-	state := "{\"on\": false}"
+	err := h.changeLightState(light, onRequest{On: false})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
 
-	err := h.changeLightState(light, state)
+// SetLightState changes the state of the specified Phillips Hue light. Only the
+// fields set in the update are changed.
+func (h *Connection) SetLightState(light int, update LightStateUpdate) error {
+	// Error checking
+	err := validateGroupAction(GroupActionUpdate{
+		On:             update.On,
+		Bri:            update.Bri,
+		Hue:            update.Hue,
+		Sat:            update.Sat,
+		XY:             update.XY,
+		CT:             update.CT,
+		Effect:         update.Effect,
+		Alert:          update.Alert,
+		TransitionTime: update.TransitionTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !h.doesLightExist(light) {
+		return fmt.Errorf("Light %d not found", light)
+	}
+
+	err = h.changeLightState(light, update)
 	if err != nil {
 		return err
 	}
@@ -316,19 +457,91 @@ func (h *Connection) TurnOffLight(light int) error {
 	return nil
 }
 
-// DeleteLight deletes a Phillips Hue light from the bridge
-func (h *Connection) DeleteLight(light int) error {
+// IdentifyLight sends an alert to the specified Phillips Hue light so it can be
+// identified. Use AlertSelect for a single breathe cycle, AlertLSelect to breathe
+// for 15 seconds, or AlertNone to cancel an alert.
+func (h *Connection) IdentifyLight(light int, alert string) error {
 	// Error checking
 	if !h.doesLightExist(light) {
 		return fmt.Errorf("Light %d not found", light)
 	}
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/lights/%d", h.baseURL, light), nil)
+	err := validateAlert(alert)
 	if err != nil {
 		return err
 	}
 
-	err = h.execute(req)
+	// Set state
+	err = h.changeLightState(light, alertRequest{Alert: alert})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetLightStartup sets the state the specified Phillips Hue light powers on to.
+// The custom settings are only used when the mode is StartupCustom.
+func (h *Connection) SetLightStartup(light int, mode string, custom LightStartupSettings) error {
+	// Error checking
+	currentLight, err := h.GetLight(light)
+	if err != nil {
+		return fmt.Errorf("Light %d not found", light)
+	}
+	currentLight.ID = light
+
+	if !currentLight.HasStartupConfig() {
+		return fmt.Errorf("Light %d does not support startup configuration", light)
+	}
+
+	isValid := false
+	for _, m := range StartupModes {
+		if m == mode {
+			isValid = true
+		}
+	}
+
+	if !isValid {
+		return fmt.Errorf("Startup mode must be one of the following: %s", strings.Join(StartupModes, ", "))
+	}
+
+	startup := lightStartupRequest{
+		Mode: mode,
+	}
+
+	if mode == StartupCustom {
+		err = validateStartupSettings(currentLight, custom)
+		if err != nil {
+			return err
+		}
+
+		startup.CustomSettings = &custom
+	} else if custom.Bri != 0 || custom.XY != nil || custom.CT != 0 {
+		return errors.New("Custom settings can only be set when the startup mode is custom")
+	}
+
+	err = h.execute("PUT", fmt.Sprintf("lights/%d/config", light), lightConfigRequest{Startup: startup})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// HasStartupConfig returns whether the light reports a startup configuration.
+// Lights that don't can't have their startup mode set.
+func (l Light) HasStartupConfig() bool {
+	return l.Config.Startup.Mode != ""
+}
+
+// DeleteLight deletes a Phillips Hue light from the bridge
+func (h *Connection) DeleteLight(light int) error {
+	// Error checking
+	if !h.doesLightExist(light) {
+		return fmt.Errorf("Light %d not found", light)
+	}
+
+	err := h.execute("DELETE", fmt.Sprintf("lights/%d", light), nil)
 	if err != nil {
 		return err
 	}
@@ -356,14 +569,17 @@ func (h *Connection) allLightsValid(lights []int) bool {
 	return true
 }
 
-func (h *Connection) changeLightState(light int, state string) error {
-	reqBody := strings.NewReader(state)
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/lights/%d/state", h.baseURL, light), reqBody)
+func (h *Connection) changeLightState(light int, state interface{}) error {
+	return h.changeLightStateContext(context.Background(), light, state)
+}
+
+func (h *Connection) changeLightStateContext(ctx context.Context, light int, state interface{}) error {
+	err := h.throttle(ctx, "lights", lightCommandInterval)
 	if err != nil {
 		return err
 	}
 
-	err = h.execute(req)
+	err = h.executeContext(ctx, "PUT", fmt.Sprintf("lights/%d/state", light), state)
 	if err != nil {
 		return err
 	}
@@ -371,6 +587,67 @@ func (h *Connection) changeLightState(light int, state string) error {
 	return nil
 }
 
+func validateStartupSettings(light Light, custom LightStartupSettings) error {
+	if custom.Bri == 0 && custom.XY == nil && custom.CT == 0 {
+		return errors.New("Custom settings must not be empty")
+	}
+
+	if custom.Bri != 0 && (custom.Bri < 1 || custom.Bri > 254) {
+		return errors.New("Invalid brightness value: bri must be between 1 and 254")
+	}
+
+	if custom.XY != nil && custom.CT != 0 {
+		return errors.New("Custom settings can only contain one of xy and ct")
+	}
+
+	if custom.XY != nil {
+		if light.Capabilities.Control.ColorGamutType == "" {
+			return fmt.Errorf("Light %d does not support color", light.ID)
+		}
+
+		if len(custom.XY) != 2 || custom.XY[0] < 0 || custom.XY[0] > 1 || custom.XY[1] < 0 || custom.XY[1] > 1 {
+			return errors.New("Invalid color value: xy must contain an x and y value between 0 and 1")
+		}
+	}
+
+	if custom.CT != 0 {
+		ct := light.Capabilities.Control.CT
+		if ct.Max == 0 {
+			return fmt.Errorf("Light %d does not support color temperature", light.ID)
+		}
+
+		if custom.CT < ct.Min || custom.CT > ct.Max {
+			return fmt.Errorf("Invalid color temperature value: ct must be between %d and %d", ct.Min, ct.Max)
+		}
+	}
+
+	return nil
+}
+
+// isSerial returns true if the serial is a valid Phillips Hue serial number,
+// which is 6 hexadecimal characters
+func isSerial(serial string) bool {
+	if len(serial) != 6 {
+		return false
+	}
+
+	for _, c := range serial {
+		if !strings.ContainsRune("0123456789ABCDEF", c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validateAlert(alert string) error {
+	if alert != AlertNone && alert != AlertSelect && alert != AlertLSelect {
+		return errors.New("Alert must be one of the following: none, select, lselect")
+	}
+
+	return nil
+}
+
 func (h *Connection) validateColorParams(x, y float32, bri, hue, sat int) error {
 	if x < 0 || x > 1 {
 		return errors.New("Invalid color value: x must be between 0 and 1")
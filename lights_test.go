@@ -1,7 +1,13 @@
 package hue
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestGetLights(t *testing.T) {
@@ -340,6 +346,58 @@ func TestTurnOnLightWithColor(t *testing.T) {
 	})
 }
 
+func TestSetLightState(t *testing.T) {
+	h, server, lastBody := createRecordingConnection()
+	defer server.Close()
+
+	on := true
+	bri := 150
+	transition := 600
+
+	t.Run("Successful", func(t *testing.T) {
+		err := h.SetLightState(1, LightStateUpdate{On: &on, Bri: &bri, XY: []float32{0.5, 0.4}, TransitionTime: &transition})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := `{"on":true,"bri":150,"xy":[0.5,0.4],"transitiontime":600}`
+			if string(lastBody()) != expected {
+				t.Fatalf("Expected body to equal %s, got %s", expected, lastBody())
+			}
+		}
+	})
+
+	t.Run("Light doesn't exist", func(t *testing.T) {
+		err := h.SetLightState(3, LightStateUpdate{On: &on})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Light 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Empty update", func(t *testing.T) {
+		err := h.SetLightState(1, LightStateUpdate{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+
+	t.Run("Invalid brightness", func(t *testing.T) {
+		invalid := 300
+		err := h.SetLightState(1, LightStateUpdate{Bri: &invalid})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+}
+
 func TestTurnOffLight(t *testing.T) {
 	h, server := createTestConnection(1)
 	defer server.Close()
@@ -391,3 +449,312 @@ func TestDeleteLight(t *testing.T) {
 		}
 	})
 }
+
+func TestIdentifyLight(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Light exists", func(t *testing.T) {
+		err := h.IdentifyLight(1, AlertLSelect)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Cancel alert", func(t *testing.T) {
+		err := h.IdentifyLight(1, AlertNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Light doesn't exist", func(t *testing.T) {
+		err := h.IdentifyLight(3, AlertSelect)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Light 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid alert", func(t *testing.T) {
+		err := h.IdentifyLight(1, "blink")
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Alert must be one of the following: none, select, lselect"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+}
+
+func TestSetLightStartup(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Successful startup mode", func(t *testing.T) {
+		err := h.SetLightStartup(1, StartupPowerfail, LightStartupSettings{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Successful custom startup", func(t *testing.T) {
+		err := h.SetLightStartup(1, StartupCustom, LightStartupSettings{Bri: 100, CT: 366})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Light doesn't exist", func(t *testing.T) {
+		err := h.SetLightStartup(3, StartupSafety, LightStartupSettings{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Light 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Startup not supported", func(t *testing.T) {
+		err := h.SetLightStartup(4, StartupSafety, LightStartupSettings{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Light 4 does not support startup configuration"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid mode", func(t *testing.T) {
+		err := h.SetLightStartup(1, "previous", LightStartupSettings{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Startup mode must be one of the following: safety, powerfail, custom, lastonstate"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Custom settings without custom mode", func(t *testing.T) {
+		err := h.SetLightStartup(1, StartupSafety, LightStartupSettings{Bri: 100})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Custom settings can only be set when the startup mode is custom"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Empty custom settings", func(t *testing.T) {
+		err := h.SetLightStartup(1, StartupCustom, LightStartupSettings{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Custom settings must not be empty"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid ct value", func(t *testing.T) {
+		err := h.SetLightStartup(1, StartupCustom, LightStartupSettings{CT: 600})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Invalid color temperature value: ct must be between 153 and 500"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+}
+
+func TestHasStartupConfig(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Startup supported", func(t *testing.T) {
+		light, err := h.GetLight(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !light.HasStartupConfig() {
+			t.Fatal("Expected light to have a startup configuration")
+		}
+
+		if light.Config.Startup.Mode != StartupSafety {
+			t.Fatalf("Expected startup mode to equal %s, got %s", StartupSafety, light.Config.Startup.Mode)
+		}
+	})
+
+	t.Run("Startup not supported", func(t *testing.T) {
+		light, err := h.GetLight(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if light.HasStartupConfig() {
+			t.Fatal("Expected light not to have a startup configuration")
+		}
+	})
+}
+
+func TestSearchLightsBySerial(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Successful search", func(t *testing.T) {
+		err := h.SearchLightsBySerial([]string{"45af34", "543636"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("No serials", func(t *testing.T) {
+		err := h.SearchLightsBySerial([]string{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Serials must not be empty"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Too many serials", func(t *testing.T) {
+		serials := []string{"000001", "000002", "000003", "000004", "000005", "000006", "000007", "000008", "000009", "00000A", "00000B"}
+
+		err := h.SearchLightsBySerial(serials)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Serials must contain at most 10 serial numbers"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid serial", func(t *testing.T) {
+		err := h.SearchLightsBySerial([]string{"45AF34", "XYZ"})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Invalid serial number: XYZ"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+}
+
+func TestWaitForLightSearch(t *testing.T) {
+	t.Run("Search complete", func(t *testing.T) {
+		h, server := createTestConnection(1)
+		defer server.Close()
+
+		newLights, err := h.WaitForLightSearch(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := 1
+			if len(newLights.NewLights) != expected {
+				t.Fatalf("Expected number of new lights to equal %d, got %d", expected, len(newLights.NewLights))
+			}
+		}
+	})
+
+	t.Run("Search still active", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("{\"lastscan\": \"active\"}"))
+		}))
+		defer server.Close()
+
+		h := Connection{
+			baseURL:       server.URL,
+			isInitialized: true,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := h.WaitForLightSearch(ctx)
+		if err != context.DeadlineExceeded {
+			t.Fatalf("Expected error to equal %v, got %v", context.DeadlineExceeded, err)
+		}
+	})
+}
+
+func BenchmarkGetLights(b *testing.B) {
+	// Simulate a bridge with 60 lights
+	lights := make(map[string]interface{})
+	for i := 1; i <= 60; i++ {
+		lights[strconv.Itoa(i)] = generateTestData("/lights/1")
+	}
+
+	data, err := json.Marshal(lights)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	h := Connection{
+		baseURL:       server.URL,
+		isInitialized: true,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := h.GetLights()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
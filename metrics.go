@@ -0,0 +1,274 @@
+package hue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of every request a Connection sends to the
+// bridge. Requests are labelled by resource type, such as lights or groups,
+// and operation, which is one of get, create, update, set_state, or delete.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest is called once for every request sent to the bridge
+	ObserveRequest(resource, operation string, latency time.Duration)
+
+	// ObserveError is called once for every error a request resulted in.
+	// errorType is the bridge's error type, such as 201, http_503 if the
+	// bridge responded with an HTTP error, or transport if the request failed.
+	ObserveError(resource, operation, errorType string)
+
+	// ObserveThrottle is called every time a command waits for the rate limit
+	ObserveThrottle(resource string, delay time.Duration)
+}
+
+// SetMetrics sets the Metrics the Connection reports to. Passing nil stops
+// reporting.
+func (h *Connection) SetMetrics(m Metrics) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.metrics = m
+}
+
+func (h *Connection) getMetrics() Metrics {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.metrics
+}
+
+// observe reports a request sent to the bridge
+func (h *Connection) observe(req *Request, resp *Response, err error, latency time.Duration) {
+	m := h.getMetrics()
+	if m == nil {
+		return
+	}
+
+	resource := resourceType(req.Path)
+	operation := requestOperation(req.Method, req.Path)
+
+	m.ObserveRequest(resource, operation, latency)
+
+	if err != nil {
+		m.ObserveError(resource, operation, "transport")
+		return
+	}
+
+	if resp == nil {
+		return
+	}
+
+	if resp.StatusCode >= 400 {
+		m.ObserveError(resource, operation, fmt.Sprintf("http_%d", resp.StatusCode))
+	}
+
+	for _, bridgeErr := range resp.Errors {
+		m.ObserveError(resource, operation, strconv.Itoa(bridgeErr.Type))
+	}
+}
+
+// throttle waits for the rate limit of the specified bucket and reports the
+// delay
+func (h *Connection) throttle(ctx context.Context, bucket string, interval time.Duration) error {
+	start := time.Now()
+
+	err := h.limiter.wait(ctx, bucket, interval)
+
+	if m := h.getMetrics(); m != nil {
+		m.ObserveThrottle(bucket, time.Since(start))
+	}
+
+	return err
+}
+
+// requestOperation returns the operation label for a request
+func requestOperation(method, path string) string {
+	switch method {
+	case "GET":
+		return "get"
+	case "POST":
+		return "create"
+	case "DELETE":
+		return "delete"
+	case "PUT":
+		if strings.HasSuffix(path, "/state") || strings.HasSuffix(path, "/action") {
+			return "set_state"
+		}
+		return "update"
+	}
+
+	return strings.ToLower(method)
+}
+
+// Default histogram buckets, in seconds
+var (
+	latencyBuckets  = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	throttleBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+)
+
+// MetricsCollector is a Metrics implementation that keeps counters and
+// histograms in memory. Its Handler exports them in the Prometheus text format.
+type MetricsCollector struct {
+	mu        sync.Mutex
+	requests  map[string]float64
+	errors    map[string]float64
+	latency   map[string]*histogram
+	throttles map[string]*histogram
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []float64
+	sum     float64
+	count   float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]float64, len(buckets))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += value
+	h.count++
+}
+
+// NewMetricsCollector creates an empty MetricsCollector
+func NewMetricsCollector() *MetricsCollector {
+	return &MetricsCollector{
+		requests:  make(map[string]float64),
+		errors:    make(map[string]float64),
+		latency:   make(map[string]*histogram),
+		throttles: make(map[string]*histogram),
+	}
+}
+
+// ObserveRequest implements Metrics
+func (c *MetricsCollector) ObserveRequest(resource, operation string, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := formatLabels("resource", resource, "operation", operation)
+	c.requests[key]++
+
+	if c.latency[key] == nil {
+		c.latency[key] = newHistogram(latencyBuckets)
+	}
+	c.latency[key].observe(latency.Seconds())
+}
+
+// ObserveError implements Metrics
+func (c *MetricsCollector) ObserveError(resource, operation, errorType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.errors[formatLabels("resource", resource, "operation", operation, "type", errorType)]++
+}
+
+// ObserveThrottle implements Metrics
+func (c *MetricsCollector) ObserveThrottle(resource string, delay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := formatLabels("resource", resource)
+	if c.throttles[key] == nil {
+		c.throttles[key] = newHistogram(throttleBuckets)
+	}
+	c.throttles[key].observe(delay.Seconds())
+}
+
+// Handler returns an http.Handler that serves the collected metrics in the
+// Prometheus text format
+func (c *MetricsCollector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write([]byte(c.String()))
+	})
+}
+
+// String returns the collected metrics in the Prometheus text format
+func (c *MetricsCollector) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var b strings.Builder
+
+	writeCounter(&b, "hue_requests_total", "Requests sent to the Hue bridge.", c.requests)
+	writeCounter(&b, "hue_request_errors_total", "Errors returned for requests sent to the Hue bridge, by error type.", c.errors)
+	writeHistogram(&b, "hue_request_duration_seconds", "Latency of requests sent to the Hue bridge.", c.latency)
+	writeHistogram(&b, "hue_throttle_delay_seconds", "Time commands waited for the Hue bridge rate limit.", c.throttles)
+
+	return b.String()
+}
+
+func writeCounter(b *strings.Builder, name, help string, values map[string]float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+	for _, labels := range sortedKeys(values) {
+		fmt.Fprintf(b, "%s{%s} %s\n", name, labels, formatFloat(values[labels]))
+	}
+}
+
+func writeHistogram(b *strings.Builder, name, help string, values map[string]*histogram) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+	keys := make([]string, 0, len(values))
+	for labels := range values {
+		keys = append(keys, labels)
+	}
+	sort.Strings(keys)
+
+	for _, labels := range keys {
+		h := values[labels]
+
+		for i, bound := range h.buckets {
+			fmt.Fprintf(b, "%s_bucket{%s,le=\"%s\"} %s\n", name, labels, formatFloat(bound), formatFloat(h.counts[i]))
+		}
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %s\n", name, labels, formatFloat(h.count))
+		fmt.Fprintf(b, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+		fmt.Fprintf(b, "%s_count{%s} %s\n", name, labels, formatFloat(h.count))
+	}
+}
+
+// formatLabels formats pairs of label names and values as used in the
+// Prometheus text format
+func formatLabels(pairs ...string) string {
+	labels := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, fmt.Sprintf("%s=\"%s\"", pairs[i], escapeLabelValue(pairs[i+1])))
+	}
+
+	return strings.Join(labels, ",")
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
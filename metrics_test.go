@@ -0,0 +1,91 @@
+package hue
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsCollector(t *testing.T) {
+	h, server := createErrorConnection(`[{"error": {"type": 201, "address": "/lights/1/state/on", "description": "parameter, on, is not modifiable. Device is set to off."}}]`)
+	defer server.Close()
+
+	metrics := NewMetricsCollector()
+	h.SetMetrics(metrics)
+
+	h.execute("PUT", "lights/1/state", onRequest{On: true})
+	h.execute("PUT", "lights/1", nameRequest{Name: "Lamp"})
+	h.get("groups")
+
+	err := h.throttle(context.Background(), "groups", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, _ := ioutil.ReadAll(rec.Body)
+	out := string(body)
+
+	expected := []string{
+		`# TYPE hue_requests_total counter`,
+		`hue_requests_total{resource="lights",operation="set_state"} 1`,
+		`hue_requests_total{resource="lights",operation="update"} 1`,
+		`hue_requests_total{resource="groups",operation="get"} 1`,
+		`hue_request_errors_total{resource="lights",operation="set_state",type="201"} 1`,
+		`# TYPE hue_request_duration_seconds histogram`,
+		`hue_request_duration_seconds_bucket{resource="lights",operation="set_state",le="+Inf"} 1`,
+		`hue_request_duration_seconds_count{resource="groups",operation="get"} 1`,
+		`hue_throttle_delay_seconds_count{resource="groups"} 1`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Fatalf("Expected output to contain %s, got:\n%s", line, out)
+		}
+	}
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Unexpected content type %s", ct)
+	}
+}
+
+func TestMetricsTransportError(t *testing.T) {
+	h, server := createErrorConnection("")
+	server.Close()
+
+	metrics := NewMetricsCollector()
+	h.SetMetrics(metrics)
+
+	_, err := h.get("lights")
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	if out := metrics.String(); !strings.Contains(out, `hue_request_errors_total{resource="lights",operation="get",type="transport"} 1`) {
+		t.Fatalf("Expected transport error to be counted, got:\n%s", out)
+	}
+}
+
+func TestRequestOperation(t *testing.T) {
+	operations := []struct {
+		method, path, expected string
+	}{
+		{"GET", "lights", "get"},
+		{"POST", "groups", "create"},
+		{"PUT", "groups/1", "update"},
+		{"PUT", "groups/1/action", "set_state"},
+		{"PUT", "lights/1/state", "set_state"},
+		{"DELETE", "scenes/abc", "delete"},
+	}
+
+	for _, o := range operations {
+		if got := requestOperation(o.method, o.path); got != o.expected {
+			t.Fatalf("Expected %s for %s %s, got %s", o.expected, o.method, o.path, got)
+		}
+	}
+}
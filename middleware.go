@@ -0,0 +1,170 @@
+package hue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Request contains the details of a single request sent to the bridge
+type Request struct {
+	Method string
+
+	// URL is the full URL, which includes the username
+	URL string
+
+	// Path is the URL relative to the username, for example lights/1/state
+	Path string
+
+	// Body is the JSON request body, or nil if there isn't one
+	Body []byte
+}
+
+// Response contains the details of the bridge's response to a single request
+type Response struct {
+	StatusCode int
+	Body       []byte
+	Latency    time.Duration
+
+	// Errors contains any errors the bridge returned in the response body
+	Errors BridgeErrors
+}
+
+// RequestHandler sends a request to the bridge
+type RequestHandler func(ctx context.Context, req *Request) (*Response, error)
+
+// Interceptor is called for every request sent to the bridge. It can inspect or
+// change the request, must call next to send it, and can then inspect or change
+// the response. If the bridge can't be reached and is discovered again, the
+// retried request is passed through the interceptors again.
+type Interceptor func(ctx context.Context, req *Request, next RequestHandler) (*Response, error)
+
+// Use adds interceptors to the Connection. The first interceptor added is the
+// outermost, so it sees each request first and each response last.
+func (h *Connection) Use(interceptors ...Interceptor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.interceptors = append(h.interceptors, interceptors...)
+}
+
+// chain wraps the handler in all of the Connection's interceptors
+func (h *Connection) chain(handler RequestHandler) RequestHandler {
+	h.mu.RLock()
+	interceptors := h.interceptors
+	h.mu.RUnlock()
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor := interceptors[i]
+		next := handler
+
+		handler = func(ctx context.Context, req *Request) (*Response, error) {
+			return interceptor(ctx, req, next)
+		}
+	}
+
+	return handler
+}
+
+// Tracer starts a span for each request sent to the bridge. It has the same
+// shape as an OpenTelemetry tracer, so one can be adapted with a few lines of
+// code without this package depending on OpenTelemetry.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span records a single request sent to the bridge
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// TracingInterceptor creates an interceptor that records a span for every
+// request sent to the bridge
+func TracingInterceptor(tracer Tracer) Interceptor {
+	return func(ctx context.Context, req *Request, next RequestHandler) (*Response, error) {
+		ctx, span := tracer.Start(ctx, fmt.Sprintf("hue %s %s", req.Method, resourceType(req.Path)))
+		defer span.End()
+
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.url", RedactURL(req.URL))
+		span.SetAttribute("hue.path", RedactURL(req.Path))
+
+		resp, err := next(ctx, req)
+		if err != nil {
+			span.RecordError(err)
+			return resp, err
+		}
+
+		span.SetAttribute("http.status_code", resp.StatusCode)
+
+		for _, bridgeErr := range resp.Errors {
+			span.SetAttribute("hue.error_type", bridgeErr.Type)
+			span.RecordError(bridgeErr)
+		}
+
+		return resp, nil
+	}
+}
+
+// LogInterceptor creates an interceptor that logs every request sent to the
+// bridge. Successful requests are logged at debug level, requests the bridge
+// returned errors for at warn level, and requests that failed at error level.
+// Usernames in URLs are redacted.
+func LogInterceptor(logger *slog.Logger) Interceptor {
+	return func(ctx context.Context, req *Request, next RequestHandler) (*Response, error) {
+		resp, err := next(ctx, req)
+
+		attrs := []slog.Attr{
+			slog.String("method", req.Method),
+			slog.String("url", RedactURL(req.URL)),
+		}
+
+		if req.Body != nil {
+			attrs = append(attrs, slog.String("body", string(req.Body)))
+		}
+
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+			logger.LogAttrs(ctx, slog.LevelError, "hue request failed", attrs...)
+			return resp, err
+		}
+
+		attrs = append(attrs,
+			slog.Int("status", resp.StatusCode),
+			slog.Duration("latency", resp.Latency),
+		)
+
+		if len(resp.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", strings.TrimSpace(resp.Errors.Error())))
+			logger.LogAttrs(ctx, slog.LevelWarn, "hue request returned errors", attrs...)
+			return resp, nil
+		}
+
+		logger.LogAttrs(ctx, slog.LevelDebug, "hue request", attrs...)
+
+		return resp, nil
+	}
+}
+
+var usernamePattern = regexp.MustCompile(`(/api/|whitelist/)[^/?#]+`)
+
+// RedactURL replaces the usernames in a bridge URL or path with REDACTED
+func RedactURL(url string) string {
+	return usernamePattern.ReplaceAllString(url, "${1}REDACTED")
+}
+
+// resourceType returns the type of resource a path refers to, for example
+// lights for lights/1/state
+func resourceType(path string) string {
+	resource := strings.SplitN(path, "/", 2)[0]
+	if resource == "" {
+		return "bridge"
+	}
+
+	return resource
+}
@@ -0,0 +1,194 @@
+package hue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// createErrorConnection creates a test connection to a bridge that returns the
+// specified body for every request
+func createErrorConnection(body string) (*Connection, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+
+	return &Connection{
+		UserID:            "TEST",
+		internalIPAddress: "localhost",
+		baseURL:           server.URL + "/api/TEST",
+		isInitialized:     true,
+	}, server
+}
+
+type testSpan struct {
+	name       string
+	attributes map[string]interface{}
+	errs       []error
+	ended      bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *testSpan) RecordError(err error)                      { s.errs = append(s.errs, err) }
+func (s *testSpan) End()                                       { s.ended = true }
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	span := &testSpan{name: name, attributes: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+
+	return ctx, span
+}
+
+func TestInterceptors(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	order := []string{}
+
+	h.Use(
+		func(ctx context.Context, req *Request, next RequestHandler) (*Response, error) {
+			order = append(order, "outer before")
+			resp, err := next(ctx, req)
+			order = append(order, "outer after")
+			return resp, err
+		},
+		func(ctx context.Context, req *Request, next RequestHandler) (*Response, error) {
+			order = append(order, "inner before")
+
+			if req.Method == "PUT" && string(req.Body) != `{"name":"Lamp"}` {
+				t.Fatalf("Unexpected body %s", req.Body)
+			}
+
+			resp, err := next(ctx, req)
+			if err != nil {
+				t.Fatalf("Expected no errors, got %s", err)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+			}
+
+			order = append(order, "inner after")
+			return resp, err
+		},
+	)
+
+	err := h.RenameLight(1, "Lamp")
+	if err != nil {
+		t.Fatalf("Expected no errors, got %s", err)
+	}
+
+	// RenameLight checks that the light exists first, so only look at the PUT
+	expected := "outer before,inner before,inner after,outer after"
+	if got := strings.Join(order[len(order)-4:], ","); got != expected {
+		t.Fatalf("Expected order %s, got %s", expected, got)
+	}
+}
+
+func TestBridgeErrors(t *testing.T) {
+	h, server := createErrorConnection(`[{"error": {"type": 201, "address": "/lights/1/state/bri", "description": "parameter, bri, is not modifiable. Device is set to off."}}]`)
+	defer server.Close()
+
+	var seen BridgeErrors
+	h.Use(func(ctx context.Context, req *Request, next RequestHandler) (*Response, error) {
+		resp, err := next(ctx, req)
+		if resp != nil {
+			seen = resp.Errors
+		}
+		return resp, err
+	})
+
+	err := h.execute("PUT", "lights/1/state", colorStateRequest{Bri: 100})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	if err.Error() != "Error: parameter, bri, is not modifiable. Device is set to off.\n" {
+		t.Fatalf("Unexpected error message %q", err.Error())
+	}
+
+	bridgeErrs := BridgeErrors{}
+	if !errors.As(err, &bridgeErrs) || !bridgeErrs.HasType(ErrorDeviceIsOff) {
+		t.Fatalf("Expected BridgeErrors with type %d, got %#v", ErrorDeviceIsOff, err)
+	}
+
+	if len(seen) != 1 || seen[0].Address != "/lights/1/state/bri" {
+		t.Fatalf("Expected interceptor to see the bridge error, got %#v", seen)
+	}
+}
+
+func TestLogInterceptor(t *testing.T) {
+	h, server := createErrorConnection(`[{"error": {"type": 3, "address": "/lights/9", "description": "resource, /lights/9, not available"}}]`)
+	defer server.Close()
+
+	buf := &bytes.Buffer{}
+	h.Use(LogInterceptor(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+	h.execute("DELETE", "lights/9", nil)
+
+	out := buf.String()
+	if strings.Contains(out, "TEST") {
+		t.Fatalf("Expected username to be redacted, got %s", out)
+	}
+
+	if !strings.Contains(out, "level=WARN") || !strings.Contains(out, "/api/REDACTED/lights/9") || !strings.Contains(out, "not available") {
+		t.Fatalf("Unexpected log output %s", out)
+	}
+}
+
+func TestTracingInterceptor(t *testing.T) {
+	h, server := createErrorConnection(`[{"error": {"type": 901, "address": "/groups/1/action", "description": "Internal error, 404"}}]`)
+	defer server.Close()
+
+	tracer := &testTracer{}
+	h.Use(TracingInterceptor(tracer))
+
+	h.execute("PUT", "groups/1/action", onRequest{On: true})
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(tracer.spans))
+	}
+
+	span := tracer.spans[0]
+	if span.name != "hue PUT groups" || !span.ended {
+		t.Fatalf("Unexpected span %#v", span)
+	}
+
+	if span.attributes["http.status_code"] != http.StatusOK || span.attributes["hue.error_type"] != ErrorInternal {
+		t.Fatalf("Unexpected span attributes %#v", span.attributes)
+	}
+
+	if len(span.errs) != 1 {
+		t.Fatalf("Expected 1 recorded error, got %d", len(span.errs))
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	urls := map[string]string{
+		"http://192.168.1.2/api/abc123/lights/1":        "http://192.168.1.2/api/REDACTED/lights/1",
+		"http://192.168.1.2/api/abc123":                 "http://192.168.1.2/api/REDACTED",
+		"config/whitelist/abc123":                       "config/whitelist/REDACTED",
+		"http://192.168.1.2/api/abc123/config?x=1":      "http://192.168.1.2/api/REDACTED/config?x=1",
+		"http://192.168.1.2/api/abc/config/whitelist/d": "http://192.168.1.2/api/REDACTED/config/whitelist/REDACTED",
+	}
+
+	for url, expected := range urls {
+		if got := RedactURL(url); got != expected {
+			t.Fatalf("Expected %s, got %s", expected, got)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package mqtt
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+)
+
+// Broker is a minimal in-memory MQTT 3.1.1 broker, for testing and trying out
+// the daemon without a real broker. It supports retained messages, wills, and
+// wildcard subscriptions. Every message is delivered with QoS 0, and
+// authentication isn't checked.
+type Broker struct {
+	mu       sync.Mutex
+	retained map[string][]byte
+	sessions map[*session]struct{}
+	listener net.Listener
+	closed   bool
+}
+
+// session is a client connected to the broker
+type session struct {
+	conn    net.Conn
+	filters []string
+	will    *Message
+
+	mu sync.Mutex
+}
+
+// NewBroker creates a Broker with no messages
+func NewBroker() *Broker {
+	return &Broker{
+		retained: map[string][]byte{},
+		sessions: map[*session]struct{}{},
+	}
+}
+
+// Listen starts accepting connections on the address, which may use port 0 to
+// choose one, and returns the address being listened on
+func (b *Broker) Listen(address string) (string, error) {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	b.listener = l
+	b.mu.Unlock()
+
+	go b.serve(l)
+
+	return l.Addr().String(), nil
+}
+
+// Close stops accepting connections and disconnects every client
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.sessions {
+		s.conn.Close()
+	}
+
+	if b.listener == nil {
+		return nil
+	}
+
+	return b.listener.Close()
+}
+
+// Publish publishes a message to every subscribed client, as if a client had
+// published it
+func (b *Broker) Publish(m Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.publish(m)
+}
+
+// Retained returns the retained message of a topic
+func (b *Broker) Retained(topic string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	payload, ok := b.retained[topic]
+
+	return payload, ok
+}
+
+// RetainedTopics returns the topics with a retained message matching the
+// filter, in order
+func (b *Broker) RetainedTopics(filter string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topics := []string{}
+	for topic := range b.retained {
+		if MatchTopic(filter, topic) {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+
+	return topics
+}
+
+func (b *Broker) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go b.handle(conn)
+	}
+}
+
+// handle reads packets from a client until it disconnects
+func (b *Broker) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+
+	s, err := b.accept(conn, r)
+	if err != nil {
+		return
+	}
+
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			break
+		}
+
+		switch p.packetType() {
+		case packetPublish:
+			m, qos, id, err := parsePublish(p)
+			if err != nil {
+				break
+			}
+
+			if qos == 1 {
+				s.write(packet{header: packetPubAck << 4, body: []byte{byte(id >> 8), byte(id)}})
+			}
+
+			b.Publish(m)
+		case packetSubscribe:
+			b.subscribe(s, p)
+		case packetUnsubscribe:
+			b.unsubscribe(s, p)
+		case packetPingReq:
+			s.write(packet{header: packetPingResp << 4})
+		case packetDisconnect:
+			s.will = nil
+			b.remove(s)
+			return
+		}
+	}
+
+	b.remove(s)
+}
+
+// accept reads the CONNECT packet and adds the session
+func (b *Broker) accept(conn net.Conn, r *bufio.Reader) (*session, error) {
+	p, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.packetType() != packetConnect {
+		return nil, errors.New("First packet must be CONNECT")
+	}
+
+	body := &reader{data: p.body}
+	protocol := body.string()
+	level := body.byte()
+	flags := body.byte()
+	body.uint16() // Keep alive
+	body.string() // Client ID
+
+	s := &session{conn: conn}
+
+	if flags&0x04 != 0 {
+		s.will = &Message{Topic: body.string(), Payload: body.bytes(), Retain: flags&0x20 != 0}
+	}
+
+	if body.err != nil {
+		return nil, body.err
+	}
+
+	if protocol != "MQTT" || level != 4 {
+		s.write(packet{header: packetConnAck << 4, body: []byte{0, 1}})
+		return nil, errors.New("Unsupported protocol")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, errors.New("Broker is closed")
+	}
+
+	b.sessions[s] = struct{}{}
+	s.write(packet{header: packetConnAck << 4, body: []byte{0, 0}})
+
+	return s, nil
+}
+
+// subscribe adds the filters of a SUBSCRIBE packet and sends the matching
+// retained messages
+func (b *Broker) subscribe(s *session, p packet) {
+	body := &reader{data: p.body}
+	id := body.uint16()
+
+	filters := []string{}
+	for len(body.data) > 0 && body.err == nil {
+		filters = append(filters, body.string())
+		body.byte() // Requested QoS
+	}
+
+	if body.err != nil {
+		return
+	}
+
+	granted := make([]byte, len(filters))
+	s.write(packet{header: packetSubAck << 4, body: append([]byte{byte(id >> 8), byte(id)}, granted...)})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s.filters = append(s.filters, filters...)
+
+	topics := []string{}
+	for topic := range b.retained {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	for _, topic := range topics {
+		for _, filter := range filters {
+			if MatchTopic(filter, topic) {
+				s.write(publishPacket(Message{Topic: topic, Payload: b.retained[topic], Retain: true}))
+				break
+			}
+		}
+	}
+}
+
+// unsubscribe removes the filters of an UNSUBSCRIBE packet
+func (b *Broker) unsubscribe(s *session, p packet) {
+	body := &reader{data: p.body}
+	id := body.uint16()
+
+	b.mu.Lock()
+	for len(body.data) > 0 && body.err == nil {
+		filter := body.string()
+
+		for i, f := range s.filters {
+			if f == filter {
+				s.filters = append(s.filters[:i], s.filters[i+1:]...)
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	s.write(packet{header: packetUnsubAck << 4, body: []byte{byte(id >> 8), byte(id)}})
+}
+
+// remove removes a session and publishes its will, if it has one
+func (b *Broker) remove(s *session) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.sessions, s)
+
+	if s.will != nil && !b.closed {
+		b.publish(*s.will)
+	}
+}
+
+// publish retains the message if needed and sends it to every matching
+// session. b.mu must be held.
+func (b *Broker) publish(m Message) {
+	if m.Retain {
+		if len(m.Payload) == 0 {
+			delete(b.retained, m.Topic)
+		} else {
+			b.retained[m.Topic] = m.Payload
+		}
+	}
+
+	// Messages are sent to existing subscribers without the retain flag
+	m.Retain = false
+
+	for s := range b.sessions {
+		for _, filter := range s.filters {
+			if MatchTopic(filter, m.Topic) {
+				s.write(publishPacket(m))
+				break
+			}
+		}
+	}
+}
+
+func (s *session) write(p packet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writePacket(s.conn, p)
+}
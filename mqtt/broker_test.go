@@ -0,0 +1,178 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// createTestBroker starts a Broker listening on a random local port
+func createTestBroker(t *testing.T) (*Broker, string) {
+	t.Helper()
+
+	b := NewBroker()
+
+	address, err := b.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+
+	return b, address
+}
+
+func dial(t *testing.T, address string, options ClientOptions) *Client {
+	t.Helper()
+
+	c, err := Dial(context.Background(), address, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// receive waits for the next message from the client
+func receive(t *testing.T, c *Client) Message {
+	t.Helper()
+
+	select {
+	case m := <-c.Messages():
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a message")
+	}
+
+	return Message{}
+}
+
+// waitFor polls until the condition is true
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBroker(t *testing.T) {
+	t.Run("Publish and subscribe", func(t *testing.T) {
+		_, address := createTestBroker(t)
+
+		sub := dial(t, address, ClientOptions{ClientID: "sub"})
+		defer sub.Disconnect()
+
+		err := sub.Subscribe("hue/+/state")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pub := dial(t, address, ClientOptions{ClientID: "pub"})
+		defer pub.Disconnect()
+
+		// The subscription may not have been processed yet, so publish until
+		// it's received
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			for {
+				pub.Publish(Message{Topic: "hue/desk/state", Payload: []byte("on")})
+				pub.Publish(Message{Topic: "hue/desk/set", Payload: []byte("off")})
+
+				select {
+				case <-done:
+					return
+				case <-time.After(20 * time.Millisecond):
+				}
+			}
+		}()
+
+		m := receive(t, sub)
+
+		{
+			expected := "hue/desk/state"
+			if m.Topic != expected || string(m.Payload) != "on" || m.Retain {
+				t.Errorf("Expected message to %s, got %+v", expected, m)
+			}
+		}
+	})
+
+	t.Run("Retained", func(t *testing.T) {
+		b, address := createTestBroker(t)
+
+		pub := dial(t, address, ClientOptions{})
+		defer pub.Disconnect()
+
+		pub.Publish(Message{Topic: "hue/desk/state", Payload: []byte("on"), Retain: true})
+		pub.Publish(Message{Topic: "hue/lamp/state", Payload: []byte("off"), Retain: true})
+
+		waitFor(t, func() bool { return len(b.RetainedTopics("#")) == 2 })
+
+		sub := dial(t, address, ClientOptions{})
+		defer sub.Disconnect()
+
+		err := sub.Subscribe("hue/desk/#")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m := receive(t, sub)
+		if m.Topic != "hue/desk/state" || string(m.Payload) != "on" || !m.Retain {
+			t.Errorf("Expected retained message to hue/desk/state, got %+v", m)
+		}
+
+		// An empty payload clears the retained message
+		pub.Publish(Message{Topic: "hue/desk/state", Retain: true})
+
+		waitFor(t, func() bool {
+			_, ok := b.Retained("hue/desk/state")
+			return !ok
+		})
+	})
+
+	t.Run("Will", func(t *testing.T) {
+		b, address := createTestBroker(t)
+
+		will := &Message{Topic: "hue/availability", Payload: []byte("offline"), Retain: true}
+
+		c := dial(t, address, ClientOptions{Will: will})
+		c.Disconnect()
+
+		// Disconnecting cleanly doesn't publish the will
+		time.Sleep(50 * time.Millisecond)
+		if _, ok := b.Retained(will.Topic); ok {
+			t.Error("Expected will not to be published")
+		}
+
+		c = dial(t, address, ClientOptions{Will: will})
+		c.conn.Close()
+
+		waitFor(t, func() bool {
+			payload, _ := b.Retained(will.Topic)
+			return string(payload) == "offline"
+		})
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		b, address := createTestBroker(t)
+
+		c := dial(t, address, ClientOptions{})
+		b.Close()
+
+		select {
+		case <-c.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected connection to be closed")
+		}
+
+		if c.Err() == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// ClientOptions contains the settings used to connect to a broker
+type ClientOptions struct {
+	ClientID string
+	Username string
+	Password string
+
+	// KeepAlive is how often the client pings the broker when idle. If 0,
+	// 30 seconds is used.
+	KeepAlive time.Duration
+
+	// Will is published by the broker if the client disconnects without
+	// calling Disconnect
+	Will *Message
+}
+
+// Client is a minimal MQTT 3.1.1 client. Messages are published and
+// subscribed to with QoS 0.
+type Client struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	messages chan Message
+	done     chan struct{}
+	stop     chan struct{}
+
+	mu       sync.Mutex
+	err      error
+	packetID uint16
+	closed   bool
+}
+
+// connectReturnCodes contains the reason for each CONNACK return code
+var connectReturnCodes = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad username or password",
+	5: "not authorized",
+}
+
+// Dial connects to the broker at the address, of the form host:port
+func Dial(ctx context.Context, address string, options ClientOptions) (*Client, error) {
+	if options.KeepAlive <= 0 {
+		options.KeepAlive = 30 * time.Second
+	}
+
+	dialer := net.Dialer{}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		conn:     conn,
+		reader:   bufio.NewReader(conn),
+		messages: make(chan Message, 64),
+		done:     make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+
+	err = c.connect(options)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go c.readLoop()
+	go c.keepAlive(options.KeepAlive)
+
+	return c, nil
+}
+
+// connect sends the CONNECT packet and waits for the broker to accept it
+func (c *Client) connect(options ClientOptions) error {
+	flags := byte(0x02) // Clean session
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4) // Protocol level 3.1.1
+
+	if options.Will != nil {
+		flags |= 0x04
+		if options.Will.Retain {
+			flags |= 0x20
+		}
+	}
+	if options.Username != "" {
+		flags |= 0x80
+	}
+	if options.Password != "" {
+		flags |= 0x40
+	}
+
+	body = append(body, flags)
+	body = append(body, byte(options.KeepAlive/time.Second>>8), byte(options.KeepAlive/time.Second))
+	body = appendString(body, options.ClientID)
+
+	if options.Will != nil {
+		body = appendString(body, options.Will.Topic)
+		body = appendBytes(body, options.Will.Payload)
+	}
+	if options.Username != "" {
+		body = appendString(body, options.Username)
+	}
+	if options.Password != "" {
+		body = appendString(body, options.Password)
+	}
+
+	c.conn.SetDeadline(time.Now().Add(10 * time.Second))
+	defer c.conn.SetDeadline(time.Time{})
+
+	err := writePacket(c.conn, packet{header: packetConnect << 4, body: body})
+	if err != nil {
+		return err
+	}
+
+	p, err := readPacket(c.reader)
+	if err != nil {
+		return err
+	}
+
+	if p.packetType() != packetConnAck || len(p.body) != 2 {
+		return errors.New("Broker didn't acknowledge the connection")
+	}
+
+	if code := p.body[1]; code != 0 {
+		return fmt.Errorf("Broker refused the connection: %s", connectReturnCodes[code])
+	}
+
+	return nil
+}
+
+// Publish publishes a message
+func (c *Client) Publish(m Message) error {
+	return c.write(publishPacket(m))
+}
+
+// Subscribe subscribes to the topics matching each filter. Matching messages
+// are received from Messages.
+func (c *Client) Subscribe(filters ...string) error {
+	c.mu.Lock()
+	c.packetID++
+	if c.packetID == 0 {
+		c.packetID = 1
+	}
+	id := c.packetID
+	c.mu.Unlock()
+
+	body := []byte{byte(id >> 8), byte(id)}
+	for _, filter := range filters {
+		body = appendString(body, filter)
+		body = append(body, 0) // QoS 0
+	}
+
+	return c.write(packet{header: packetSubscribe<<4 | 0x02, body: body})
+}
+
+// Messages returns the channel that receives messages for subscribed topics.
+// It's closed when the connection is.
+func (c *Client) Messages() <-chan Message {
+	return c.messages
+}
+
+// Done returns a channel that's closed when the connection is closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error that closed the connection, or nil if it was closed
+// with Disconnect
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// Disconnect closes the connection. The broker doesn't publish the will.
+func (c *Client) Disconnect() error {
+	err := c.write(packet{header: packetDisconnect << 4})
+
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.stop)
+	}
+	c.mu.Unlock()
+
+	c.conn.Close()
+	<-c.done
+
+	return err
+}
+
+func (c *Client) write(p packet) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return errors.New("Connection is closed")
+	}
+
+	return writePacket(c.conn, p)
+}
+
+// readLoop reads packets until the connection is closed
+func (c *Client) readLoop() {
+	defer func() {
+		close(c.messages)
+		close(c.done)
+	}()
+
+	for {
+		p, err := readPacket(c.reader)
+		if err != nil {
+			c.mu.Lock()
+			if !c.closed {
+				c.err = err
+				c.closed = true
+				close(c.stop)
+			}
+			c.mu.Unlock()
+
+			c.conn.Close()
+			return
+		}
+
+		if p.packetType() != packetPublish {
+			continue
+		}
+
+		m, qos, id, err := parsePublish(p)
+		if err != nil {
+			continue
+		}
+
+		if qos == 1 {
+			c.write(packet{header: packetPubAck << 4, body: []byte{byte(id >> 8), byte(id)}})
+		}
+
+		select {
+		case c.messages <- m:
+		case <-c.stop:
+		}
+	}
+}
+
+// keepAlive pings the broker so it doesn't close the connection
+func (c *Client) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.write(packet{header: packetPingReq << 4})
+		}
+	}
+}
@@ -0,0 +1,655 @@
+// Package mqtt publishes the state of a bridge to an MQTT broker and changes
+// lights and groups from commands published to it, for home automation systems
+// such as Home Assistant.
+//
+// Topics are under <prefix>/<bridge>, which defaults to hue/<bridge ID>:
+//
+//	availability                 online or offline, retained
+//	lights/<name>/state          state of a light, retained
+//	lights/<name>/set            JSON commands for a light
+//	groups/<name>/state          state of a group, retained
+//	groups/<name>/set            JSON commands for a group
+//	sensors/<name>/state         readings of a sensor, retained
+//
+// The package includes a minimal MQTT 3.1.1 client, and a Broker for tests and
+// trying out the daemon without a real broker.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattvella07/hue"
+)
+
+// Config contains the settings for Run
+type Config struct {
+	// Broker is the address of the MQTT broker, of the form host:port
+	Broker   string
+	Username string
+	Password string
+
+	// ClientID identifies the daemon to the broker. If empty, hue-<bridge> is
+	// used.
+	ClientID string
+
+	// Prefix is the first level of every topic. If empty, hue is used.
+	Prefix string
+
+	// Bridge is the second level of every topic. If empty, the bridge ID in
+	// lowercase is used.
+	Bridge string
+
+	// DiscoveryPrefix is the prefix Home Assistant discovery payloads are
+	// published under. If empty, homeassistant is used. If "-", discovery
+	// payloads aren't published.
+	DiscoveryPrefix string
+
+	// Interval is how often the bridge is polled for changes. If 0, 2 seconds
+	// is used.
+	Interval time.Duration
+
+	// OnError is called with errors from commands received on set topics,
+	// which otherwise have nowhere to be returned, and when a command is
+	// dropped because too many are queued
+	OnError func(err error)
+}
+
+// commandQueueSize is the number of commands queued behind the one being sent
+// to the bridge
+const commandQueueSize = 16
+
+// Topics published and subscribed to under <prefix>/<bridge>
+const (
+	topicAvailability = "availability"
+	topicState        = "state"
+	topicSet          = "set"
+)
+
+// Availability payloads
+const (
+	payloadOnline  = "online"
+	payloadOffline = "offline"
+)
+
+// daemon publishes the state of a bridge and handles commands
+type daemon struct {
+	h      *hue.Connection
+	client *Client
+	config Config
+	base   string
+
+	// published contains the payload last published to each retained topic
+	published map[string]string
+
+	mu  sync.Mutex
+	ids map[string]map[string]int
+}
+
+// Run connects to the broker and publishes the state of every light, group,
+// and sensor as retained JSON messages to <prefix>/<bridge>/<resource>/<name>/state,
+// where name is the resource's name in lowercase with runs of other characters
+// replaced by _. Lights and groups are changed by publishing JSON commands in
+// the Home Assistant JSON schema to .../set. Home Assistant discovery payloads
+// are also published, so lights and sensors appear automatically.
+//
+// Run blocks until the context is done, when it marks the bridge offline and
+// disconnects, or until the connection to the broker is lost.
+func Run(ctx context.Context, h *hue.Connection, config Config) error {
+	// Error checking
+	if config.Broker == "" {
+		return errors.New("Broker must not be empty")
+	}
+
+	if config.Prefix == "" {
+		config.Prefix = "hue"
+	}
+	if config.DiscoveryPrefix == "" {
+		config.DiscoveryPrefix = "homeassistant"
+	}
+	if config.Interval == 0 {
+		config.Interval = 2 * time.Second
+	}
+
+	if config.Bridge == "" {
+		c, err := h.GetConfiguration()
+		if err != nil {
+			return err
+		}
+
+		config.Bridge = slug(c.BridgeID)
+		if config.Bridge == "" {
+			config.Bridge = "bridge"
+		}
+	}
+
+	if config.ClientID == "" {
+		config.ClientID = "hue-" + config.Bridge
+	}
+
+	d := &daemon{
+		h:         h,
+		config:    config,
+		base:      config.Prefix + "/" + config.Bridge,
+		published: map[string]string{},
+		ids:       map[string]map[string]int{},
+	}
+
+	watcher, err := h.Watch(config.Interval)
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	client, err := Dial(ctx, config.Broker, ClientOptions{
+		ClientID: config.ClientID,
+		Username: config.Username,
+		Password: config.Password,
+		Will:     &Message{Topic: d.base + "/" + topicAvailability, Payload: []byte(payloadOffline), Retain: true},
+	})
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect()
+
+	d.client = client
+
+	err = client.Subscribe(d.base+"/"+hue.ResourceLights+"/+/"+topicSet, d.base+"/"+hue.ResourceGroups+"/+/"+topicSet)
+	if err != nil {
+		return err
+	}
+
+	err = d.publish(topicAvailability, payloadOnline)
+	if err != nil {
+		return err
+	}
+
+	snapshots, unsubscribe := watcher.Subscribe()
+	defer unsubscribe()
+
+	// Commands are sent one at a time so they reach the bridge in order
+	commands := make(chan Message, commandQueueSize)
+	handled := make(chan struct{})
+	go d.handleCommands(ctx, commands, handled)
+	defer func() {
+		close(commands)
+		<-handled
+	}()
+
+	messages := client.Messages()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return d.publish(topicAvailability, payloadOffline)
+		case <-client.Done():
+			return fmt.Errorf("Connection to broker lost: %w", client.Err())
+		case s, ok := <-snapshots:
+			if !ok {
+				return watcher.Err()
+			}
+
+			err := d.publishSnapshot(s)
+			if err != nil {
+				return err
+			}
+		case m, ok := <-messages:
+			if !ok {
+				// Wait for Done to report the error
+				messages = nil
+				continue
+			}
+
+			select {
+			case commands <- m:
+			default:
+				d.reportError(fmt.Errorf("Too many commands queued, dropped command for %s", m.Topic))
+			}
+		}
+	}
+}
+
+// handleCommands sends the commands to the bridge one at a time until the
+// channel is closed, then closes done
+func (d *daemon) handleCommands(ctx context.Context, commands <-chan Message, done chan<- struct{}) {
+	defer close(done)
+
+	for m := range commands {
+		d.reportError(d.handleCommand(ctx, m))
+	}
+}
+
+// reportError calls OnError with the error, if both are set
+func (d *daemon) reportError(err error) {
+	if err != nil && d.config.OnError != nil {
+		d.config.OnError(err)
+	}
+}
+
+// publish publishes a retained payload to a topic under <prefix>/<bridge>
+func (d *daemon) publish(topic, payload string) error {
+	return d.client.Publish(Message{Topic: d.base + "/" + topic, Payload: []byte(payload), Retain: true})
+}
+
+// publishSnapshot publishes every retained message whose payload changed, and
+// clears the topics that are no longer used
+func (d *daemon) publishSnapshot(s hue.Snapshot) error {
+	messages, ids := d.messages(s)
+
+	d.mu.Lock()
+	d.ids = ids
+	d.mu.Unlock()
+
+	for topic, payload := range messages {
+		if d.published[topic] == payload {
+			continue
+		}
+
+		err := d.client.Publish(Message{Topic: topic, Payload: []byte(payload), Retain: true})
+		if err != nil {
+			return err
+		}
+		d.published[topic] = payload
+	}
+
+	for topic := range d.published {
+		if _, ok := messages[topic]; ok {
+			continue
+		}
+
+		err := d.client.Publish(Message{Topic: topic, Retain: true})
+		if err != nil {
+			return err
+		}
+		delete(d.published, topic)
+	}
+
+	return nil
+}
+
+// messages returns the payload of every retained topic for a snapshot, and
+// the ID of each light and group by topic name
+func (d *daemon) messages(s hue.Snapshot) (map[string]string, map[string]map[string]int) {
+	messages := map[string]string{}
+	ids := map[string]map[string]int{}
+
+	add := func(topic string, value interface{}) {
+		data, _ := json.Marshal(value)
+		messages[topic] = string(data)
+	}
+
+	names := func(resource string) func(name string, id int) string {
+		ids[resource] = map[string]int{}
+
+		return func(name string, id int) string {
+			n := slug(name)
+			if _, ok := ids[resource][n]; ok || n == "" {
+				n = strings.TrimPrefix(n+"_"+strconv.Itoa(id), "_")
+			}
+			ids[resource][n] = id
+
+			return d.base + "/" + resource + "/" + n + "/" + topicState
+		}
+	}
+
+	lightTopic := names(hue.ResourceLights)
+	for _, l := range s.Lights {
+		topic := lightTopic(l.Name, l.ID)
+
+		state := lightPayload(l.State.On, l.State.Bri, l.State.XY, l.State.CT, l.State.ColorMode)
+		reachable := l.State.Reachable
+		state.Reachable = &reachable
+		add(topic, state)
+
+		if d.discovery() {
+			uid := d.config.Bridge + "_light_" + strconv.Itoa(l.ID)
+			add(d.config.DiscoveryPrefix+"/light/"+uid+"/config", d.lightDiscovery(uid, l.Name, topic, l.State.XY, l.State.CT, l.State.Bri,
+				discoveryDevice{Identifiers: []string{uid}, Name: l.Name, Manufacturer: l.ManufacturerName, Model: l.ModelID}))
+		}
+	}
+
+	groupTopic := names(hue.ResourceGroups)
+	for _, g := range s.Groups {
+		topic := groupTopic(g.Name, g.ID)
+
+		add(topic, lightPayload(g.State.AnyOn, g.Action.Bri, g.Action.XY, g.Action.CT, g.Action.ColorMode))
+
+		if d.discovery() {
+			uid := d.config.Bridge + "_group_" + strconv.Itoa(g.ID)
+			add(d.config.DiscoveryPrefix+"/light/"+uid+"/config", d.lightDiscovery(uid, g.Name, topic, g.Action.XY, g.Action.CT, g.Action.Bri,
+				discoveryDevice{Identifiers: []string{uid}, Name: g.Name, Model: string(g.Type)}))
+		}
+	}
+
+	sensorTopic := names(hue.ResourceSensors)
+	for _, sensor := range s.Sensors {
+		topic := sensorTopic(sensor.Name, sensor.ID)
+
+		state := sensorPayload(sensor)
+		add(topic, state)
+
+		if !d.discovery() {
+			continue
+		}
+
+		uid := d.config.Bridge + "_sensor_" + strconv.Itoa(sensor.ID)
+		device := discoveryDevice{Identifiers: []string{uid}, Name: sensor.Name, Manufacturer: sensor.ManufacturerName, Model: sensor.ModelID}
+
+		readings := []struct {
+			component string
+			field     string
+			class     string
+			unit      string
+			present   bool
+		}{
+			{"binary_sensor", "presence", "motion", "", state.Presence != nil},
+			{"sensor", "temperature", "temperature", "°C", state.Temperature != nil},
+			{"sensor", "illuminance", "illuminance", "lx", state.Illuminance != nil},
+			{"sensor", "battery", "battery", "%", state.Battery != 0},
+		}
+
+		for _, r := range readings {
+			if !r.present {
+				continue
+			}
+
+			template := "{{ value_json." + r.field + " }}"
+			if r.component == "binary_sensor" {
+				template = "{{ 'ON' if value_json." + r.field + " else 'OFF' }}"
+			}
+
+			add(d.config.DiscoveryPrefix+"/"+r.component+"/"+uid+"_"+r.field+"/config", discoveryConfig{
+				Name:              sensor.Name + " " + r.field,
+				UniqueID:          uid + "_" + r.field,
+				StateTopic:        topic,
+				DeviceClass:       r.class,
+				UnitOfMeasurement: r.unit,
+				ValueTemplate:     template,
+				AvailabilityTopic: d.base + "/" + topicAvailability,
+				Device:            device,
+			})
+		}
+	}
+
+	return messages, ids
+}
+
+func (d *daemon) discovery() bool {
+	return d.config.DiscoveryPrefix != "-"
+}
+
+// lightDiscovery returns the discovery payload for a light or group, with the
+// color modes supported by its current state
+func (d *daemon) lightDiscovery(uid, name, topic string, xy []float32, ct, bri int, device discoveryDevice) discoveryConfig {
+	modes := []string{}
+	if len(xy) == 2 {
+		modes = append(modes, "xy")
+	}
+	if ct != 0 {
+		modes = append(modes, "color_temp")
+	}
+	if len(modes) == 0 {
+		if bri != 0 {
+			modes = append(modes, "brightness")
+		} else {
+			modes = append(modes, "onoff")
+		}
+	}
+
+	brightness := modes[0] != "onoff"
+	scale := 0
+	if brightness {
+		scale = 254
+	}
+
+	return discoveryConfig{
+		Name:                name,
+		UniqueID:            uid,
+		Schema:              "json",
+		StateTopic:          topic,
+		CommandTopic:        strings.TrimSuffix(topic, topicState) + topicSet,
+		Brightness:          brightness,
+		BrightnessScale:     scale,
+		SupportedColorModes: modes,
+		AvailabilityTopic:   d.base + "/" + topicAvailability,
+		Device:              device,
+	}
+}
+
+// handleCommand changes the state of the light or group of a set topic
+func (d *daemon) handleCommand(ctx context.Context, m Message) error {
+	levels := strings.Split(strings.TrimPrefix(m.Topic, d.base+"/"), "/")
+	if len(levels) != 3 || levels[2] != topicSet {
+		return nil
+	}
+
+	resource, name := levels[0], levels[1]
+
+	var c command
+	err := parseCommand(m.Payload, &c)
+	if err != nil {
+		return fmt.Errorf("Invalid command for %s: %w", m.Topic, err)
+	}
+
+	d.mu.Lock()
+	id, ok := d.ids[resource][name]
+	d.mu.Unlock()
+
+	var on, off func() error
+	var fade func(keyframes []hue.FadeKeyframe) error
+
+	switch resource {
+	case hue.ResourceLights:
+		if !ok {
+			id, err = d.h.ResolveLight(name)
+			if err != nil {
+				return err
+			}
+		}
+
+		on = func() error { return d.h.TurnOnLight(id) }
+		off = func() error { return d.h.TurnOffLight(id) }
+		fade = func(k []hue.FadeKeyframe) error { return d.h.FadeLight(ctx, id, k) }
+	case hue.ResourceGroups:
+		if !ok {
+			id, err = d.h.ResolveGroup(name)
+			if err != nil {
+				return err
+			}
+		}
+
+		on = func() error { return d.h.TurnOnGroup(id) }
+		off = func() error { return d.h.TurnOffGroup(id) }
+		fade = func(k []hue.FadeKeyframe) error { return d.h.FadeGroup(ctx, id, k) }
+	default:
+		return nil
+	}
+
+	switch {
+	case c.State == "OFF":
+		return off()
+	case c.Brightness != 0 || c.Color != nil || c.ColorTemp != 0:
+		k := hue.FadeKeyframe{
+			Bri:      c.Brightness,
+			CT:       c.ColorTemp,
+			Duration: time.Duration(c.Transition * float64(time.Second)),
+		}
+		if c.Color != nil {
+			k.XY = []float32{c.Color.X, c.Color.Y}
+		}
+
+		return fade([]hue.FadeKeyframe{k})
+	case c.State == "ON":
+		return on()
+	}
+
+	return fmt.Errorf("Invalid command for %s: state, brightness, color, or color_temp must be set", m.Topic)
+}
+
+// command is a command in the Home Assistant JSON schema
+type command struct {
+	State      string        `json:"state"`
+	Brightness int           `json:"brightness"`
+	Color      *colorPayload `json:"color"`
+	ColorTemp  int           `json:"color_temp"`
+	Transition float64       `json:"transition"`
+}
+
+// parseCommand parses a JSON command. A payload of ON or OFF is also accepted.
+func parseCommand(payload []byte, c *command) error {
+	switch s := strings.TrimSpace(string(payload)); s {
+	case "ON", "OFF":
+		c.State = s
+		return nil
+	}
+
+	err := json.Unmarshal(payload, c)
+	if err != nil {
+		return err
+	}
+
+	c.State = strings.ToUpper(c.State)
+	if c.State != "" && c.State != "ON" && c.State != "OFF" {
+		return errors.New("state must be ON or OFF")
+	}
+
+	if c.Brightness < 0 || c.Brightness > 254 {
+		return errors.New("brightness must be between 1 and 254")
+	}
+
+	if c.Transition < 0 {
+		return errors.New("transition must not be negative")
+	}
+
+	return nil
+}
+
+// statePayload is the state of a light or group in the Home Assistant JSON
+// schema
+type statePayload struct {
+	State      string        `json:"state"`
+	Brightness int           `json:"brightness,omitempty"`
+	ColorMode  string        `json:"color_mode,omitempty"`
+	Color      *colorPayload `json:"color,omitempty"`
+	ColorTemp  int           `json:"color_temp,omitempty"`
+	Reachable  *bool         `json:"reachable,omitempty"`
+}
+
+type colorPayload struct {
+	X float32 `json:"x"`
+	Y float32 `json:"y"`
+}
+
+func lightPayload(on bool, bri int, xy []float32, ct int, colorMode string) statePayload {
+	state := statePayload{State: "OFF", Brightness: bri, ColorTemp: ct}
+	if on {
+		state.State = "ON"
+	}
+
+	if len(xy) == 2 {
+		state.Color = &colorPayload{X: xy[0], Y: xy[1]}
+	}
+
+	switch {
+	case colorMode == "ct" && ct != 0:
+		state.ColorMode = "color_temp"
+	case state.Color != nil:
+		state.ColorMode = "xy"
+	case ct != 0:
+		state.ColorMode = "color_temp"
+	case bri != 0:
+		state.ColorMode = "brightness"
+	default:
+		state.ColorMode = "onoff"
+	}
+
+	return state
+}
+
+// sensorState is the state of a sensor. Only the readings reported by the
+// type of sensor are set.
+type sensorState struct {
+	Presence    *bool    `json:"presence,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	Illuminance *float64 `json:"illuminance,omitempty"`
+	Dark        *bool    `json:"dark,omitempty"`
+	Daylight    *bool    `json:"daylight,omitempty"`
+	ButtonEvent int      `json:"buttonevent,omitempty"`
+	Battery     int      `json:"battery,omitempty"`
+	LastUpdated string   `json:"last_updated,omitempty"`
+}
+
+func sensorPayload(s hue.Sensor) sensorState {
+	state := sensorState{Battery: s.Config.Battery, LastUpdated: s.State.LastUpdated}
+
+	switch strings.TrimPrefix(strings.TrimPrefix(s.Type, "ZLL"), "CLIP") {
+	case "Presence":
+		state.Presence = &s.State.Presence
+	case "Temperature":
+		celsius := s.State.Celsius()
+		state.Temperature = &celsius
+	case "LightLevel":
+		lux := float64(int(s.State.Lux()*10+0.5)) / 10
+		state.Illuminance = &lux
+		state.Dark = &s.State.Dark
+		state.Daylight = &s.State.Daylight
+	case "Daylight":
+		state.Daylight = &s.State.Daylight
+	case "Switch":
+		state.ButtonEvent = s.State.ButtonEvent
+	}
+
+	return state
+}
+
+// discoveryConfig is a Home Assistant MQTT discovery payload
+type discoveryConfig struct {
+	Name                string          `json:"name"`
+	UniqueID            string          `json:"unique_id"`
+	Schema              string          `json:"schema,omitempty"`
+	StateTopic          string          `json:"state_topic"`
+	CommandTopic        string          `json:"command_topic,omitempty"`
+	Brightness          bool            `json:"brightness,omitempty"`
+	BrightnessScale     int             `json:"brightness_scale,omitempty"`
+	SupportedColorModes []string        `json:"supported_color_modes,omitempty"`
+	DeviceClass         string          `json:"device_class,omitempty"`
+	UnitOfMeasurement   string          `json:"unit_of_measurement,omitempty"`
+	ValueTemplate       string          `json:"value_template,omitempty"`
+	AvailabilityTopic   string          `json:"availability_topic"`
+	Device              discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+	Model        string   `json:"model,omitempty"`
+}
+
+// slug converts a name to lowercase, replacing each run of characters other
+// than letters and digits with _
+func slug(name string) string {
+	var b strings.Builder
+	separate := false
+
+	for _, r := range strings.ToLower(name) {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			separate = true
+			continue
+		}
+
+		if separate && b.Len() > 0 {
+			b.WriteByte('_')
+		}
+		separate = false
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
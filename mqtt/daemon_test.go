@@ -0,0 +1,238 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattvella07/hue/huetest"
+)
+
+// retainedJSON decodes the retained message of a topic
+func retainedJSON(b *Broker, topic string, value interface{}) bool {
+	payload, ok := b.Retained(topic)
+	if !ok {
+		return false
+	}
+
+	return json.Unmarshal(payload, value) == nil
+}
+
+func TestRun(t *testing.T) {
+	s := huetest.NewServer()
+	defer s.Close()
+
+	s.Bridge.AddLight("Desk", huetest.ExtendedColorLight)
+	s.Bridge.AddLight("Floor lamp", huetest.ColorTemperatureLight)
+	s.Bridge.AddGroup("Office", "Room", []string{"1", "2"})
+	s.Bridge.AddSensor("Hall", huetest.TemperatureSensor)
+	s.Bridge.SetSensorState("1", map[string]interface{}{"temperature": float64(2150)})
+
+	b, address := createTestBroker(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	errs := []error{}
+	result := make(chan error, 1)
+
+	go func() {
+		result <- Run(ctx, s.Connection(), Config{
+			Broker:   address,
+			Interval: 50 * time.Millisecond,
+			OnError: func(err error) {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			},
+		})
+	}()
+
+	base := "hue/001788fffe4e7a11"
+
+	waitFor(t, func() bool {
+		_, ok := b.Retained(base + "/sensors/hall/state")
+		return ok
+	})
+
+	t.Run("Availability", func(t *testing.T) {
+		payload, _ := b.Retained(base + "/availability")
+		if string(payload) != "online" {
+			t.Errorf("Expected online, got %q", payload)
+		}
+	})
+
+	t.Run("State", func(t *testing.T) {
+		var state statePayload
+		if !retainedJSON(b, base+"/lights/floor_lamp/state", &state) {
+			t.Fatal("Expected state of Floor lamp to be retained")
+		}
+
+		if state.State != "OFF" && state.State != "ON" {
+			t.Errorf("Expected state to be ON or OFF, got %q", state.State)
+		}
+
+		if state.Reachable == nil || !*state.Reachable {
+			t.Error("Expected light to be reachable")
+		}
+
+		var sensor sensorState
+		if !retainedJSON(b, base+"/sensors/hall/state", &sensor) {
+			t.Fatal("Expected state of Hall to be retained")
+		}
+
+		if sensor.Temperature == nil || *sensor.Temperature != 21.5 {
+			t.Errorf("Expected temperature of 21.5, got %v", sensor.Temperature)
+		}
+
+		if _, ok := b.Retained(base + "/groups/office/state"); !ok {
+			t.Error("Expected state of Office to be retained")
+		}
+	})
+
+	t.Run("Discovery", func(t *testing.T) {
+		var config discoveryConfig
+		if !retainedJSON(b, "homeassistant/light/001788fffe4e7a11_light_1/config", &config) {
+			t.Fatal("Expected discovery payload for Desk")
+		}
+
+		if config.StateTopic != base+"/lights/desk/state" || config.CommandTopic != base+"/lights/desk/set" || config.Schema != "json" {
+			t.Errorf("Unexpected discovery payload %+v", config)
+		}
+
+		if config.AvailabilityTopic != base+"/availability" {
+			t.Errorf("Expected availability topic %s, got %s", base+"/availability", config.AvailabilityTopic)
+		}
+
+		if !retainedJSON(b, "homeassistant/sensor/001788fffe4e7a11_sensor_1_temperature/config", &config) {
+			t.Fatal("Expected discovery payload for Hall")
+		}
+
+		if config.DeviceClass != "temperature" || config.UnitOfMeasurement != "°C" {
+			t.Errorf("Unexpected discovery payload %+v", config)
+		}
+	})
+
+	c := dial(t, address, ClientOptions{})
+	defer c.Disconnect()
+
+	t.Run("Set", func(t *testing.T) {
+		c.Publish(Message{Topic: base + "/lights/desk/set", Payload: []byte(`{"state":"ON","brightness":100,"color":{"x":0.6,"y":0.3}}`)})
+
+		waitFor(t, func() bool {
+			var state statePayload
+			return retainedJSON(b, base+"/lights/desk/state", &state) && state.State == "ON" && state.Brightness == 100 && state.ColorMode == "xy"
+		})
+
+		c.Publish(Message{Topic: base + "/groups/office/set", Payload: []byte("OFF")})
+
+		waitFor(t, func() bool {
+			var state statePayload
+			return retainedJSON(b, base+"/groups/office/state", &state) && state.State == "OFF"
+		})
+	})
+
+	t.Run("Commands in order", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			c.Publish(Message{Topic: base + "/lights/desk/set", Payload: []byte(`{"state":"ON","brightness":200}`)})
+			c.Publish(Message{Topic: base + "/lights/desk/set", Payload: []byte("OFF")})
+		}
+		c.Publish(Message{Topic: base + "/lights/desk/set", Payload: []byte(`{"state":"ON","brightness":42}`)})
+
+		waitFor(t, func() bool {
+			light, err := s.Connection().GetLight(1)
+			return err == nil && light.State.On && light.State.Bri == 42
+		})
+
+		// No earlier command arrives after the last one
+		time.Sleep(100 * time.Millisecond)
+
+		light, err := s.Connection().GetLight(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !light.State.On || light.State.Bri != 42 {
+			t.Errorf("Expected the last command to win, got %+v", light.State)
+		}
+	})
+
+	t.Run("Renamed", func(t *testing.T) {
+		err := s.Connection().RenameLight(2, "Reading lamp")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		waitFor(t, func() bool {
+			_, old := b.Retained(base + "/lights/floor_lamp/state")
+			_, renamed := b.Retained(base + "/lights/reading_lamp/state")
+			return !old && renamed
+		})
+	})
+
+	cancel()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Run to return")
+	}
+
+	waitFor(t, func() bool {
+		payload, _ := b.Retained(base + "/availability")
+		return string(payload) == "offline"
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(errs) > 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		valid   bool
+	}{
+		{"On", "ON", true},
+		{"JSON", `{"state":"on","brightness":254,"transition":1.5}`, true},
+		{"Invalid state", `{"state":"toggle"}`, false},
+		{"Invalid brightness", `{"brightness":300}`, false},
+		{"Negative transition", `{"state":"ON","transition":-1}`, false},
+		{"Invalid JSON", "{", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var c command
+			err := parseCommand([]byte(test.payload), &c)
+			if (err == nil) != test.valid {
+				t.Errorf("Expected valid to be %t, got error %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"Desk":              "desk",
+		"Floor lamp":        "floor_lamp",
+		"  Kid's room (2) ": "kid_s_room_2",
+		"!!!":               "",
+	}
+
+	for name, expected := range tests {
+		if actual := slug(name); actual != expected {
+			t.Errorf("Expected %q, got %q", expected, actual)
+		}
+	}
+}
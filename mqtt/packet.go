@@ -0,0 +1,236 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Packet types used by MQTT 3.1.1
+const (
+	packetConnect     = 1
+	packetConnAck     = 2
+	packetPublish     = 3
+	packetPubAck      = 4
+	packetSubscribe   = 8
+	packetSubAck      = 9
+	packetUnsubscribe = 10
+	packetUnsubAck    = 11
+	packetPingReq     = 12
+	packetPingResp    = 13
+	packetDisconnect  = 14
+)
+
+// maxPacketSize is the largest packet accepted, in bytes
+const maxPacketSize = 1 << 20
+
+// Message is a message published to a topic
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// packet is a single MQTT control packet
+type packet struct {
+	// header is the first byte of the fixed header, containing the packet
+	// type and flags
+	header byte
+	body   []byte
+}
+
+func (p packet) packetType() byte {
+	return p.header >> 4
+}
+
+// readPacket reads a single packet
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	// The remaining length is encoded in up to 4 bytes, 7 bits at a time
+	length := 0
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errors.New("Invalid remaining length")
+		}
+
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+
+		length |= int(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	if length > maxPacketSize {
+		return packet{}, fmt.Errorf("Packet of %d bytes is too large", length)
+	}
+
+	body := make([]byte, length)
+
+	_, err = io.ReadFull(r, body)
+	if err != nil {
+		return packet{}, err
+	}
+
+	return packet{header: header, body: body}, nil
+}
+
+// writePacket writes a single packet
+func writePacket(w io.Writer, p packet) error {
+	data := []byte{p.header}
+
+	length := len(p.body)
+	for {
+		b := byte(length & 0x7F)
+		length >>= 7
+		if length > 0 {
+			b |= 0x80
+		}
+		data = append(data, b)
+
+		if length == 0 {
+			break
+		}
+	}
+
+	_, err := w.Write(append(data, p.body...))
+
+	return err
+}
+
+// appendString appends a length prefixed UTF-8 string
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b []byte, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+// reader reads the fields of a packet body
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) bytes() []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if len(r.data) < 2 {
+		r.err = errors.New("Packet is too short")
+		return nil
+	}
+
+	n := int(binary.BigEndian.Uint16(r.data))
+	if len(r.data) < 2+n {
+		r.err = errors.New("Packet is too short")
+		return nil
+	}
+
+	value := r.data[2 : 2+n]
+	r.data = r.data[2+n:]
+
+	return value
+}
+
+func (r *reader) string() string {
+	return string(r.bytes())
+}
+
+func (r *reader) uint16() uint16 {
+	if r.err != nil {
+		return 0
+	}
+
+	if len(r.data) < 2 {
+		r.err = errors.New("Packet is too short")
+		return 0
+	}
+
+	value := binary.BigEndian.Uint16(r.data)
+	r.data = r.data[2:]
+
+	return value
+}
+
+func (r *reader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+
+	if len(r.data) < 1 {
+		r.err = errors.New("Packet is too short")
+		return 0
+	}
+
+	value := r.data[0]
+	r.data = r.data[1:]
+
+	return value
+}
+
+// publishPacket creates a PUBLISH packet with QoS 0
+func publishPacket(m Message) packet {
+	header := byte(packetPublish << 4)
+	if m.Retain {
+		header |= 0x01
+	}
+
+	return packet{header: header, body: append(appendString(nil, m.Topic), m.Payload...)}
+}
+
+// parsePublish parses a PUBLISH packet. The packet ID is returned for QoS 1
+// and 2, and is 0 otherwise.
+func parsePublish(p packet) (Message, byte, uint16, error) {
+	qos := (p.header >> 1) & 0x03
+
+	r := &reader{data: p.body}
+	topic := r.string()
+
+	var id uint16
+	if qos > 0 {
+		id = r.uint16()
+	}
+
+	if r.err != nil {
+		return Message{}, 0, 0, r.err
+	}
+
+	return Message{Topic: topic, Payload: r.data, Retain: p.header&0x01 != 0}, qos, id, nil
+}
+
+// MatchTopic returns true if the topic matches the filter, which may contain
+// the + and # wildcards
+func MatchTopic(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+
+		if i >= len(topicLevels) {
+			return false
+		}
+
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
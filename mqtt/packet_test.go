@@ -0,0 +1,82 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPackets(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		for _, size := range []int{0, 127, 128, 16383, 16384, 300000} {
+			buf := &bytes.Buffer{}
+			sent := packet{header: packetPublish<<4 | 0x01, body: bytes.Repeat([]byte{'x'}, size)}
+
+			err := writePacket(buf, sent)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			received, err := readPacket(bufio.NewReader(buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if received.header != sent.header || !bytes.Equal(received.body, sent.body) {
+				t.Errorf("Expected packet with %d bytes to be unchanged", size)
+			}
+		}
+	})
+
+	t.Run("Publish", func(t *testing.T) {
+		m, qos, _, err := parsePublish(publishPacket(Message{Topic: "hue/lights", Payload: []byte("on"), Retain: true}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := Message{Topic: "hue/lights", Payload: []byte("on"), Retain: true}
+			if m.Topic != expected.Topic || string(m.Payload) != string(expected.Payload) || m.Retain != expected.Retain || qos != 0 {
+				t.Errorf("Expected %v, got %v with QoS %d", expected, m, qos)
+			}
+		}
+	})
+
+	t.Run("Too short", func(t *testing.T) {
+		_, _, _, err := parsePublish(packet{header: packetPublish << 4, body: []byte{0, 5, 'h'}})
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("Invalid remaining length", func(t *testing.T) {
+		_, err := readPacket(bufio.NewReader(strings.NewReader("\x30\xff\xff\xff\xff\x01")))
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		expected      bool
+	}{
+		{"hue/lights", "hue/lights", true},
+		{"hue/lights", "hue/groups", false},
+		{"hue/+/state", "hue/desk/state", true},
+		{"hue/+/state", "hue/desk/set", false},
+		{"hue/+", "hue/desk/state", false},
+		{"hue/#", "hue/desk/state", true},
+		{"hue/#", "hue", true},
+		{"#", "hue/desk", true},
+		{"hue/desk/state/#", "hue/desk", false},
+	}
+
+	for _, test := range tests {
+		if actual := MatchTopic(test.filter, test.topic); actual != test.expected {
+			t.Errorf("Expected MatchTopic(%q, %q) to be %t, got %t", test.filter, test.topic, test.expected, actual)
+		}
+	}
+}
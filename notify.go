@@ -0,0 +1,215 @@
+package hue
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NotifyPattern contains the colors a notification flashes and how often
+// they're flashed
+type NotifyPattern struct {
+	// Colors contains the xy colors flashed, in order, for each repetition
+	Colors [][]float32
+
+	// Bri is the brightness of each flash. If 0, full brightness is used.
+	Bri int
+
+	// Count is the number of times the colors are flashed. If 0, they're
+	// flashed once.
+	Count int
+
+	// OnDuration and OffDuration are how long each flash stays on and off.
+	// If 0, 500ms is used.
+	OnDuration  time.Duration
+	OffDuration time.Duration
+}
+
+// Built in notification patterns
+var (
+	// NotifyFailure flashes red three times
+	NotifyFailure = NotifyPattern{
+		Colors: [][]float32{{0.675, 0.322}},
+		Count:  3,
+	}
+
+	// NotifySuccess flashes green twice
+	NotifySuccess = NotifyPattern{
+		Colors: [][]float32{{0.2, 0.7}},
+		Count:  2,
+	}
+
+	// NotifyDoorbell alternates between white and blue twice
+	NotifyDoorbell = NotifyPattern{
+		Colors: [][]float32{{0.3227, 0.329}, {0.167, 0.04}},
+		Count:  2,
+	}
+)
+
+// NotifyPatterns contains all built in notification patterns by name
+var NotifyPatterns = map[string]NotifyPattern{
+	"failure":  NotifyFailure,
+	"success":  NotifySuccess,
+	"doorbell": NotifyDoorbell,
+}
+
+// Notify flashes the pattern on the target lights and groups, then restores every
+// affected light to the state it was in before. Notify blocks until the pattern
+// completes or the context is done, and the lights are restored in either case.
+func (h *Connection) Notify(ctx context.Context, target EffectTarget, pattern NotifyPattern) (err error) {
+	// Error checking
+	if len(target.Lights) == 0 && len(target.Groups) == 0 {
+		return errors.New("Target must contain at least one light or group")
+	}
+
+	if len(pattern.Colors) == 0 {
+		return errors.New("Pattern colors must not be empty")
+	}
+
+	for _, xy := range pattern.Colors {
+		if len(xy) != 2 || xy[0] < 0 || xy[0] > 1 || xy[1] < 0 || xy[1] > 1 {
+			return errors.New("Invalid pattern: each color must contain an x and y value between 0 and 1")
+		}
+	}
+
+	if pattern.Bri == 0 {
+		pattern.Bri = 254
+	}
+
+	if pattern.Bri < 1 || pattern.Bri > 254 {
+		return errors.New("Invalid brightness value: bri must be between 1 and 254")
+	}
+
+	if pattern.Count <= 0 {
+		pattern.Count = 1
+	}
+
+	if pattern.OnDuration <= 0 {
+		pattern.OnDuration = 500 * time.Millisecond
+	}
+
+	if pattern.OffDuration <= 0 {
+		pattern.OffDuration = 500 * time.Millisecond
+	}
+
+	saved, err := h.saveEffectTarget(target)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		restoreErr := h.restoreLights(saved)
+		if err == nil {
+			err = restoreErr
+		}
+	}()
+
+	for i := 0; i < pattern.Count; i++ {
+		for _, xy := range pattern.Colors {
+			err = h.sendToTarget(ctx, target, EffectState{On: true, Bri: pattern.Bri, XY: xy})
+			if err != nil {
+				return err
+			}
+
+			err = sleepContext(ctx, pattern.OnDuration)
+			if err != nil {
+				return err
+			}
+
+			err = h.sendToTarget(ctx, target, EffectState{On: false})
+			if err != nil {
+				return err
+			}
+
+			err = sleepContext(ctx, pattern.OffDuration)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// notifyQueueSize is the number of notifications NotifyHandler queues behind
+// the one being flashed
+const notifyQueueSize = 8
+
+// NotifyHandler returns an http.Handler that triggers notifications on the target
+// lights and groups, for use with local webhooks. A POST to /<name> flashes the
+// pattern with that name. Notifications run one at a time in the background so
+// they never overlap, and a POST gets 503 Service Unavailable when the queue is
+// full. onError, if not nil, is called with the error of each failed notification.
+// When the context is done, the notification being flashed is cancelled, queued
+// ones are dropped, and later POSTs also get 503 Service Unavailable.
+func (h *Connection) NotifyHandler(ctx context.Context, target EffectTarget, patterns map[string]NotifyPattern, onError func(err error)) http.Handler {
+	if patterns == nil {
+		patterns = NotifyPatterns
+	}
+
+	queue := make(chan NotifyPattern, notifyQueueSize)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case pattern := <-queue:
+				err := h.Notify(ctx, target, pattern)
+				if err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "Method must be POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.Trim(r.URL.Path, "/")
+		pattern, ok := patterns[name]
+		if !ok {
+			http.Error(w, "Pattern "+name+" not found", http.StatusNotFound)
+			return
+		}
+
+		if ctx.Err() != nil {
+			http.Error(w, "Notifications have stopped", http.StatusServiceUnavailable)
+			return
+		}
+
+		select {
+		case queue <- pattern:
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			http.Error(w, "Too many notifications queued", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// sendToTarget sends the same state to every light and group in the target
+func (h *Connection) sendToTarget(ctx context.Context, target EffectTarget, state EffectState) error {
+	cmd := newEffectCommand(state)
+
+	for _, light := range target.Lights {
+		err := h.changeLightStateContext(ctx, light, cmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, group := range target.Groups {
+		err := h.updateGroupContext(ctx, group, "state", cmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
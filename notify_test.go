@@ -0,0 +1,264 @@
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Successful notification", func(t *testing.T) {
+		h, server, requests := createSequenceConnection(nil)
+		defer server.Close()
+
+		pattern := NotifyPattern{
+			Colors:      [][]float32{{0.675, 0.322}, {0.167, 0.04}},
+			Count:       2,
+			OnDuration:  time.Millisecond,
+			OffDuration: time.Millisecond,
+		}
+
+		err := h.Notify(context.Background(), EffectTarget{Lights: []int{1}}, pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		red := `{"on":true,"bri":254,"xy":[0.675,0.322],"transitiontime":0}`
+		blue := `{"on":true,"bri":254,"xy":[0.167,0.04],"transitiontime":0}`
+		off := `{"on":false,"transitiontime":0}`
+
+		// Each color is flashed on then off, then light 1 is restored to its
+		// brightness and turned off
+		expected := []string{red, off, blue, off, red, off, blue, off, `{"on":true,"bri":100,"transitiontime":0}`, `{"on":false}`}
+
+		got := requests()
+		if len(got) != len(expected) {
+			t.Fatalf("Expected %d requests, got %d: %v", len(expected), len(got), got)
+		}
+
+		for i, r := range got {
+			if r.path != "/lights/1/state" {
+				t.Fatalf("Expected request %d to be sent to light 1, got %s", i, r.path)
+			}
+
+			if r.body != expected[i] {
+				t.Fatalf("Expected request %d body to equal %s, got %s", i, expected[i], r.body)
+			}
+		}
+	})
+
+	t.Run("Light that was on is restored", func(t *testing.T) {
+		h, server, requests := createSequenceConnection(nil)
+		defer server.Close()
+
+		err := h.Notify(context.Background(), EffectTarget{Lights: []int{4}}, NotifyPattern{
+			Colors:      [][]float32{{0.2, 0.7}},
+			Bri:         100,
+			OnDuration:  time.Millisecond,
+			OffDuration: time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := requests()
+		if len(got) == 0 {
+			t.Fatal("Expected requests, got none")
+		}
+
+		{
+			expected := `{"on":true,"bri":254}`
+			if last := got[len(got)-1].body; last != expected {
+				t.Fatalf("Expected restore body to equal %s, got %s", expected, last)
+			}
+		}
+	})
+
+	t.Run("Empty target", func(t *testing.T) {
+		err := h.Notify(context.Background(), EffectTarget{}, NotifyFailure)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Target must contain at least one light or group"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Empty pattern", func(t *testing.T) {
+		err := h.Notify(context.Background(), EffectTarget{Lights: []int{1}}, NotifyPattern{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Pattern colors must not be empty"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := h.Notify(ctx, EffectTarget{Lights: []int{1}}, NotifyFailure)
+		if err != context.Canceled {
+			t.Fatalf("Expected error to equal %v, got %v", context.Canceled, err)
+		}
+	})
+}
+
+func TestNotifyHandler(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+
+	handler := h.NotifyHandler(ctx, EffectTarget{Lights: []int{1}}, map[string]NotifyPattern{
+		"build": {
+			Colors:      [][]float32{{0.675, 0.322}},
+			OnDuration:  time.Millisecond,
+			OffDuration: time.Millisecond,
+		},
+	}, func(err error) {
+		errs <- err
+	})
+
+	t.Run("Pattern found", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/build", nil))
+
+		expected := http.StatusAccepted
+		if rec.Code != expected {
+			t.Fatalf("Expected status code %d, got %d", expected, rec.Code)
+		}
+	})
+
+	t.Run("Pattern not found", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/deploy", nil))
+
+		expected := http.StatusNotFound
+		if rec.Code != expected {
+			t.Fatalf("Expected status code %d, got %d", expected, rec.Code)
+		}
+	})
+
+	t.Run("Invalid method", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/build", nil))
+
+		expected := http.StatusMethodNotAllowed
+		if rec.Code != expected {
+			t.Fatalf("Expected status code %d, got %d", expected, rec.Code)
+		}
+	})
+
+	t.Run("Notification fails", func(t *testing.T) {
+		failing := h.NotifyHandler(ctx, EffectTarget{Lights: []int{3}}, nil, func(err error) {
+			errs <- err
+		})
+
+		rec := httptest.NewRecorder()
+		failing.ServeHTTP(rec, httptest.NewRequest("POST", "/success", nil))
+
+		select {
+		case err := <-errs:
+			expected := "Light 3 not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the error to be reported")
+		}
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		stopped, stop := context.WithCancel(context.Background())
+		stop()
+
+		rec := httptest.NewRecorder()
+		h.NotifyHandler(stopped, EffectTarget{Lights: []int{1}}, nil, nil).ServeHTTP(rec, httptest.NewRequest("POST", "/success", nil))
+
+		expected := http.StatusServiceUnavailable
+		if rec.Code != expected {
+			t.Fatalf("Expected status code %d, got %d", expected, rec.Code)
+		}
+	})
+}
+
+func TestNotifyHandlerQueue(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+
+	// The first flash blocks until released, so the worker stays busy
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			returnData, _ := json.Marshal(generateTestData(r.URL.String()))
+			w.Write(returnData)
+			return
+		}
+
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+
+		<-release
+		w.Write([]byte("[{\"success\": {}}]"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	h := Connection{
+		UserID:            "TEST",
+		internalIPAddress: "localhost",
+		baseURL:           server.URL,
+		isInitialized:     true,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := h.NotifyHandler(ctx, EffectTarget{Lights: []int{1}}, nil, nil)
+
+	post := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/success", nil))
+
+		return rec.Code
+	}
+
+	if code := post(); code != http.StatusAccepted {
+		t.Fatalf("Expected status code %d, got %d", http.StatusAccepted, code)
+	}
+
+	<-started
+
+	for i := 0; i < notifyQueueSize; i++ {
+		if code := post(); code != http.StatusAccepted {
+			t.Fatalf("Expected status code %d, got %d", http.StatusAccepted, code)
+		}
+	}
+
+	{
+		expected := http.StatusServiceUnavailable
+		if code := post(); code != expected {
+			t.Fatalf("Expected status code %d, got %d", expected, code)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package hue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Profile contains the settings for connecting to a Phillips Hue bridge
+type Profile struct {
+	// BridgeID is the ID of the bridge, used to discover it
+	BridgeID string `json:"bridgeid,omitempty"`
+
+	// Address is the host and optional port of the bridge. If set, the bridge
+	// isn't discovered.
+	Address string `json:"address,omitempty"`
+
+	// Username is the user on the bridge
+	Username string `json:"username"`
+
+	// Defaults contains settings for applications using the profile, such as
+	// the output format of the command line tool
+	Defaults map[string]string `json:"defaults,omitempty"`
+}
+
+// Connection creates a Connection to the profile's bridge
+func (p Profile) Connection() *Connection {
+	return &Connection{
+		UserID:   p.Username,
+		BridgeID: p.BridgeID,
+		Address:  p.Address,
+	}
+}
+
+// Profiles contains named profiles for one or more bridges, as stored in a
+// configuration file
+type Profiles struct {
+	// Default is the name of the profile used when none is specified
+	Default string `json:"default,omitempty"`
+
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// DefaultProfilesPath returns the path of the profiles configuration file,
+// which is hue/config.json in the user's configuration directory, such as
+// ~/.config/hue/config.json
+func DefaultProfilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "hue", "config.json"), nil
+}
+
+// LoadProfiles reads the profiles configuration file at the path. If the file
+// doesn't exist, no profiles are returned.
+func LoadProfiles(path string) (*Profiles, error) {
+	profiles := &Profiles{Profiles: make(map[string]Profile)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, profiles)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid profiles file %s: %s", path, err)
+	}
+
+	if profiles.Profiles == nil {
+		profiles.Profiles = make(map[string]Profile)
+	}
+
+	return profiles, nil
+}
+
+// Save writes the profiles to the configuration file at the path. The file is
+// only readable by the current user, since it contains usernames.
+func (p *Profiles) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Profile returns the profile with the specified name. If the name is empty,
+// the default profile is returned, or the only profile if there is just one.
+func (p *Profiles) Profile(name string) (Profile, error) {
+	name = strings.Trim(name, " ")
+	if name == "" {
+		name = p.Default
+	}
+
+	if name == "" {
+		if len(p.Profiles) == 1 {
+			for _, profile := range p.Profiles {
+				return profile, nil
+			}
+		}
+
+		return Profile{}, errors.New("No default profile")
+	}
+
+	profile, ok := p.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("Profile %s not found", name)
+	}
+
+	return profile, nil
+}
+
+// Set adds or replaces the profile with the specified name. The first profile
+// added becomes the default.
+func (p *Profiles) Set(name string, profile Profile) error {
+	// Error checking
+	name = strings.Trim(name, " ")
+	if name == "" {
+		return errors.New("Name must not be empty")
+	}
+
+	if strings.Trim(profile.Username, " ") == "" {
+		return errors.New("Username must not be empty")
+	}
+
+	if p.Profiles == nil {
+		p.Profiles = make(map[string]Profile)
+	}
+
+	p.Profiles[name] = profile
+
+	if p.Default == "" {
+		p.Default = name
+	}
+
+	return nil
+}
+
+// Names returns the names of all profiles in order
+func (p *Profiles) Names() []string {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ConnectProfile creates a Connection using the profile with the specified
+// name from the default profiles configuration file. If the name is empty, the
+// default profile is used.
+func ConnectProfile(name string) (*Connection, error) {
+	path, err := DefaultProfilesPath()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := LoadProfiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := profiles.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return profile.Connection(), nil
+}
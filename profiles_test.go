@@ -0,0 +1,160 @@
+package hue
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hue", "config.json")
+
+	t.Run("Missing file", func(t *testing.T) {
+		profiles, err := LoadProfiles(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(profiles.Profiles) != 0 {
+			t.Fatalf("Expected no profiles, got %d", len(profiles.Profiles))
+		}
+
+		_, err = profiles.Profile("")
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+
+	t.Run("Save and load", func(t *testing.T) {
+		profiles, _ := LoadProfiles(path)
+
+		err := profiles.Set("home", Profile{BridgeID: "001788FFFE000001", Username: "abc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = profiles.Set("lab", Profile{Address: "10.0.0.2:8080", Username: "def", Defaults: map[string]string{"output": "json"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = profiles.Save(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Mode().Perm() != 0600 {
+			t.Fatalf("Expected the file to only be readable by the user, got %s", info.Mode())
+		}
+
+		loaded, err := LoadProfiles(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := "home"
+			if loaded.Default != expected {
+				t.Fatalf("Expected Default to equal %s, got %s", expected, loaded.Default)
+			}
+		}
+
+		profile, err := loaded.Profile("lab")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := "json"
+			if profile.Defaults["output"] != expected {
+				t.Fatalf("Expected output default to equal %s, got %s", expected, profile.Defaults["output"])
+			}
+		}
+
+		h := profile.Connection()
+		if h.UserID != "def" || h.Address != "10.0.0.2:8080" {
+			t.Fatalf("Unexpected Connection %s %s", h.UserID, h.Address)
+		}
+
+		// The default profile is used when no name is given
+		profile, err = loaded.Profile("")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if profile.BridgeID != "001788FFFE000001" {
+			t.Fatalf("Expected the home profile, got %+v", profile)
+		}
+	})
+
+	t.Run("Profile not found", func(t *testing.T) {
+		profiles, _ := LoadProfiles(path)
+
+		_, err := profiles.Profile("office")
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Profile office not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Invalid profile", func(t *testing.T) {
+		profiles, _ := LoadProfiles(path)
+
+		err := profiles.Set("office", Profile{})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		err = profiles.Set(" ", Profile{Username: "abc"})
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+}
+
+func TestConnectProfile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	path, err := DefaultProfilesPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := &Profiles{}
+	profiles.Set("home", Profile{Address: "10.0.0.1", Username: "abc"})
+
+	err = profiles.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := ConnectProfile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		expected := "abc"
+		if h.UserID != expected {
+			t.Fatalf("Expected UserID to equal %s, got %s", expected, h.UserID)
+		}
+	}
+
+	_, err = ConnectProfile("office")
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+}
@@ -0,0 +1,54 @@
+package hue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Minimum time between commands sent to the bridge, as recommended by the
+// Phillips Hue API. Light commands are limited to roughly 10 per second and
+// group commands to roughly 1 per second.
+const (
+	lightCommandInterval = 100 * time.Millisecond
+	groupCommandInterval = time.Second
+)
+
+// rateLimiter spaces out commands so the bridge isn't overloaded. Commands
+// are grouped into buckets which are each limited independently.
+type rateLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// wait blocks until a command in the specified bucket is allowed to be sent,
+// or until the context is done
+func (r *rateLimiter) wait(ctx context.Context, bucket string, interval time.Duration) error {
+	r.mu.Lock()
+	if r.next == nil {
+		r.next = make(map[string]time.Time)
+	}
+
+	now := time.Now()
+	at := r.next[bucket]
+	if at.Before(now) {
+		at = now
+	}
+	r.next[bucket] = at.Add(interval)
+	r.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
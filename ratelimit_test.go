@@ -0,0 +1,57 @@
+package hue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	t.Run("Commands are spaced out", func(t *testing.T) {
+		r := rateLimiter{}
+		start := time.Now()
+
+		for i := 0; i < 3; i++ {
+			err := r.wait(context.Background(), "lights", 20*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		expected := 40 * time.Millisecond
+		if elapsed := time.Since(start); elapsed < expected {
+			t.Fatalf("Expected at least %s to elapse, got %s", expected, elapsed)
+		}
+	})
+
+	t.Run("Buckets are independent", func(t *testing.T) {
+		r := rateLimiter{}
+
+		err := r.wait(context.Background(), "lights", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = r.wait(context.Background(), "groups", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		r := rateLimiter{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		err := r.wait(ctx, "lights", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cancel()
+
+		err = r.wait(ctx, "lights", time.Hour)
+		if err != context.Canceled {
+			t.Fatalf("Expected error to equal %v, got %v", context.Canceled, err)
+		}
+	})
+}
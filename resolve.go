@@ -0,0 +1,411 @@
+package hue
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Resource types that can be resolved by name
+const (
+	ResourceLights        = "lights"
+	ResourceGroups        = "groups"
+	ResourceScenes        = "scenes"
+	ResourceSchedules     = "schedules"
+	ResourceRules         = "rules"
+	ResourceSensors       = "sensors"
+	ResourceResourceLinks = "resourcelinks"
+)
+
+// AmbiguousNameError is returned when a name matches more than one resource
+type AmbiguousNameError struct {
+	Resource string
+	Name     string
+	IDs      []string
+}
+
+func (e *AmbiguousNameError) Error() string {
+	return fmt.Sprintf("Name %s matches multiple %s: %s", e.Name, e.Resource, strings.Join(e.IDs, ", "))
+}
+
+// NotFoundError is returned when a name doesn't match any resource
+type NotFoundError struct {
+	Resource string
+	Name     string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", resourceNames[e.Resource], e.Name)
+}
+
+// namedResource contains the fields a resource can be resolved by
+type namedResource struct {
+	ID       string
+	Name     string
+	UniqueID string
+}
+
+// Resolve returns the ID of the resource of the specified type whose ID, unique
+// ID, or name matches. Names are matched case-insensitively. If fuzzy is true and
+// no name matches exactly, names containing the query or within a small edit
+// distance of it also match. An AmbiguousNameError is returned if more than one
+// resource matches.
+func (h *Connection) Resolve(resource, name string, fuzzy bool) (string, error) {
+	candidates, err := h.namedResources(resource)
+	if err != nil {
+		return "", err
+	}
+
+	return resolveName(resource, name, candidates, fuzzy)
+}
+
+// ResolveLight returns the ID of the light with the specified name, unique ID, or ID
+func (h *Connection) ResolveLight(name string) (int, error) {
+	return h.resolveInt(ResourceLights, name)
+}
+
+// ResolveGroup returns the ID of the group with the specified name or ID. The
+// ID 0 resolves to AllLightsGroup, which isn't listed by the bridge.
+func (h *Connection) ResolveGroup(name string) (int, error) {
+	if strings.TrimSpace(name) == strconv.Itoa(AllLightsGroup) {
+		return AllLightsGroup, nil
+	}
+
+	return h.resolveInt(ResourceGroups, name)
+}
+
+// ResolveScene returns the ID of the scene with the specified name or ID
+func (h *Connection) ResolveScene(name string) (string, error) {
+	return h.Resolve(ResourceScenes, name, false)
+}
+
+// ResolveSchedule returns the ID of the schedule with the specified name or ID
+func (h *Connection) ResolveSchedule(name string) (int, error) {
+	return h.resolveInt(ResourceSchedules, name)
+}
+
+// ResolveRule returns the ID of the rule with the specified name or ID
+func (h *Connection) ResolveRule(name string) (int, error) {
+	return h.resolveInt(ResourceRules, name)
+}
+
+// ResolveSensor returns the ID of the sensor with the specified name, unique ID, or ID
+func (h *Connection) ResolveSensor(name string) (int, error) {
+	return h.resolveInt(ResourceSensors, name)
+}
+
+// ResolveResourceLink returns the ID of the resource link with the specified name or ID
+func (h *Connection) ResolveResourceLink(name string) (int, error) {
+	return h.resolveInt(ResourceResourceLinks, name)
+}
+
+// ResolveSceneInGroup returns the ID of the scene with the specified name or ID
+// that belongs to the specified group
+func (h *Connection) ResolveSceneInGroup(group int, name string) (string, error) {
+	scenes, err := h.GetScenes()
+	if err != nil {
+		return "", err
+	}
+
+	groupID := strconv.Itoa(group)
+	candidates := []namedResource{}
+	for _, s := range scenes {
+		if s.Group == groupID {
+			candidates = append(candidates, namedResource{ID: s.ID, Name: s.Name})
+		}
+	}
+
+	return resolveName(ResourceScenes, name, candidates, false)
+}
+
+// GetLightByName gets the Phillips Hue light with the specified name
+func (h *Connection) GetLightByName(name string) (Light, error) {
+	light, err := h.ResolveLight(name)
+	if err != nil {
+		return Light{}, err
+	}
+
+	return h.GetLight(light)
+}
+
+// TurnOnLightByName turns on the Phillips Hue light with the specified name
+func (h *Connection) TurnOnLightByName(name string) error {
+	light, err := h.ResolveLight(name)
+	if err != nil {
+		return err
+	}
+
+	return h.TurnOnLight(light)
+}
+
+// TurnOffLightByName turns off the Phillips Hue light with the specified name
+func (h *Connection) TurnOffLightByName(name string) error {
+	light, err := h.ResolveLight(name)
+	if err != nil {
+		return err
+	}
+
+	return h.TurnOffLight(light)
+}
+
+// GetGroupByName gets the Phillips Hue group with the specified name
+func (h *Connection) GetGroupByName(name string) (Group, error) {
+	group, err := h.ResolveGroup(name)
+	if err != nil {
+		return Group{}, err
+	}
+
+	return h.GetGroup(group)
+}
+
+// TurnOnGroupByName turns on all lights in the Phillips Hue group with the
+// specified name
+func (h *Connection) TurnOnGroupByName(name string) error {
+	group, err := h.ResolveGroup(name)
+	if err != nil {
+		return err
+	}
+
+	return h.TurnOnGroup(group)
+}
+
+// TurnOffGroupByName turns off all lights in the Phillips Hue group with the
+// specified name
+func (h *Connection) TurnOffGroupByName(name string) error {
+	group, err := h.ResolveGroup(name)
+	if err != nil {
+		return err
+	}
+
+	return h.TurnOffGroup(group)
+}
+
+// RecallSceneByName recalls the scene with the specified name in the Phillips Hue
+// group with the specified name
+func (h *Connection) RecallSceneByName(group, scene string) error {
+	groupID, err := h.ResolveGroup(group)
+	if err != nil {
+		return err
+	}
+
+	sceneID, err := h.ResolveSceneInGroup(groupID, scene)
+	if err != nil {
+		return err
+	}
+
+	return h.RecallScene(groupID, sceneID, SceneTransition)
+}
+
+func (h *Connection) resolveInt(resource, name string) (int, error) {
+	id, err := h.Resolve(resource, name, false)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(id)
+}
+
+// namedResources gets all resources of the specified type
+func (h *Connection) namedResources(resource string) ([]namedResource, error) {
+	candidates := []namedResource{}
+
+	switch resource {
+	case ResourceLights:
+		lights, err := h.GetLights()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range lights {
+			candidates = append(candidates, namedResource{ID: strconv.Itoa(l.ID), Name: l.Name, UniqueID: l.UniqueID})
+		}
+	case ResourceGroups:
+		groups, err := h.GetGroups()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range groups {
+			candidates = append(candidates, namedResource{ID: strconv.Itoa(g.ID), Name: g.Name})
+		}
+	case ResourceScenes:
+		scenes, err := h.GetScenes()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range scenes {
+			candidates = append(candidates, namedResource{ID: s.ID, Name: s.Name})
+		}
+	case ResourceSchedules:
+		schedules, err := h.GetSchedules()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range schedules {
+			candidates = append(candidates, namedResource{ID: strconv.Itoa(s.ID), Name: s.Name})
+		}
+	case ResourceRules:
+		rules, err := h.GetRules()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range rules {
+			candidates = append(candidates, namedResource{ID: strconv.Itoa(r.ID), Name: r.Name})
+		}
+	case ResourceSensors:
+		sensors, err := h.GetSensors()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range sensors {
+			candidates = append(candidates, namedResource{ID: strconv.Itoa(s.ID), Name: s.Name, UniqueID: s.UniqueID})
+		}
+	case ResourceResourceLinks:
+		links, err := h.GetResourceLinks()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range links {
+			candidates = append(candidates, namedResource{ID: strconv.Itoa(l.ID), Name: l.Name})
+		}
+	default:
+		return nil, fmt.Errorf("Resource must be one of the following: %s, %s, %s, %s, %s, %s, %s", ResourceLights, ResourceGroups, ResourceScenes, ResourceSchedules, ResourceRules, ResourceSensors, ResourceResourceLinks)
+	}
+
+	return candidates, nil
+}
+
+func resolveName(resource, name string, candidates []namedResource, fuzzy bool) (string, error) {
+	query := normalizeName(name)
+	if query == "" {
+		return "", errors.New("Name must not be empty")
+	}
+
+	// IDs and unique IDs always take priority over names
+	for _, c := range candidates {
+		if c.ID == strings.Trim(name, " ") {
+			return c.ID, nil
+		}
+
+		if c.UniqueID != "" && strings.EqualFold(c.UniqueID, strings.Trim(name, " ")) {
+			return c.ID, nil
+		}
+	}
+
+	matches := []string{}
+	for _, c := range candidates {
+		if normalizeName(c.Name) == query {
+			matches = append(matches, c.ID)
+		}
+	}
+
+	if len(matches) == 0 && fuzzy {
+		matches = fuzzyMatches(query, candidates)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", &NotFoundError{Resource: resource, Name: name}
+	case 1:
+		return matches[0], nil
+	}
+
+	sort.Strings(matches)
+
+	return "", &AmbiguousNameError{
+		Resource: resource,
+		Name:     name,
+		IDs:      matches,
+	}
+}
+
+// fuzzyMatches returns the IDs of the candidates whose name contains the query,
+// or failing that, whose name is closest to the query within a small edit distance
+func fuzzyMatches(query string, candidates []namedResource) []string {
+	matches := []string{}
+	for _, c := range candidates {
+		if strings.Contains(normalizeName(c.Name), query) {
+			matches = append(matches, c.ID)
+		}
+	}
+
+	if len(matches) > 0 {
+		return matches
+	}
+
+	maxDistance := len(query) / 4
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	best := maxDistance + 1
+	for _, c := range candidates {
+		d := editDistance(query, normalizeName(c.Name))
+		if d < best {
+			best = d
+			matches = []string{c.ID}
+		} else if d == best {
+			matches = append(matches, c.ID)
+		}
+	}
+
+	return matches
+}
+
+// normalizeName lowercases a name and collapses its whitespace
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// resourceNames contains the name used in error messages for each resource type
+var resourceNames = map[string]string{
+	ResourceLights:        "Light",
+	ResourceGroups:        "Group",
+	ResourceScenes:        "Scene",
+	ResourceSchedules:     "Schedule",
+	ResourceRules:         "Rule",
+	ResourceSensors:       "Sensor",
+	ResourceResourceLinks: "Resource link",
+}
@@ -0,0 +1,248 @@
+package hue
+
+import (
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Light by name", func(t *testing.T) {
+		light, err := h.ResolveLight("hue COLOR lamp 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 1
+		if light != expected {
+			t.Fatalf("Expected light %d, got %d", expected, light)
+		}
+	})
+
+	t.Run("Light by unique ID", func(t *testing.T) {
+		light, err := h.ResolveLight("AB:CD:EF")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 1
+		if light != expected {
+			t.Fatalf("Expected light %d, got %d", expected, light)
+		}
+	})
+
+	t.Run("Light by ID", func(t *testing.T) {
+		light, err := h.ResolveLight("1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := 1
+		if light != expected {
+			t.Fatalf("Expected light %d, got %d", expected, light)
+		}
+	})
+
+	t.Run("Light not found", func(t *testing.T) {
+		_, err := h.ResolveLight("Desk lamp")
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Light Desk lamp not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+
+		notFound, ok := err.(*NotFoundError)
+		if !ok || notFound.Resource != ResourceLights || notFound.Name != "Desk lamp" {
+			t.Fatalf("Expected a NotFoundError, got %#v", err)
+		}
+	})
+
+	t.Run("Fuzzy group", func(t *testing.T) {
+		group, err := h.Resolve(ResourceGroups, "grup 1", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "1"
+		if group != expected {
+			t.Fatalf("Expected group %s, got %s", expected, group)
+		}
+	})
+
+	t.Run("Every resource type", func(t *testing.T) {
+		names := map[string]string{
+			ResourceScenes:        "Night time",
+			ResourceSchedules:     "Timer",
+			ResourceRules:         "Rule 1",
+			ResourceSensors:       "Daylight",
+			ResourceResourceLinks: "Sunrise",
+		}
+
+		for resource, name := range names {
+			id, err := h.Resolve(resource, name, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := "1"
+			if id != expected {
+				t.Fatalf("Expected %s %s, got %s", resource, expected, id)
+			}
+		}
+	})
+
+	t.Run("Invalid resource", func(t *testing.T) {
+		_, err := h.Resolve("bridges", "Bridge", false)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+}
+
+func TestResolveName(t *testing.T) {
+	candidates := []namedResource{
+		{ID: "1", Name: "Kitchen"},
+		{ID: "2", Name: "Kitchen island"},
+		{ID: "3", Name: "Desk lamp"},
+		{ID: "4", Name: "desk lamp"},
+		{ID: "5", Name: "Hallway"},
+	}
+
+	t.Run("Exact match preferred over fuzzy", func(t *testing.T) {
+		id, err := resolveName(ResourceLights, "kitchen", candidates, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "1"
+		if id != expected {
+			t.Fatalf("Expected %s, got %s", expected, id)
+		}
+	})
+
+	t.Run("Ambiguous name", func(t *testing.T) {
+		_, err := resolveName(ResourceLights, "Desk lamp", candidates, false)
+
+		ambiguous, ok := err.(*AmbiguousNameError)
+		if !ok {
+			t.Fatalf("Expected an AmbiguousNameError, got %v", err)
+		}
+
+		{
+			expected := 2
+			if len(ambiguous.IDs) != expected {
+				t.Fatalf("Expected %d IDs, got %d", expected, len(ambiguous.IDs))
+			}
+		}
+
+		{
+			expected := "Name Desk lamp matches multiple lights: 3, 4"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+
+	t.Run("Fuzzy substring", func(t *testing.T) {
+		id, err := resolveName(ResourceLights, "island", candidates, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "2"
+		if id != expected {
+			t.Fatalf("Expected %s, got %s", expected, id)
+		}
+	})
+
+	t.Run("Fuzzy typo", func(t *testing.T) {
+		id, err := resolveName(ResourceLights, "hallwya", candidates, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "5"
+		if id != expected {
+			t.Fatalf("Expected %s, got %s", expected, id)
+		}
+	})
+
+	t.Run("Fuzzy disabled", func(t *testing.T) {
+		_, err := resolveName(ResourceLights, "island", candidates, false)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+
+	t.Run("Empty name", func(t *testing.T) {
+		_, err := resolveName(ResourceLights, " ", candidates, false)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Name must not be empty"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+}
+
+func TestByName(t *testing.T) {
+	h, server := createTestConnection(1)
+	defer server.Close()
+
+	t.Run("Turn on light", func(t *testing.T) {
+		err := h.TurnOnLightByName("Hue color lamp 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Turn off group", func(t *testing.T) {
+		err := h.TurnOffGroupByName("Group 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Get group", func(t *testing.T) {
+		group, err := h.GetGroupByName("group 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "Group 1"
+		if group.Name != expected {
+			t.Fatalf("Expected Name to equal %s, got %s", expected, group.Name)
+		}
+	})
+
+	t.Run("Recall scene", func(t *testing.T) {
+		err := h.RecallSceneByName("Group 1", "night time")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Scene not in group", func(t *testing.T) {
+		err := h.RecallSceneByName("Group 1", "Morning")
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+
+		{
+			expected := "Scene Morning not found"
+			if err.Error() != expected {
+				t.Fatalf("Expected error message to equal %s, got %s", expected, err.Error())
+			}
+		}
+	})
+}
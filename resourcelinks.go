@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -20,6 +20,13 @@ type ResourceLink struct {
 	ID          int      `json:"id"`
 }
 
+type resourceLinkCreateRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Recycle     bool     `json:"recycle"`
+	Links       []string `json:"links"`
+}
+
 // GetResourceLinks gets all Phillips Hue resource links
 func (h *Connection) GetResourceLinks() ([]ResourceLink, error) {
 	data, err := h.get("resourcelinks")
@@ -31,31 +38,17 @@ func (h *Connection) GetResourceLinks() ([]ResourceLink, error) {
 		return []ResourceLink{}, nil
 	}
 
-	// Create map to store JSON response
-	fullResponse := make(map[string]interface{})
+	// Decode directly into a map of IDs to resource links
+	fullResponse := make(map[string]ResourceLink)
 
 	err = json.Unmarshal(data, &fullResponse)
 	if err != nil {
 		return []ResourceLink{}, err
 	}
 
-	allResourcelinks := []ResourceLink{}
-
-	// Loop through all keys in the map and Unmarshal into
-	// ResourceLink type
-	for key, val := range fullResponse {
-		link := ResourceLink{}
-
-		s, err := json.Marshal(val)
-		if err != nil {
-			return []ResourceLink{}, err
-		}
-
-		err = json.Unmarshal(s, &link)
-		if err != nil {
-			return []ResourceLink{}, err
-		}
+	allResourcelinks := make([]ResourceLink, 0, len(fullResponse))
 
+	for key, link := range fullResponse {
 		id, err := strconv.Atoi(key)
 		if err != nil {
 			return []ResourceLink{}, err
@@ -66,6 +59,11 @@ func (h *Connection) GetResourceLinks() ([]ResourceLink, error) {
 		allResourcelinks = append(allResourcelinks, link)
 	}
 
+	// Sort by ID so the order is the same for every call
+	sort.Slice(allResourcelinks, func(i, j int) bool {
+		return allResourcelinks[i].ID < allResourcelinks[j].ID
+	})
+
 	return allResourcelinks, nil
 }
 
@@ -102,13 +100,12 @@ func (h *Connection) CreateResourceLink(name, description string, recycle bool,
 		return errors.New("Links must not be empty")
 	}
 
-	reqBody := strings.NewReader(fmt.Sprintf("{\"name\": \"%s\", \"description\": \"%s\", \"recycle\": %t, \"links\": %s}", name, description, recycle, links))
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/resourcelinks", h.baseURL), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("POST", "resourcelinks", resourceLinkCreateRequest{
+		Name:        name,
+		Description: description,
+		Recycle:     recycle,
+		Links:       links,
+	})
 	if err != nil {
 		return err
 	}
@@ -127,15 +124,7 @@ func (h *Connection) RenameResourceLink(resourceLink int, name string) error {
 		return errors.New("Name must not be empty")
 	}
 
-	attributes := fmt.Sprintf("{ \"name\": \"%s\" }", name)
-
-	reqBody := strings.NewReader(attributes)
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/resourcelinks/%d", h.baseURL, resourceLink), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("PUT", fmt.Sprintf("resourcelinks/%d", resourceLink), nameRequest{Name: name})
 	if err != nil {
 		return err
 	}
@@ -155,15 +144,7 @@ func (h *Connection) SetResourceLinkDescription(resourceLink int, description st
 		return errors.New("Description must not be empty")
 	}
 
-	attributes := fmt.Sprintf("{ \"description\": \"%s\" }", description)
-
-	reqBody := strings.NewReader(attributes)
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/resourcelinks/%d", h.baseURL, resourceLink), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("PUT", fmt.Sprintf("resourcelinks/%d", resourceLink), descriptionRequest{Description: description})
 	if err != nil {
 		return err
 	}
@@ -178,12 +159,7 @@ func (h *Connection) DeleteResourceLink(resourceLink int) error {
 		return fmt.Errorf("Resource link %d not found", resourceLink)
 	}
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/resourcelinks/%d", h.baseURL, resourceLink), nil)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("DELETE", fmt.Sprintf("resourcelinks/%d", resourceLink), nil)
 	if err != nil {
 		return err
 	}
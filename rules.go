@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -45,6 +45,12 @@ type Rule struct {
 	ID             int              `json:"id"`
 }
 
+type ruleCreateRequest struct {
+	Name       string           `json:"name"`
+	Conditions []RuleConditions `json:"conditions,omitempty"`
+	Actions    []RuleActions    `json:"actions,omitempty"`
+}
+
 // GetRules gets all Phillips Hue rules
 func (h *Connection) GetRules() ([]Rule, error) {
 	data, err := h.get("rules")
@@ -56,31 +62,17 @@ func (h *Connection) GetRules() ([]Rule, error) {
 		return []Rule{}, nil
 	}
 
-	// Create map to store JSON response
-	fullResponse := make(map[string]interface{})
+	// Decode directly into a map of IDs to rules
+	fullResponse := make(map[string]Rule)
 
 	err = json.Unmarshal(data, &fullResponse)
 	if err != nil {
 		return []Rule{}, err
 	}
 
-	allRules := []Rule{}
-
-	// Loop through all keys in the map and Unmarshal into
-	// Rule type
-	for key, val := range fullResponse {
-		rule := Rule{}
-
-		s, err := json.Marshal(val)
-		if err != nil {
-			return []Rule{}, err
-		}
-
-		err = json.Unmarshal(s, &rule)
-		if err != nil {
-			return []Rule{}, err
-		}
+	allRules := make([]Rule, 0, len(fullResponse))
 
+	for key, rule := range fullResponse {
 		id, err := strconv.Atoi(key)
 		if err != nil {
 			return []Rule{}, err
@@ -91,6 +83,11 @@ func (h *Connection) GetRules() ([]Rule, error) {
 		allRules = append(allRules, rule)
 	}
 
+	// Sort by ID so the order is the same for every call
+	sort.Slice(allRules, func(i, j int) bool {
+		return allRules[i].ID < allRules[j].ID
+	})
+
 	return allRules, nil
 }
 
@@ -123,25 +120,19 @@ func (h *Connection) CreateRule(name string, conditions []RuleConditions, action
 		return errors.New("Name must not be empty")
 	}
 
-	bodyStr := fmt.Sprintf("{\"name\": \"%s\"", name)
+	body := ruleCreateRequest{
+		Name: name,
+	}
 
 	if len(conditions) > 0 && (strings.Trim(conditions[0].Address, " ") != "" || strings.Trim(conditions[0].Operator, " ") != "" || strings.Trim(conditions[0].Value, " ") != "") {
-		bodyStr += fmt.Sprintf(", \"conditions\": %s", h.formatStruct(conditions))
+		body.Conditions = conditions
 	}
 
 	if len(actions) > 0 && (strings.Trim(actions[0].Address, " ") != "" || strings.Trim(actions[0].Method, " ") != "") {
-		bodyStr += fmt.Sprintf(", \"actions\": %s", h.formatStruct(actions))
+		body.Actions = actions
 	}
 
-	bodyStr += "}"
-
-	reqBody := strings.NewReader(bodyStr)
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/rules", h.baseURL), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("POST", "rules", body)
 	if err != nil {
 		return err
 	}
@@ -160,13 +151,7 @@ func (h *Connection) RenameRule(rule int, name string) error {
 		return errors.New("Name must not be empty")
 	}
 
-	reqBody := strings.NewReader(fmt.Sprintf("{ \"name\": \"%s\" }", name))
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/rules/%d", h.baseURL, rule), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("PUT", fmt.Sprintf("rules/%d", rule), nameRequest{Name: name})
 	if err != nil {
 		return err
 	}
@@ -181,12 +166,7 @@ func (h *Connection) DeleteRule(rule int) error {
 		return fmt.Errorf("Rule %d not found", rule)
 	}
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/rules/%d", h.baseURL, rule), nil)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("DELETE", fmt.Sprintf("rules/%d", rule), nil)
 	if err != nil {
 		return err
 	}
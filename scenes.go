@@ -4,7 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -32,6 +33,15 @@ type Scene struct {
 	ID          string       `json:"id"`
 }
 
+type sceneCreateRequest struct {
+	Name    string        `json:"name"`
+	Type    string        `json:"type"`
+	Group   string        `json:"group,omitempty"`
+	Lights  []string      `json:"lights,omitempty"`
+	Recycle bool          `json:"recycle"`
+	AppData *SceneAppData `json:"appdata,omitempty"`
+}
+
 // GetScenes gets all Phillips Hue scenes
 func (h *Connection) GetScenes() ([]Scene, error) {
 	data, err := h.get("scenes")
@@ -43,36 +53,27 @@ func (h *Connection) GetScenes() ([]Scene, error) {
 		return []Scene{}, nil
 	}
 
-	// Create map to store JSON response
-	fullResponse := make(map[string]interface{})
+	// Decode directly into a map of IDs to scenes
+	fullResponse := make(map[string]Scene)
 
 	err = json.Unmarshal(data, &fullResponse)
 	if err != nil {
 		return []Scene{}, err
 	}
 
-	allScenes := []Scene{}
-
-	// Loop through all keys in the map and Unmarshal into
-	// Scene type
-	for key, val := range fullResponse {
-		scene := Scene{}
-
-		s, err := json.Marshal(val)
-		if err != nil {
-			return []Scene{}, err
-		}
-
-		err = json.Unmarshal(s, &scene)
-		if err != nil {
-			return []Scene{}, err
-		}
+	allScenes := make([]Scene, 0, len(fullResponse))
 
+	for key, scene := range fullResponse {
 		scene.ID = key
 
 		allScenes = append(allScenes, scene)
 	}
 
+	// Sort by ID so the order is the same for every call
+	sort.Slice(allScenes, func(i, j int) bool {
+		return allScenes[i].ID < allScenes[j].ID
+	})
+
 	return allScenes, nil
 }
 
@@ -87,20 +88,18 @@ func (h *Connection) CreateLightScene(name string, lights []int, recycle bool, a
 		return errors.New("One of the lights is invalid")
 	}
 
-	bodyStr := fmt.Sprintf("{\"name\": \"%s\", \"type\": \"LightScene\", \"lights\": %s, \"recycle\": %t", name, h.formatSlice(lights), recycle)
-
-	if appData.Version != 0 || strings.Trim(appData.Data, " ") != "" {
-		bodyStr += fmt.Sprintf(", \"appdata\": %s", h.formatStruct(appData))
+	body := sceneCreateRequest{
+		Name:    name,
+		Type:    "LightScene",
+		Lights:  formatIDs(lights),
+		Recycle: recycle,
 	}
-	bodyStr += "}"
 
-	reqBody := strings.NewReader(bodyStr)
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/scenes", h.baseURL), reqBody)
-	if err != nil {
-		return err
+	if appData.Version != 0 || strings.Trim(appData.Data, " ") != "" {
+		body.AppData = &appData
 	}
 
-	err = h.execute(req)
+	err := h.execute("POST", "scenes", body)
 	if err != nil {
 		return err
 	}
@@ -115,20 +114,18 @@ func (h *Connection) CreateGroupScene(name string, group int, recycle bool, appD
 		return fmt.Errorf("Group %d not found", group)
 	}
 
-	bodyStr := fmt.Sprintf("{\"name\": \"%s\", \"type\": \"GroupScene\", \"group\": \"%d\", \"recycle\": %t", name, group, recycle)
-
-	if appData.Version != 0 || strings.Trim(appData.Data, " ") != "" {
-		bodyStr += fmt.Sprintf(", \"appdata\": %s", h.formatStruct(appData))
+	body := sceneCreateRequest{
+		Name:    name,
+		Type:    "GroupScene",
+		Group:   strconv.Itoa(group),
+		Recycle: recycle,
 	}
-	bodyStr += "}"
 
-	reqBody := strings.NewReader(bodyStr)
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/scenes", h.baseURL), reqBody)
-	if err != nil {
-		return err
+	if appData.Version != 0 || strings.Trim(appData.Data, " ") != "" {
+		body.AppData = &appData
 	}
 
-	err = h.execute(req)
+	err := h.execute("POST", "scenes", body)
 	if err != nil {
 		return err
 	}
@@ -147,9 +144,7 @@ func (h *Connection) RenameScene(scene, name string) error {
 		return errors.New("Name must not be empty")
 	}
 
-	attributes := fmt.Sprintf("{ \"name\": \"%s\" }", name)
-
-	err := h.updateScene(scene, attributes)
+	err := h.updateScene(scene, nameRequest{Name: name})
 	if err != nil {
 		return err
 	}
@@ -172,9 +167,7 @@ func (h *Connection) SetLightsInScene(scene string, lights []int) error {
 		return errors.New("One of the lights is invalid")
 	}
 
-	attributes := fmt.Sprintf("{ \"lights\": %s }", h.formatSlice(lights))
-
-	err := h.updateScene(scene, attributes)
+	err := h.updateScene(scene, lightsRequest{Lights: formatIDs(lights)})
 	if err != nil {
 		return err
 	}
@@ -189,12 +182,7 @@ func (h *Connection) DeleteScene(scene string) error {
 		return fmt.Errorf("Scene %s not found", scene)
 	}
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/scenes/%s", h.baseURL, scene), nil)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("DELETE", fmt.Sprintf("scenes/%s", scene), nil)
 	if err != nil {
 		return err
 	}
@@ -239,16 +227,8 @@ func (h *Connection) doesSceneExist(scene string) bool {
 	return true
 }
 
-func (h *Connection) updateScene(scene, value string) error {
-	url := fmt.Sprintf("%s/scenes/%s", h.baseURL, scene)
-
-	reqBody := strings.NewReader(value)
-	req, err := http.NewRequest("PUT", url, reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+func (h *Connection) updateScene(scene string, value interface{}) error {
+	err := h.execute("PUT", fmt.Sprintf("scenes/%s", scene), value)
 	if err != nil {
 		return err
 	}
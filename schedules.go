@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -39,6 +39,20 @@ type Schedule struct {
 	ID          int             `json:"id"`
 }
 
+type scheduleCreateRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Command     ScheduleCommand `json:"command"`
+	LocalTime   string          `json:"localtime"`
+	Status      string          `json:"status,omitempty"`
+	AutoDelete  bool            `json:"autodelete"`
+	Recycle     bool            `json:"recycle"`
+}
+
+type statusRequest struct {
+	Status string `json:"status"`
+}
+
 // GetSchedules gets all Phillips Hue schedules
 func (h *Connection) GetSchedules() ([]Schedule, error) {
 	data, err := h.get("schedules")
@@ -50,31 +64,17 @@ func (h *Connection) GetSchedules() ([]Schedule, error) {
 		return []Schedule{}, nil
 	}
 
-	// Create map to store JSON response
-	fullResponse := make(map[string]interface{})
+	// Decode directly into a map of IDs to schedules
+	fullResponse := make(map[string]Schedule)
 
 	err = json.Unmarshal(data, &fullResponse)
 	if err != nil {
 		return []Schedule{}, err
 	}
 
-	allSchedules := []Schedule{}
-
-	// Loop through all keys in the map and Unmarshal into
-	// Schedule type
-	for key, val := range fullResponse {
-		schedule := Schedule{}
-
-		s, err := json.Marshal(val)
-		if err != nil {
-			return []Schedule{}, err
-		}
-
-		err = json.Unmarshal(s, &schedule)
-		if err != nil {
-			return []Schedule{}, err
-		}
+	allSchedules := make([]Schedule, 0, len(fullResponse))
 
+	for key, schedule := range fullResponse {
 		id, err := strconv.Atoi(key)
 		if err != nil {
 			return []Schedule{}, err
@@ -85,6 +85,11 @@ func (h *Connection) GetSchedules() ([]Schedule, error) {
 		allSchedules = append(allSchedules, schedule)
 	}
 
+	// Sort by ID so the order is the same for every call
+	sort.Slice(allSchedules, func(i, j int) bool {
+		return allSchedules[i].ID < allSchedules[j].ID
+	})
+
 	return allSchedules, nil
 }
 
@@ -117,13 +122,15 @@ func (h *Connection) CreateSchedule(name, description string, command ScheduleCo
 		}
 	}
 
-	reqBody := strings.NewReader(fmt.Sprintf("{\"name\": \"%s\", \"description\": \"%s\", \"command\": %s, \"localtime\": \"%s\", \"status\": \"%s\", \"autodelete\": %t, \"recycle\": %t }", name, description, h.formatStruct(command), localtime, status, autodelete, recycle))
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/schedules", h.baseURL), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("POST", "schedules", scheduleCreateRequest{
+		Name:        name,
+		Description: description,
+		Command:     command,
+		LocalTime:   localtime,
+		Status:      status,
+		AutoDelete:  autodelete,
+		Recycle:     recycle,
+	})
 	if err != nil {
 		return err
 	}
@@ -164,9 +171,7 @@ func (h *Connection) RenameSchedule(schedule int, name string) error {
 		return errors.New("Name must not be empty")
 	}
 
-	attributes := fmt.Sprintf("{ \"name\": \"%s\" }", name)
-
-	err := h.updateSchedule(schedule, attributes)
+	err := h.updateSchedule(schedule, nameRequest{Name: name})
 	if err != nil {
 		return err
 	}
@@ -181,9 +186,7 @@ func (h *Connection) SetScheduleDescription(schedule int, description string) er
 		return fmt.Errorf("Schedule %d not found", schedule)
 	}
 
-	attributes := fmt.Sprintf("{ \"description\": \"%s\" }", description)
-
-	err := h.updateSchedule(schedule, attributes)
+	err := h.updateSchedule(schedule, descriptionRequest{Description: description})
 	if err != nil {
 		return err
 	}
@@ -202,9 +205,7 @@ func (h *Connection) SetScheduleStatus(schedule int, status string) error {
 		return errors.New("Status must be one of the following: enabled, disabled")
 	}
 
-	attributes := fmt.Sprintf("{ \"status\": \"%s\" }", status)
-
-	err := h.updateSchedule(schedule, attributes)
+	err := h.updateSchedule(schedule, statusRequest{Status: status})
 	if err != nil {
 		return err
 	}
@@ -219,12 +220,7 @@ func (h *Connection) DeleteSchedule(schedule int) error {
 		return fmt.Errorf("Schedule %d not found", schedule)
 	}
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/schedules/%d", h.baseURL, schedule), nil)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("DELETE", fmt.Sprintf("schedules/%d", schedule), nil)
 	if err != nil {
 		return err
 	}
@@ -242,14 +238,8 @@ func (h *Connection) doesScheduleExist(schedule int) bool {
 	return true
 }
 
-func (h *Connection) updateSchedule(schedule int, attributes string) error {
-	reqBody := strings.NewReader(attributes)
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/schedules/%d", h.baseURL, schedule), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+func (h *Connection) updateSchedule(schedule int, attributes interface{}) error {
+	err := h.execute("PUT", fmt.Sprintf("schedules/%d", schedule), attributes)
 	if err != nil {
 		return err
 	}
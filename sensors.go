@@ -1,10 +1,12 @@
 package hue
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -14,6 +16,31 @@ import (
 type SensorState struct {
 	Daylight    bool   `json:"daylight"`
 	LastUpdated string `json:"lastupdated"`
+
+	// Readings reported by specific types of sensors. Temperature is in
+	// hundredths of a degree Celsius, and LightLevel is 10000 log10(lux) + 1.
+	Presence    bool `json:"presence,omitempty"`
+	Temperature int  `json:"temperature,omitempty"`
+	LightLevel  int  `json:"lightlevel,omitempty"`
+	Dark        bool `json:"dark,omitempty"`
+	ButtonEvent int  `json:"buttonevent,omitempty"`
+	Humidity    int  `json:"humidity,omitempty"`
+}
+
+// Celsius returns the temperature reported by a temperature sensor in degrees
+// Celsius
+func (s SensorState) Celsius() float64 {
+	return float64(s.Temperature) / 100
+}
+
+// Lux returns the light level reported by a light level sensor in lux. A light
+// level of 0, reported in darkness or before the first reading, is 0 lux.
+func (s SensorState) Lux() float64 {
+	if s.LightLevel <= 0 {
+		return 0
+	}
+
+	return math.Pow(10, float64(s.LightLevel-1)/10000)
 }
 
 // SensorConfig contains the data for the Config field in the
@@ -24,6 +51,10 @@ type SensorConfig struct {
 	Lat           string `json:"lat"`
 	SunriseOffset int    `json:"sunriseoffset"`
 	SunsetOffset  int    `json:"sunsetoffset"`
+
+	// Battery is the remaining battery in percent for battery powered sensors
+	Battery   int  `json:"battery,omitempty"`
+	Reachable bool `json:"reachable,omitempty"`
 }
 
 // Sensor contains all data returned from the Phillips Hue API
@@ -36,6 +67,7 @@ type Sensor struct {
 	ModelID          string       `json:"modelid"`
 	ManufacturerName string       `json:"manufacturername"`
 	SWVersion        string       `json:"swversion"`
+	UniqueID         string       `json:"uniqueid"`
 	ID               int          `json:"id"`
 }
 
@@ -52,6 +84,18 @@ type NewSensorResponse struct {
 	LastScan   string      `json:"lastscan"`
 }
 
+type sensorCreateRequest struct {
+	Name             string       `json:"name"`
+	ModelID          string       `json:"modelid"`
+	SWVersion        string       `json:"swversion"`
+	Type             string       `json:"type"`
+	UniqueID         string       `json:"uniqueid"`
+	ManufacturerName string       `json:"manufacturername"`
+	State            SensorState  `json:"state"`
+	Config           SensorConfig `json:"config"`
+	Recycle          bool         `json:"recycle"`
+}
+
 // GetSensors gets all Phillips Hue sensors
 func (h *Connection) GetSensors() ([]Sensor, error) {
 	data, err := h.get("sensors")
@@ -63,31 +107,17 @@ func (h *Connection) GetSensors() ([]Sensor, error) {
 		return []Sensor{}, nil
 	}
 
-	// Create map to store JSON response
-	fullResponse := make(map[string]interface{})
+	// Decode directly into a map of IDs to sensors
+	fullResponse := make(map[string]Sensor)
 
 	err = json.Unmarshal(data, &fullResponse)
 	if err != nil {
 		return []Sensor{}, err
 	}
 
-	allSensors := []Sensor{}
-
-	// Loop through all keys in the map and Unmarshal into
-	// Sensor type
-	for key, val := range fullResponse {
-		sensor := Sensor{}
-
-		s, err := json.Marshal(val)
-		if err != nil {
-			return []Sensor{}, err
-		}
-
-		err = json.Unmarshal(s, &sensor)
-		if err != nil {
-			return []Sensor{}, err
-		}
+	allSensors := make([]Sensor, 0, len(fullResponse))
 
+	for key, sensor := range fullResponse {
 		id, err := strconv.Atoi(key)
 		if err != nil {
 			return []Sensor{}, err
@@ -98,6 +128,11 @@ func (h *Connection) GetSensors() ([]Sensor, error) {
 		allSensors = append(allSensors, sensor)
 	}
 
+	// Sort by ID so the order is the same for every call
+	sort.Slice(allSensors, func(i, j int) bool {
+		return allSensors[i].ID < allSensors[j].ID
+	})
+
 	return allSensors, nil
 }
 
@@ -128,13 +163,17 @@ func (h *Connection) CreateSensor(name, modelID, swVersion, sensorType, uniqueID
 		return errors.New("ManufacturerName must not be empty")
 	}
 
-	reqBody := strings.NewReader(fmt.Sprintf("{\"name\": \"%s\", \"modelid\": \"%s\", \"swversion\": %s, \"type\": \"%s\", \"uniqueid\": \"%s\", \"manufacturername\": \"%s\", \"state\": %s, \"config\": %s, \"recycle\": %t }", name, modelID, swVersion, sensorType, uniqueID, manufacturerName, h.formatStruct(state), h.formatStruct(config), recycle))
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/sensors", h.baseURL), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("POST", "sensors", sensorCreateRequest{
+		Name:             name,
+		ModelID:          modelID,
+		SWVersion:        swVersion,
+		Type:             sensorType,
+		UniqueID:         uniqueID,
+		ManufacturerName: manufacturerName,
+		State:            state,
+		Config:           config,
+		Recycle:          recycle,
+	})
 	if err != nil {
 		return err
 	}
@@ -145,12 +184,7 @@ func (h *Connection) CreateSensor(name, modelID, swVersion, sensorType, uniqueID
 // FindNewSensors finds new Phillips Hue sensors that have been added since
 // the last time performing this call
 func (h *Connection) FindNewSensors() error {
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/sensors", h.baseURL), nil)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("POST", "sensors", nil)
 	if err != nil {
 		return err
 	}
@@ -170,8 +204,9 @@ func (h *Connection) GetNewSensors() (NewSensorResponse, error) {
 		return NewSensorResponse{}, nil
 	}
 
-	// Create map to store JSON response
-	fullResponse := make(map[string]interface{})
+	// Decode each entry only once. Every key is the ID of a new sensor except
+	// for lastscan.
+	fullResponse := make(map[string]json.RawMessage)
 
 	err = json.Unmarshal(data, &fullResponse)
 	if err != nil {
@@ -180,20 +215,16 @@ func (h *Connection) GetNewSensors() (NewSensorResponse, error) {
 
 	newSensorRes := NewSensorResponse{}
 
-	// Loop through all keys in the map and Unmarshal into
-	// Group type
 	for key, val := range fullResponse {
 		if key == "lastscan" {
-			newSensorRes.LastScan = val.(string)
-		} else {
-			newSensor := NewSensor{}
-
-			l, err := json.Marshal(val)
+			err = json.Unmarshal(val, &newSensorRes.LastScan)
 			if err != nil {
 				return NewSensorResponse{}, err
 			}
+		} else {
+			newSensor := NewSensor{}
 
-			err = json.Unmarshal(l, &newSensor)
+			err = json.Unmarshal(val, &newSensor)
 			if err != nil {
 				return NewSensorResponse{}, err
 			}
@@ -209,9 +240,34 @@ func (h *Connection) GetNewSensors() (NewSensorResponse, error) {
 		}
 	}
 
+	// Sort by ID so the order is the same for every call
+	sort.Slice(newSensorRes.NewSensors, func(i, j int) bool {
+		return newSensorRes.NewSensors[i].ID < newSensorRes.NewSensors[j].ID
+	})
+
 	return newSensorRes, nil
 }
 
+// WaitForSensorSearch blocks until the search started by FindNewSensors
+// completes, then returns the sensors that were found
+func (h *Connection) WaitForSensorSearch(ctx context.Context) (NewSensorResponse, error) {
+	for {
+		newSensors, err := h.GetNewSensors()
+		if err != nil {
+			return NewSensorResponse{}, err
+		}
+
+		if newSensors.LastScan != scanActive {
+			return newSensors, nil
+		}
+
+		err = sleepContext(ctx, searchPollInterval)
+		if err != nil {
+			return NewSensorResponse{}, err
+		}
+	}
+}
+
 // GetSensor gets the specified Phillips Hue sensor
 func (h *Connection) GetSensor(sensor int) (Sensor, error) {
 	data, err := h.get(fmt.Sprintf("sensors/%d", sensor))
@@ -245,13 +301,7 @@ func (h *Connection) RenameSensor(sensor int, name string) error {
 		return errors.New("Name must not be empty")
 	}
 
-	reqBody := strings.NewReader(fmt.Sprintf("{ \"name\": \"%s\" }", name))
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/sensors/%d", h.baseURL, sensor), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("PUT", fmt.Sprintf("sensors/%d", sensor), nameRequest{Name: name})
 	if err != nil {
 		return err
 	}
@@ -266,12 +316,7 @@ func (h *Connection) DeleteSensor(sensor int) error {
 		return fmt.Errorf("Sensor %d not found", sensor)
 	}
 
-	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/sensors/%d", h.baseURL, sensor), nil)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("DELETE", fmt.Sprintf("sensors/%d", sensor), nil)
 	if err != nil {
 		return err
 	}
@@ -286,13 +331,7 @@ func (h *Connection) TurnOnSensor(sensor int) error {
 		return fmt.Errorf("Sensor %d not found", sensor)
 	}
 
-	reqBody := strings.NewReader("{ \"on\": true }")
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/sensors/%d/config", h.baseURL, sensor), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("PUT", fmt.Sprintf("sensors/%d/config", sensor), onRequest{On: true})
 	if err != nil {
 		return err
 	}
@@ -307,13 +346,7 @@ func (h *Connection) TurnOffSensor(sensor int) error {
 		return fmt.Errorf("Sensor %d not found", sensor)
 	}
 
-	reqBody := strings.NewReader("{ \"on\": false }")
-	req, err := http.NewRequest("PUT", fmt.Sprintf("%s/sensors/%d/config", h.baseURL, sensor), reqBody)
-	if err != nil {
-		return err
-	}
-
-	err = h.execute(req)
+	err := h.execute("PUT", fmt.Sprintf("sensors/%d/config", sensor), onRequest{On: false})
 	if err != nil {
 		return err
 	}
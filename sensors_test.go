@@ -1,6 +1,7 @@
 package hue
 
 import (
+	"context"
 	"testing"
 )
 
@@ -411,3 +412,72 @@ func TestTurnOffSensor(t *testing.T) {
 		}
 	})
 }
+
+func TestWaitForSensorSearch(t *testing.T) {
+	t.Run("Search complete", func(t *testing.T) {
+		h, server := createTestConnection(1)
+		defer server.Close()
+
+		newSensors, err := h.WaitForSensorSearch(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := 1
+			if len(newSensors.NewSensors) != expected {
+				t.Fatalf("Expected number of new sensors to equal %d, got %d", expected, len(newSensors.NewSensors))
+			}
+		}
+
+		{
+			expected := "2018-10-12T12:00:00"
+			if newSensors.LastScan != expected {
+				t.Fatalf("Expected LastScan to equal %s, got %s", expected, newSensors.LastScan)
+			}
+		}
+	})
+
+	t.Run("No new sensors found", func(t *testing.T) {
+		h, server := createTestConnection(2)
+		defer server.Close()
+
+		// An empty response has no last scan, so the search isn't active
+		newSensors, err := h.WaitForSensorSearch(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			expected := 0
+			if len(newSensors.NewSensors) != expected {
+				t.Fatalf("Expected %d new sensors, got %d", expected, len(newSensors.NewSensors))
+			}
+		}
+	})
+}
+
+func TestSensorReadings(t *testing.T) {
+	state := SensorState{Temperature: 2150, LightLevel: 20001}
+
+	{
+		expected := 21.5
+		if state.Celsius() != expected {
+			t.Fatalf("Expected %f degrees, got %f", expected, state.Celsius())
+		}
+	}
+
+	{
+		expected := 100.0
+		if lux := state.Lux(); lux < expected-0.001 || lux > expected+0.001 {
+			t.Fatalf("Expected %f lux, got %f", expected, lux)
+		}
+	}
+
+	{
+		dark := SensorState{LightLevel: 0}
+		if lux := dark.Lux(); lux != 0 {
+			t.Fatalf("Expected 0 lux in darkness, got %f", lux)
+		}
+	}
+}
@@ -200,6 +200,10 @@ func generateTestData(url string) interface{} {
 				ArcheType: "sultanbulb",
 				Function:  "mixed",
 				Direction: "omnidirectional",
+				Startup: lightStartup{
+					Mode:       "safety",
+					Configured: true,
+				},
 			},
 			UniqueID:   "ab:cd:ef",
 			SWVersion:  "1.29",
@@ -207,6 +211,29 @@ func generateTestData(url string) interface{} {
 			ProductID:  "Phillips-LCT016",
 		}
 
+		return data
+	case "/lights/4":
+		data := Light{
+			State: lightState{
+				On:        true,
+				Bri:       254,
+				Alert:     "none",
+				Reachable: true,
+			},
+			Type:             "Dimmable light",
+			Name:             "Hue white lamp 4",
+			ModelID:          "LWB004",
+			ManufacturerName: "Phillips",
+			ProductName:      "Hue white lamp",
+			Config: lightConfig{
+				ArcheType: "classicbulb",
+				Function:  "functional",
+				Direction: "omnidirectional",
+			},
+			UniqueID:  "ab:cd:12",
+			SWVersion: "5.38",
+		}
+
 		return data
 	case "/lights/new":
 		data := newLightTestData{
@@ -237,6 +264,24 @@ func generateTestData(url string) interface{} {
 			},
 		}
 
+		return data
+	case "/groups/0":
+		data := Group{
+			Name:   "Group 0",
+			Lights: []string{"1", "2"},
+			Type:   "LightGroup",
+			State:  groupState{AllOn: false, AnyOn: true},
+		}
+
+		return data
+	case "/groups/4":
+		data := Group{
+			Name:   "Room 4",
+			Lights: []string{"1"},
+			Type:   GroupTypeRoom,
+			Class:  ClassOffice,
+		}
+
 		return data
 	case "/groups/1":
 		data := Group{
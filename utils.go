@@ -1,133 +1,201 @@
 package hue
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
-	"reflect"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// Search related values. While a search for new lights or sensors is running,
+// the bridge reports the last scan as active.
+const (
+	scanActive         = "active"
+	maxSearchSerials   = 10
+	searchPollInterval = time.Second
+)
+
+// apiRootPath is the path of requests sent to the root of the API rather than
+// below the user, which is only done to create users
+const apiRootPath = "/"
+
 func (h *Connection) get(url string) ([]byte, error) {
-	err := h.initializeHue()
+	resp, err := h.do(context.Background(), "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := http.Get(fmt.Sprintf("%s/%s", h.baseURL, url))
-	if err != nil {
-		return nil, err
+	if len(resp.Errors) > 0 {
+		return nil, resp.Errors
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	return resp.Body, nil
+}
+
+func (h *Connection) execute(method, path string, body interface{}) error {
+	return h.executeContext(context.Background(), method, path, body)
+}
+
+func (h *Connection) executeContext(ctx context.Context, method, path string, body interface{}) error {
+	resp, err := h.do(ctx, method, path, body)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if err = h.checkForErrors(body); err != nil {
-		return nil, err
+	if len(resp.Errors) > 0 {
+		return resp.Errors
 	}
 
-	return body, nil
+	return nil
 }
 
-func (h *Connection) execute(req *http.Request) error {
-	err := h.initializeHue()
+// do sends a request to the bridge through the Connection's interceptors. The
+// request body, if any, is marshalled to JSON. If the bridge can't be reached,
+// it's discovered again and the request is retried once.
+func (h *Connection) do(ctx context.Context, method, path string, body interface{}) (*Response, error) {
+	baseURL, err := h.initializeHue()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	client := &http.Client{}
+	var data []byte
+	if body != nil {
+		data, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	handler := h.chain(h.send)
+
+	req := &Request{
+		Method: method,
+		URL:    h.requestURL(baseURL, path),
+		Path:   path,
+		Body:   data,
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+	resp, err := h.sendObserved(ctx, handler, req)
+	if err != nil && isDialError(err) && h.canRediscover() {
+		newURL, rediscoverErr := h.rediscover(baseURL)
+		if rediscoverErr != nil {
+			return nil, err
+		}
+
+		req = &Request{
+			Method: method,
+			URL:    h.requestURL(newURL, path),
+			Path:   path,
+			Body:   data,
+		}
+
+		return h.sendObserved(ctx, handler, req)
 	}
 
-	return h.checkForErrors(body)
+	return resp, err
 }
 
-func (h *Connection) checkForErrors(data []byte) error {
-	// Check for errors
-	errMsg := ""
-	errorMsgs := make([]map[string]map[string]interface{}, 10)
+// requestURL returns the URL of a path below the base URL, or of the API root
+// for apiRootPath
+func (h *Connection) requestURL(baseURL, path string) string {
+	if path == apiRootPath {
+		return strings.TrimSuffix(strings.TrimSuffix(baseURL, h.UserID), "/")
+	}
 
-	err := json.Unmarshal(data, &errorMsgs)
+	return fmt.Sprintf("%s/%s", baseURL, path)
+}
+
+// sendObserved sends a request with the handler and reports it to the
+// Connection's metrics
+func (h *Connection) sendObserved(ctx context.Context, handler RequestHandler, req *Request) (*Response, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+	h.observe(req, resp, err, time.Since(start))
+
+	return resp, err
+}
+
+// send sends a single request to the bridge
+func (h *Connection) send(ctx context.Context, req *Request) (*Response, error) {
+	var reqBody io.Reader
+	if req.Body != nil {
+		reqBody = bytes.NewReader(req.Body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, reqBody)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	for _, e := range errorMsgs {
-		if e["error"]["description"] != nil && e["error"]["description"] != "" {
-			errMsg += fmt.Sprintf("%s\n", e["error"]["description"].(string))
-		}
+	start := time.Now()
+
+	httpResp, err := h.client().Do(httpReq)
+	if err != nil {
+		return nil, err
 	}
+	defer httpResp.Body.Close()
 
-	if errMsg != "" {
-		return fmt.Errorf("Error: %s", errMsg)
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &Response{
+		StatusCode: httpResp.StatusCode,
+		Body:       respBody,
+		Latency:    time.Since(start),
+		Errors:     parseBridgeErrors(respBody),
+	}, nil
 }
 
-// formatSlice formats an int slice as a JSON string
-func (h *Connection) formatSlice(sli []int) string {
-	str := "["
-	for _, s := range sli {
-		str += fmt.Sprintf("\"%d\",", s)
-	}
-	str = str[:len(str)-1]
-	str += "]"
+// canRediscover returns true if the bridge was found through discovery, in
+// which case it can be discovered again
+func (h *Connection) canRediscover() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 
-	return str
+	return h.bridgeID != ""
 }
 
-// formatStruct formats a struct as a JSON string
-func (h *Connection) formatStruct(data interface{}) string {
-	d := reflect.ValueOf(data)
-	t := d.Type()
-	str := ""
+// isDialError returns true if the error happened while connecting, meaning the
+// request never reached the bridge and is safe to retry
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
 
-	if d.Kind() == reflect.Slice {
-		str = "["
-		for i := 0; i < d.Len(); i++ {
-			str += fmt.Sprintf("%s,", h.formatStruct(d.Field(i)))
-		}
+// Request bodies shared by more than one resource type
+type nameRequest struct {
+	Name string `json:"name"`
+}
 
-		if len(str) > 1 {
-			str = str[:len(str)-1]
-		}
-		str += "]"
-	} else {
-		str = "{"
-
-		for i := 0; i < d.NumField(); i++ {
-			str += fmt.Sprintf("\"%s\": ", strings.ToLower(t.Field(i).Name))
-
-			switch d.Field(i).Kind() {
-			case reflect.String:
-				str += fmt.Sprintf("\"%s\",", d.Field(i).Interface())
-			// Check for slice and array
-			case reflect.Struct:
-				str += fmt.Sprintf("%s,", h.formatStruct(d.Field(i).Interface()))
-			default:
-				str += fmt.Sprintf("%v,", d.Field(i).Interface())
-			}
-		}
+type descriptionRequest struct {
+	Description string `json:"description"`
+}
 
-		if len(str) > 1 {
-			str = str[:len(str)-1]
-		}
-		str += "}"
+type onRequest struct {
+	On bool `json:"on"`
+}
+
+type lightsRequest struct {
+	Lights []string `json:"lights"`
+}
+
+// formatIDs formats int IDs as the strings used by the Phillips Hue API
+func formatIDs(ids []int) []string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(id)
 	}
 
-	return str
+	return strs
 }
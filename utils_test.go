@@ -0,0 +1,233 @@
+package hue
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// createRecordingConnection creates a test connection that records the body
+// of every PUT, POST, and DELETE request
+func createRecordingConnection() (Connection, *httptest.Server, func() []byte) {
+	var mu sync.Mutex
+	var lastBody []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			data := generateTestData(r.URL.String())
+			if data == nil {
+				return
+			}
+
+			returnData, _ := json.Marshal(data)
+			w.Write(returnData)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		lastBody = body
+		mu.Unlock()
+
+		w.Write([]byte("[{\"success\": {}}]"))
+	}))
+
+	return Connection{
+		UserID:            "TEST",
+		internalIPAddress: "localhost",
+		baseURL:           server.URL,
+		isInitialized:     true,
+	}, server, func() []byte {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return lastBody
+	}
+}
+
+// recordedRequest is a PUT, POST, or DELETE request received by a test server
+type recordedRequest struct {
+	path string
+	body string
+}
+
+// createSequenceConnection creates a test connection that records every PUT,
+// POST, and DELETE request in order. GET requests are answered with data, or
+// with generateTestData if data is nil.
+func createSequenceConnection(data func(url string) interface{}) (Connection, *httptest.Server, func() []recordedRequest) {
+	if data == nil {
+		data = generateTestData
+	}
+
+	var mu sync.Mutex
+	requests := []recordedRequest{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			d := data(r.URL.String())
+			if d == nil {
+				return
+			}
+
+			returnData, _ := json.Marshal(d)
+			w.Write(returnData)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		requests = append(requests, recordedRequest{path: r.URL.Path, body: string(body)})
+		mu.Unlock()
+
+		w.Write([]byte("[{\"success\": {}}]"))
+	}))
+
+	return Connection{
+		UserID:            "TEST",
+		internalIPAddress: "localhost",
+		baseURL:           server.URL,
+		isInitialized:     true,
+	}, server, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]recordedRequest{}, requests...)
+	}
+}
+
+func TestRequestBodies(t *testing.T) {
+	h, server, lastBody := createRecordingConnection()
+	defer server.Close()
+
+	names := map[string]string{
+		"Quotes":             `Say "hello"`,
+		"Injected attribute": `Lamp", "lights": ["1`,
+		"Backslash":          `C:\lights\`,
+		"Unicode":            "Küche 🌙 灯",
+		"Control characters": "Line 1\nLine 2\tTabbed\x01\x1f",
+	}
+
+	renames := map[string]func(name string) error{
+		"RenameLight":        func(name string) error { return h.RenameLight(1, name) },
+		"RenameGroup":        func(name string) error { return h.RenameGroup(1, name) },
+		"RenameScene":        func(name string) error { return h.RenameScene("1", name) },
+		"RenameSchedule":     func(name string) error { return h.RenameSchedule(1, name) },
+		"RenameRule":         func(name string) error { return h.RenameRule(1, name) },
+		"RenameSensor":       func(name string) error { return h.RenameSensor(1, name) },
+		"RenameResourceLink": func(name string) error { return h.RenameResourceLink(1, name) },
+		"CreateGroup":        func(name string) error { return h.CreateGroup(name, "", "", []int{1, 2}) },
+		"CreateLightScene":   func(name string) error { return h.CreateLightScene(name, []int{1}, false, SceneAppData{}) },
+		"CreateRule":         func(name string) error { return h.CreateRule(name, nil, nil) },
+		"CreateResourceLink": func(name string) error { return h.CreateResourceLink(name, name, false, []string{"/groups/1"}) },
+		"CreateSensor": func(name string) error {
+			return h.CreateSensor(name, "PHDL00", "1.0", "CLIPGenericStatus", "abc", "Phillips", SensorState{}, SensorConfig{}, false)
+		},
+		"CreateSchedule": func(name string) error {
+			command := ScheduleCommand{Address: "/api/abc/groups/0/action", Method: "PUT", Body: ScheduleCommandBody{Scene: "1234"}}
+			return h.CreateSchedule(name, name, command, "2018-07-17T09:27:35", "", false, false)
+		},
+	}
+
+	for fn, rename := range renames {
+		for desc, name := range names {
+			t.Run(fn+" "+desc, func(t *testing.T) {
+				err := rename(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				body := lastBody()
+				if !json.Valid(body) {
+					t.Fatalf("Expected body to be valid JSON, got %s", body)
+				}
+
+				decoded := map[string]interface{}{}
+				err = json.Unmarshal(body, &decoded)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if decoded["name"] != name {
+					t.Fatalf("Expected name to equal %q, got %q", name, decoded["name"])
+				}
+			})
+		}
+	}
+
+	t.Run("CreateUser", func(t *testing.T) {
+		deviceType := "app#\"device\"\n"
+
+		err := h.CreateUser(deviceType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded := map[string]interface{}{}
+		err = json.Unmarshal(lastBody(), &decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded["devicetype"] != deviceType {
+			t.Fatalf("Expected devicetype to equal %q, got %q", deviceType, decoded["devicetype"])
+		}
+	})
+
+	t.Run("SetScheduleDescription", func(t *testing.T) {
+		description := `Turns "on" the \ lights`
+
+		err := h.SetScheduleDescription(1, description)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded := map[string]interface{}{}
+		err = json.Unmarshal(lastBody(), &decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded["description"] != description || len(decoded) != 1 {
+			t.Fatalf("Expected only description to equal %q, got %v", description, decoded)
+		}
+	})
+
+	t.Run("Lights use JSON tags", func(t *testing.T) {
+		err := h.SetLightsInGroup(1, []int{1, 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `{"lights":["1","2"]}`
+		if string(lastBody()) != expected {
+			t.Fatalf("Expected body to equal %s, got %s", expected, lastBody())
+		}
+	})
+
+	t.Run("Sensor state uses JSON tags", func(t *testing.T) {
+		err := h.CreateSensor("Sensor", "PHDL00", "1.0", "Daylight", "abc", "Phillips", SensorState{Daylight: true}, SensorConfig{On: true, SunriseOffset: 30}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded := struct {
+			SWVersion string       `json:"swversion"`
+			State     SensorState  `json:"state"`
+			Config    SensorConfig `json:"config"`
+		}{}
+
+		err = json.Unmarshal(lastBody(), &decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded.SWVersion != "1.0" || !decoded.State.Daylight || decoded.Config.SunriseOffset != 30 {
+			t.Fatalf("Expected sensor fields to round trip, got %s", lastBody())
+		}
+	})
+}
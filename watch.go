@@ -0,0 +1,260 @@
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Snapshot contains the state of every light, group, and sensor at the time
+// the bridge was polled, and what changed since the previous snapshot
+type Snapshot struct {
+	Lights  []Light   `json:"lights"`
+	Groups  []Group   `json:"groups"`
+	Sensors []Sensor  `json:"sensors"`
+	Changes []Change  `json:"changes"`
+	Time    time.Time `json:"time"`
+}
+
+// Change is a light, group, or sensor that was added, changed, or removed
+// between two snapshots
+type Change struct {
+	Resource string `json:"resource"`
+	ID       int    `json:"id"`
+	Removed  bool   `json:"removed,omitempty"`
+}
+
+// Watcher polls the bridge in its own goroutine and sends a Snapshot to each
+// subscriber whenever lights, groups, or sensors change
+type Watcher struct {
+	h        *Connection
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu          sync.Mutex
+	latest      *Snapshot
+	previous    map[Change]string
+	subscribers map[chan Snapshot]struct{}
+	err         error
+}
+
+// Watch starts polling the bridge every interval until the Watcher is stopped.
+// The first poll happens immediately.
+func (h *Connection) Watch(interval time.Duration) (*Watcher, error) {
+	// Error checking
+	if interval <= 0 {
+		return nil, errors.New("Interval must be greater than 0")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := &Watcher{
+		h:           h,
+		interval:    interval,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		subscribers: map[chan Snapshot]struct{}{},
+	}
+
+	go w.run(ctx)
+
+	return w, nil
+}
+
+// Subscribe returns a channel that receives a Snapshot whenever something
+// changes, starting with the latest one if the bridge has been polled. Slow
+// subscribers only receive the most recent Snapshot. The returned function
+// unsubscribes and closes the channel.
+func (w *Watcher) Subscribe() (<-chan Snapshot, func()) {
+	ch := make(chan Snapshot, 1)
+
+	w.mu.Lock()
+	w.subscribers[ch] = struct{}{}
+	if w.latest != nil {
+		ch <- *w.latest
+	}
+	w.mu.Unlock()
+
+	var once sync.Once
+
+	return ch, func() {
+		once.Do(func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+
+			if _, ok := w.subscribers[ch]; ok {
+				delete(w.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+}
+
+// Latest returns the most recent Snapshot, and false if the bridge hasn't been
+// polled successfully yet
+func (w *Watcher) Latest() (Snapshot, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.latest == nil {
+		return Snapshot{}, false
+	}
+
+	return *w.latest, true
+}
+
+// Err returns the error from the most recent poll, or nil if it succeeded
+func (w *Watcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+// Done returns a channel that's closed once the Watcher has stopped
+func (w *Watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// Stop stops polling and closes the channel of every subscriber
+func (w *Watcher) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+func (w *Watcher) run(ctx context.Context) {
+	defer func() {
+		w.mu.Lock()
+		for ch := range w.subscribers {
+			delete(w.subscribers, ch)
+			close(ch)
+		}
+		w.mu.Unlock()
+
+		close(w.done)
+	}()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.poll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll gets the state of the bridge and sends a Snapshot to every subscriber
+// if anything changed
+func (w *Watcher) poll() {
+	s, err := w.snapshot()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.err = err
+	if err != nil {
+		return
+	}
+
+	current := map[Change]string{}
+	for _, l := range s.Lights {
+		current[Change{Resource: ResourceLights, ID: l.ID}] = fingerprint(l)
+	}
+	for _, g := range s.Groups {
+		current[Change{Resource: ResourceGroups, ID: g.ID}] = fingerprint(g)
+	}
+	for _, sensor := range s.Sensors {
+		current[Change{Resource: ResourceSensors, ID: sensor.ID}] = fingerprint(sensor)
+	}
+
+	s.Changes = diffFingerprints(w.previous, current)
+	if w.latest != nil && len(s.Changes) == 0 {
+		return
+	}
+
+	w.previous = current
+	w.latest = &s
+
+	for ch := range w.subscribers {
+		// Replace a Snapshot the subscriber hasn't received yet
+		select {
+		case <-ch:
+		default:
+		}
+
+		ch <- s
+	}
+}
+
+func (w *Watcher) snapshot() (Snapshot, error) {
+	lights, err := w.h.GetLights()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	groups, err := w.h.GetGroups()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	sensors, err := w.h.GetSensors()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return Snapshot{
+		Lights:  lights,
+		Groups:  groups,
+		Sensors: sensors,
+		Time:    time.Now(),
+	}, nil
+}
+
+// diffFingerprints returns the resources that were added, changed, or removed,
+// in a stable order
+func diffFingerprints(previous, current map[Change]string) []Change {
+	changes := []Change{}
+
+	for c, value := range current {
+		if old, ok := previous[c]; !ok || old != value {
+			changes = append(changes, c)
+		}
+	}
+
+	for c := range previous {
+		if _, ok := current[c]; !ok {
+			c.Removed = true
+			changes = append(changes, c)
+		}
+	}
+
+	sortChanges(changes)
+
+	return changes
+}
+
+func sortChanges(changes []Change) {
+	order := map[string]int{ResourceLights: 0, ResourceGroups: 1, ResourceSensors: 2}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Resource != changes[j].Resource {
+			return order[changes[i].Resource] < order[changes[j].Resource]
+		}
+		return changes[i].ID < changes[j].ID
+	})
+}
+
+// fingerprint returns a value that changes whenever the resource does
+func fingerprint(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
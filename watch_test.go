@@ -0,0 +1,95 @@
+package hue
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	var mu sync.Mutex
+	lights := `{"1":{"name":"Desk","state":{"on":false}}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if strings.HasSuffix(r.URL.Path, "/lights") {
+			fmt.Fprint(w, lights)
+			return
+		}
+
+		fmt.Fprint(w, "{}")
+	}))
+	defer server.Close()
+
+	h := &Connection{UserID: "user", Address: strings.TrimPrefix(server.URL, "http://")}
+
+	t.Run("Invalid interval", func(t *testing.T) {
+		_, err := h.Watch(0)
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+	})
+
+	w, err := h.Watch(10 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, unsubscribe := w.Subscribe()
+
+	next := func() Snapshot {
+		t.Helper()
+
+		select {
+		case s := <-snapshots:
+			return s
+		case <-time.After(time.Second):
+			t.Fatal("Expected a snapshot")
+		}
+
+		return Snapshot{}
+	}
+
+	// The first snapshot contains everything
+	s := next()
+	if len(s.Lights) != 1 || len(s.Changes) != 1 || s.Changes[0] != (Change{Resource: ResourceLights, ID: 1}) {
+		t.Fatalf("Unexpected snapshot %+v", s)
+	}
+
+	mu.Lock()
+	lights = `{"1":{"name":"Desk","state":{"on":true}},"2":{"name":"Hall","state":{"on":false}}}`
+	mu.Unlock()
+
+	s = next()
+	if len(s.Changes) != 2 || !s.Lights[0].State.On {
+		t.Fatalf("Expected light 1 to change and light 2 to be added, got %+v", s.Changes)
+	}
+
+	mu.Lock()
+	lights = `{"2":{"name":"Hall","state":{"on":false}}}`
+	mu.Unlock()
+
+	s = next()
+	if len(s.Changes) != 1 || s.Changes[0] != (Change{Resource: ResourceLights, ID: 1, Removed: true}) {
+		t.Fatalf("Expected light 1 to be removed, got %+v", s.Changes)
+	}
+
+	if latest, ok := w.Latest(); !ok || len(latest.Lights) != 1 {
+		t.Fatalf("Unexpected latest snapshot %+v", latest)
+	}
+
+	unsubscribe()
+	unsubscribe()
+
+	w.Stop()
+
+	if _, ok := <-snapshots; ok {
+		t.Fatal("Expected the channel to be closed")
+	}
+}